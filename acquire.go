@@ -0,0 +1,202 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrPoolTimeout возвращается, если свободное соединение пула не нашлось за время AcquireTimeout.
+var ErrPoolTimeout = errors.New("timed out waiting for a pool connection")
+
+// AcquireBuckets — верхние границы корзин гистограммы времени ожидания соединения из пула.
+var AcquireBuckets = []time.Duration{
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+// AcquireStats — гистограмма времени ожидания соединения из пула (Pool.Acquire и
+// неявные acquire внутри Query/Exec/Begin).
+type AcquireStats struct {
+	// Buckets — верхние границы корзин (AcquireBuckets).
+	Buckets []time.Duration
+	// Counts — число ожиданий в каждой корзине; последний элемент — ожидания дольше последней границы.
+	// Значения не накопительные: Counts[i] содержит только попадания в (Buckets[i-1], Buckets[i]].
+	Counts []int64
+	// Count — общее число acquire, Failed — сколько из них завершились ошибкой (таймаут, отмена).
+	Count  int64
+	Failed int64
+	// Sum — суммарное время ожидания.
+	Sum time.Duration
+}
+
+// BackpressureFunc вызывается при смене состояния перегрузки пула: overloaded == true,
+// когда ожидание соединений стало систематически дольше порога, и false, когда оно вернулось в норму.
+// wait — сглаженное время ожидания на момент переключения.
+type BackpressureFunc func(overloaded bool, wait time.Duration)
+
+// acquireTracer собирает гистограмму ожидания и вычисляет сигнал перегрузки.
+// Реализует pgxpool.AcquireTracer; методы pgx.QueryTracer — заглушки,
+// нужные только для подключения через multitracer.
+type acquireTracer struct {
+	counts []atomic.Int64
+	count  atomic.Int64
+	failed atomic.Int64
+	sum    atomic.Int64
+
+	threshold time.Duration
+	onChange  BackpressureFunc
+	// timeout — предел ожидания соединения (см. AcquireTimeout), 0 — без предела.
+	timeout time.Duration
+
+	mu         sync.Mutex
+	ewma       time.Duration
+	overloaded atomic.Bool
+}
+
+type acquireStartKey struct{}
+
+// acquireStart — начало ожидания и отмена контекста с пределом ожидания.
+type acquireStart struct {
+	at     time.Time
+	cancel context.CancelFunc
+}
+
+func newAcquireTracer() *acquireTracer {
+	return &acquireTracer{counts: make([]atomic.Int64, len(AcquireBuckets)+1)}
+}
+
+func (t *acquireTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return ctx
+}
+
+func (t *acquireTracer) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
+
+// TraceAcquireStart ограничивает ожидание пределом AcquireTimeout: pgxpool ждёт соединение
+// с контекстом, который вернул трейсер, а сам запрос выполняет с исходным.
+func (t *acquireTracer) TraceAcquireStart(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	start := acquireStart{at: time.Now(), cancel: func() {}}
+	if t.timeout > 0 {
+		ctx, start.cancel = context.WithTimeout(ctx, t.timeout)
+	}
+	return context.WithValue(ctx, acquireStartKey{}, start)
+}
+
+func (t *acquireTracer) TraceAcquireEnd(ctx context.Context, _ *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	start, ok := ctx.Value(acquireStartKey{}).(acquireStart)
+	if !ok {
+		return
+	}
+	start.cancel()
+	wait := time.Since(start.at)
+
+	bucket := len(AcquireBuckets)
+	for i, b := range AcquireBuckets {
+		if wait <= b {
+			bucket = i
+			break
+		}
+	}
+	t.counts[bucket].Add(1)
+	t.count.Add(1)
+	t.sum.Add(int64(wait))
+	if data.Err != nil {
+		t.failed.Add(1)
+	}
+
+	if t.threshold > 0 {
+		t.observe(wait)
+	}
+}
+
+// observe обновляет сглаженное время ожидания. Сигнал включается, когда оно превышает порог,
+// и выключается, когда опускается ниже половины порога, чтобы не "дребезжать" на границе.
+func (t *acquireTracer) observe(wait time.Duration) {
+	const alpha = 0.2
+
+	t.mu.Lock()
+	t.ewma = time.Duration(alpha*float64(wait) + (1-alpha)*float64(t.ewma))
+	ewma := t.ewma
+
+	var changed bool
+	switch {
+	case !t.overloaded.Load() && ewma > t.threshold:
+		t.overloaded.Store(true)
+		changed = true
+	case t.overloaded.Load() && ewma < t.threshold/2:
+		t.overloaded.Store(false)
+		changed = true
+	}
+	overloaded := t.overloaded.Load()
+	t.mu.Unlock()
+
+	if changed && t.onChange != nil {
+		t.onChange(overloaded, ewma)
+	}
+}
+
+func (t *acquireTracer) snapshot() AcquireStats {
+	s := AcquireStats{
+		Buckets: append([]time.Duration(nil), AcquireBuckets...),
+		Counts:  make([]int64, len(t.counts)),
+		Count:   t.count.Load(),
+		Failed:  t.failed.Load(),
+		Sum:     time.Duration(t.sum.Load()),
+	}
+	for i := range t.counts {
+		s.Counts[i] = t.counts[i].Load()
+	}
+	return s
+}
+
+// AcquireStats возвращает гистограмму времени ожидания соединений из пула с момента создания Postgres.
+func (p *Postgres) AcquireStats() AcquireStats {
+	return p.acquire.snapshot()
+}
+
+// Overloaded сообщает, включён ли сейчас сигнал перегрузки пула (см. WithBackpressure).
+// Без WithBackpressure всегда возвращает false.
+//
+// Пример сброса нагрузки в HTTP-обработчике:
+//
+//	if pg.Overloaded() {
+//	    http.Error(w, "try later", http.StatusServiceUnavailable)
+//	    return
+//	}
+func (p *Postgres) Overloaded() bool {
+	return p.acquire.overloaded.Load()
+}
+
+// poolTimeout превращает истечение предела ожидания соединения в ErrPoolTimeout. Дедлайн,
+// которого нет в контексте запроса, мог взяться только из AcquireTimeout: дедлайны ctx
+// и QueryTimeouts видны через ctx.Err().
+func poolTimeout(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrPoolTimeout, err)
+	}
+	return err
+}
+
+// poolTimeoutRow — pgx.Row, ошибка которого проходит через poolTimeout: QueryRow пула
+// получает соединение сразу, но сообщает ошибку только в Scan.
+type poolTimeoutRow struct {
+	pgx.Row
+	ctx context.Context
+}
+
+func (r poolTimeoutRow) Scan(dest ...any) error {
+	return poolTimeout(r.ctx, r.Row.Scan(dest...))
+}
@@ -0,0 +1,88 @@
+package pgfx
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ActiveQuery — запрос, выполняющийся прямо сейчас (см. ActiveQueries).
+type ActiveQuery struct {
+	// PID — PID серверного процесса соединения (совпадает с pg_stat_activity.pid).
+	PID uint32
+	SQL string
+	// Tag и TxName — тег запроса (WithQueryTag) и имя транзакции (WithTxName) из контекста.
+	Tag    string
+	TxName string
+	// Started — время начала, Duration — сколько запрос выполняется на момент вызова ActiveQueries.
+	Started  time.Time
+	Duration time.Duration
+}
+
+// activeQueries — реестр выполняющихся запросов, заполняемый трейсером.
+type activeQueries struct {
+	seq     atomic.Uint64
+	mu      sync.Mutex
+	queries map[uint64]ActiveQuery
+}
+
+type activeQueryKey struct{}
+
+func newActiveQueries() *activeQueries {
+	return &activeQueries{queries: make(map[uint64]ActiveQuery)}
+}
+
+func (a *activeQueries) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	id := a.seq.Add(1)
+
+	a.mu.Lock()
+	a.queries[id] = ActiveQuery{
+		PID:     conn.PgConn().PID(),
+		SQL:     data.SQL,
+		Tag:     QueryTag(ctx),
+		TxName:  TxName(ctx),
+		Started: time.Now(),
+	}
+	a.mu.Unlock()
+
+	return context.WithValue(ctx, activeQueryKey{}, id)
+}
+
+func (a *activeQueries) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryEndData) {
+	id, ok := ctx.Value(activeQueryKey{}).(uint64)
+	if !ok {
+		return
+	}
+
+	a.mu.Lock()
+	delete(a.queries, id)
+	a.mu.Unlock()
+}
+
+// ActiveQueries возвращает запросы, которые выполняются через пул прямо сейчас, от самых долгих
+// к самым новым — например, для служебного эндпоинта "что сервис делает в базе".
+// Требует опции TrackActiveQueries, иначе возвращает nil.
+//
+// Запрос считается выполняющимся до завершения его выполнения в pgx: для Query — до
+// закрытия rows.
+func (p *Postgres) ActiveQueries() []ActiveQuery {
+	if p.active == nil {
+		return nil
+	}
+
+	now := time.Now()
+	p.active.mu.Lock()
+	out := make([]ActiveQuery, 0, len(p.active.queries))
+	for _, q := range p.active.queries {
+		q.Duration = now.Sub(q.Started)
+		out = append(out, q)
+	}
+	p.active.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Started.Before(out[j].Started) })
+	return out
+}
@@ -0,0 +1,254 @@
+package pgfx
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Anonymizer заменяет значение колонки при ExportAnonymized. value == nil означает NULL;
+// возвращённый nil записывается как NULL.
+type Anonymizer func(value *string) *string
+
+// anonymizerRegistry — колонки с зарегистрированными анонимизаторами: таблица → колонка → функция.
+type anonymizerRegistry struct {
+	mu     sync.RWMutex
+	tables map[string]map[string]Anonymizer
+}
+
+var defaultAnonymizers = &anonymizerRegistry{tables: make(map[string]map[string]Anonymizer)}
+
+// RegisterAnonymizer задаёт анонимизатор a для колонки column таблицы table.
+// Имена таблиц указываются так же, как в ExportAnonymized (со схемой или без).
+func RegisterAnonymizer(table, column string, a Anonymizer) {
+	defaultAnonymizers.mu.Lock()
+	defer defaultAnonymizers.mu.Unlock()
+
+	table = strings.ToLower(table)
+	if defaultAnonymizers.tables[table] == nil {
+		defaultAnonymizers.tables[table] = make(map[string]Anonymizer)
+	}
+	defaultAnonymizers.tables[table][column] = a
+}
+
+func anonymizersFor(table string) map[string]Anonymizer {
+	defaultAnonymizers.mu.RLock()
+	defer defaultAnonymizers.mu.RUnlock()
+
+	return defaultAnonymizers.tables[strings.ToLower(table)]
+}
+
+// AnonymizeNull заменяет значение на NULL.
+func AnonymizeNull() Anonymizer {
+	return func(*string) *string { return nil }
+}
+
+// AnonymizeHash заменяет значение на HMAC-SHA256 с ключом salt (hex). Одинаковые значения дают
+// одинаковый результат, поэтому соединения и уникальность по колонке сохраняются, а без salt
+// исходное значение не подобрать по словарю. NULL остаётся NULL.
+func AnonymizeHash(salt []byte) Anonymizer {
+	return func(value *string) *string {
+		if value == nil {
+			return nil
+		}
+		out := hex.EncodeToString(anonymizeMAC(salt, *value))
+		return &out
+	}
+}
+
+// AnonymizeFake заменяет значение правдоподобной подделкой, которую строит gen. Генератор
+// инициализируется по HMAC исходного значения с ключом salt, поэтому одно и то же значение
+// всегда заменяется одинаково. NULL остаётся NULL.
+//
+//	pgfx.RegisterAnonymizer("users", "email", pgfx.AnonymizeFake(salt, pgfx.FakeEmail))
+func AnonymizeFake(salt []byte, gen func(r *rand.Rand) string) Anonymizer {
+	return func(value *string) *string {
+		if value == nil {
+			return nil
+		}
+		mac := anonymizeMAC(salt, *value)
+		r := rand.New(rand.NewPCG(binary.LittleEndian.Uint64(mac[:8]), binary.LittleEndian.Uint64(mac[8:16])))
+		out := gen(r)
+		return &out
+	}
+}
+
+func anonymizeMAC(salt []byte, value string) []byte {
+	m := hmac.New(sha256.New, salt)
+	m.Write([]byte(value))
+	return m.Sum(nil)
+}
+
+var (
+	_fakeFirstNames = []string{"Alex", "Maria", "Ivan", "Olga", "Sam", "Nina", "Pavel", "Anna", "Oleg", "Vera"}
+	_fakeLastNames  = []string{"Smirnov", "Ivanova", "Petrov", "Kuznetsova", "Sokolov", "Popova", "Orlov", "Volkova"}
+)
+
+// FakeName — генератор для AnonymizeFake: "Имя Фамилия".
+func FakeName(r *rand.Rand) string {
+	return _fakeFirstNames[r.IntN(len(_fakeFirstNames))] + " " + _fakeLastNames[r.IntN(len(_fakeLastNames))]
+}
+
+// FakeEmail — генератор для AnonymizeFake: уникальный с высокой вероятностью адрес в example.com.
+func FakeEmail(r *rand.Rand) string {
+	return fmt.Sprintf("user%012d@example.com", r.Int64N(1e12))
+}
+
+// FakePhone — генератор для AnonymizeFake: номер в формате +7XXXXXXXXXX.
+func FakePhone(r *rand.Rand) string {
+	return fmt.Sprintf("+7%010d", r.Int64N(1e10))
+}
+
+// ExportAnonymized выгружает таблицу table в w в текстовом формате COPY (его загружает
+// COPY table FROM STDIN на стенде), применяя анонимизаторы, зарегистрированные
+// RegisterAnonymizer для колонок таблицы. Данные идут потоком: COPY TO STDOUT на сервере,
+// построчная замена значений на клиенте. Колонки без анонимизатора копируются как есть.
+//
+// db должен поддерживать COPY TO (TransactionalPool). Возвращает количество выгруженных строк.
+func ExportAnonymized(ctx context.Context, db QueryExecutor, w io.Writer, table string) (int64, error) {
+	c, ok := db.(copyToer)
+	if !ok {
+		return 0, errors.New("export anonymized: executor does not support COPY TO")
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT attname FROM pg_attribute
+		WHERE attrelid = $1::regclass AND attnum > 0 AND NOT attisdropped
+		ORDER BY attnum`, quoteTable(table))
+	if err != nil {
+		return 0, fmt.Errorf("export anonymized: columns: %w", err)
+	}
+	columns, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, fmt.Errorf("export anonymized: columns: %w", err)
+	}
+
+	registered := anonymizersFor(table)
+	anonymizers := make([]Anonymizer, len(columns))
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		anonymizers[i] = registered[col]
+		quoted[i] = quoteIdent(col)
+	}
+
+	pr, pw := io.Pipe()
+	copyErr := make(chan error, 1)
+	go func() {
+		_, err := c.CopyTo(ctx, pw, fmt.Sprintf("COPY %s (%s) TO STDOUT", quoteTable(table), strings.Join(quoted, ", ")))
+		pw.CloseWithError(err)
+		copyErr <- err
+	}()
+
+	n, err := anonymizeCopyText(pr, w, anonymizers)
+	pr.CloseWithError(io.ErrClosedPipe)
+	if cerr := <-copyErr; cerr != nil && !errors.Is(cerr, io.ErrClosedPipe) {
+		return n, fmt.Errorf("export anonymized: copy: %w", cerr)
+	}
+	if err != nil {
+		return n, fmt.Errorf("export anonymized: %w", err)
+	}
+	return n, nil
+}
+
+// anonymizeCopyText переписывает поток в текстовом формате COPY, применяя anonymizers по колонкам.
+// В этом формате табуляции и переводы строк внутри значений экранированы, поэтому строки
+// и колонки можно разделять по сырым символам.
+func anonymizeCopyText(r io.Reader, w io.Writer, anonymizers []Anonymizer) (int64, error) {
+	in := bufio.NewReader(r)
+	out := bufio.NewWriter(w)
+
+	var n int64
+	for {
+		line, err := in.ReadString('\n')
+		if len(line) > 0 {
+			fields := strings.Split(strings.TrimSuffix(line, "\n"), "\t")
+			for i, a := range anonymizers {
+				if a == nil || i >= len(fields) {
+					continue
+				}
+				var value *string
+				if fields[i] != `\N` {
+					v := copyTextUnescape(fields[i])
+					value = &v
+				}
+				if res := a(value); res == nil {
+					fields[i] = `\N`
+				} else {
+					fields[i] = copyTextEscape(*res)
+				}
+			}
+
+			if _, werr := out.WriteString(strings.Join(fields, "\t") + "\n"); werr != nil {
+				return n, werr
+			}
+			n++
+		}
+
+		if errors.Is(err, io.EOF) {
+			return n, out.Flush()
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+var _copyTextEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func copyTextEscape(s string) string {
+	return _copyTextEscaper.Replace(s)
+}
+
+// copyTextUnescape раскрывает экранирование текстового формата COPY. Сервер при выводе
+// использует только \\, \t, \n, \r, \b, \f, \v и восьмеричные коды.
+func copyTextUnescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'v':
+			b.WriteByte('\v')
+		default:
+			if c >= '0' && c <= '7' {
+				v, j := 0, i
+				for ; j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7'; j++ {
+					v = v*8 + int(s[j]-'0')
+				}
+				b.WriteByte(byte(v))
+				i = j - 1
+				continue
+			}
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
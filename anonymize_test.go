@@ -0,0 +1,49 @@
+package pgfx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAnonymizeCopyText(t *testing.T) {
+	upper := func(v *string) *string {
+		if v == nil {
+			return nil
+		}
+		s := strings.ToUpper(*v)
+		return &s
+	}
+
+	in := "1\tann\\tlee\ta@b.c\n2\t\\N\tx@y.z\n"
+	var out bytes.Buffer
+	n, err := anonymizeCopyText(strings.NewReader(in), &out, []Anonymizer{nil, upper, AnonymizeNull()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("rows = %d, want 2", n)
+	}
+	if want := "1\tANN\\tLEE\t\\N\n2\t\\N\t\\N\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestAnonymizeDeterministic(t *testing.T) {
+	salt := []byte("salt")
+	v := "alice@example.org"
+
+	hash := AnonymizeHash(salt)
+	if *hash(&v) != *hash(&v) {
+		t.Error("AnonymizeHash must be deterministic")
+	}
+
+	fake := AnonymizeFake(salt, FakeEmail)
+	a, b := fake(&v), fake(&v)
+	if *a != *b || !strings.HasSuffix(*a, "@example.com") {
+		t.Errorf("AnonymizeFake = %q, %q", *a, *b)
+	}
+	if fake(nil) != nil {
+		t.Error("AnonymizeFake must keep NULL")
+	}
+}
@@ -0,0 +1,219 @@
+package pgfx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AuditActorSetting — параметр сессии, из которого триггер аудита берёт автора изменения.
+const AuditActorSetting = "pgfx.actor"
+
+const _auditFunction = `CREATE OR REPLACE FUNCTION pgfx_audit() RETURNS trigger LANGUAGE plpgsql AS $$
+DECLARE
+	old_row jsonb;
+	new_row jsonb;
+BEGIN
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		old_row := to_jsonb(OLD);
+	END IF;
+	IF TG_OP IN ('INSERT', 'UPDATE') THEN
+		new_row := to_jsonb(NEW);
+	END IF;
+
+	EXECUTE format('INSERT INTO %I.%I (operation, old_row, new_row, actor) VALUES ($1, $2, $3, $4)',
+		TG_TABLE_SCHEMA, TG_TABLE_NAME || '_audit')
+	USING TG_OP, old_row, new_row, nullif(current_setting('` + AuditActorSetting + `', true), '');
+
+	RETURN NULL;
+END
+$$`
+
+// AuditRecord — запись журнала аудита.
+type AuditRecord struct {
+	ID int64
+	// Operation — INSERT, UPDATE или DELETE.
+	Operation string
+	// Old — строка до изменения (nil для INSERT).
+	Old json.RawMessage
+	// New — строка после изменения (nil для DELETE).
+	New json.RawMessage
+	// Actor — значение AuditActorSetting на момент изменения, пусто, если не задано.
+	Actor     string
+	TxID      int64
+	ChangedAt time.Time
+}
+
+// DecodeOld разбирает строку до изменения в v (обычно структура с json-тегами по именам колонок).
+func (r AuditRecord) DecodeOld(v any) error {
+	return json.Unmarshal(r.Old, v)
+}
+
+// DecodeNew разбирает строку после изменения в v.
+func (r AuditRecord) DecodeNew(v any) error {
+	return json.Unmarshal(r.New, v)
+}
+
+// AuditQuery — фильтр чтения журнала аудита. Нулевые поля не ограничивают выборку.
+type AuditQuery struct {
+	Since time.Time
+	Until time.Time
+	Actor string
+	// AfterID — для постраничного чтения: вернуть записи с ID больше указанного.
+	AfterID int64
+	// Limit — максимальное количество записей, по умолчанию 100.
+	Limit int
+}
+
+// Audit создаёт стандартные таблицы и триггеры аудита и читает журнал изменений.
+//
+// Для каждой таблицы t создаётся таблица t_audit, куда триггер pgfx_audit пишет
+// тип операции, строки до и после изменения в jsonb и автора из AuditActorSetting.
+type Audit struct {
+	db QueryExecutor
+	tm *Manager
+}
+
+// NewAudit создаёт помощник аудита, работающий через TransactionalPool.
+func (p *Postgres) NewAudit() *Audit {
+	return &Audit{
+		db: p.TransactionalPool,
+		tm: p.NewTransactionManager(),
+	}
+}
+
+// SetAuditActor задаёт автора изменений для текущей транзакции db (обычно TransactionalPool).
+// Триггеры аудита запишут его во все изменения, сделанные в этой транзакции.
+func SetAuditActor(ctx context.Context, db QueryExecutor, actor string) error {
+	return SetLocal(ctx, db, AuditActorSetting, actor)
+}
+
+// Enable создаёт (или обновляет) функцию аудита, таблицы журнала и триггеры для tables.
+// Вызов идемпотентен.
+func (a *Audit) Enable(ctx context.Context, tables ...string) error {
+	return a.tm.ReadCommitted(ctx, func(ctx context.Context) error {
+		if _, err := a.db.Exec(ctx, _auditFunction); err != nil {
+			return fmt.Errorf("audit - Enable - create function: %w", err)
+		}
+
+		for _, table := range tables {
+			audit, err := a.auditTable(ctx, table)
+			if err != nil {
+				return fmt.Errorf("audit - Enable - %s: %w", table, err)
+			}
+
+			queries := []string{
+				fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id         BIGSERIAL PRIMARY KEY,
+	operation  TEXT NOT NULL,
+	old_row    JSONB,
+	new_row    JSONB,
+	actor      TEXT,
+	txid       BIGINT NOT NULL DEFAULT txid_current(),
+	changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`, audit),
+				fmt.Sprintf(`DROP TRIGGER IF EXISTS pgfx_audit ON %s`, quoteTable(table)),
+				fmt.Sprintf(`CREATE TRIGGER pgfx_audit AFTER INSERT OR UPDATE OR DELETE ON %s FOR EACH ROW EXECUTE FUNCTION pgfx_audit()`, quoteTable(table)),
+			}
+
+			for _, query := range queries {
+				if _, err := a.db.Exec(ctx, query); err != nil {
+					return fmt.Errorf("audit - Enable - %s: %w", table, err)
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// Disable удаляет триггеры аудита с tables. Таблицы журнала сохраняются.
+func (a *Audit) Disable(ctx context.Context, tables ...string) error {
+	for _, table := range tables {
+		if _, err := a.db.Exec(ctx, fmt.Sprintf(`DROP TRIGGER IF EXISTS pgfx_audit ON %s`, quoteTable(table))); err != nil {
+			return fmt.Errorf("audit - Disable - %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// Log возвращает записи журнала аудита таблицы table в порядке возрастания ID.
+func (a *Audit) Log(ctx context.Context, table string, q AuditQuery) ([]AuditRecord, error) {
+	var (
+		conds []string
+		args  []any
+	)
+
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if q.AfterID > 0 {
+		add("id > $%d", q.AfterID)
+	}
+	if !q.Since.IsZero() {
+		add("changed_at >= $%d", q.Since)
+	}
+	if !q.Until.IsZero() {
+		add("changed_at < $%d", q.Until)
+	}
+	if q.Actor != "" {
+		add("actor = $%d", q.Actor)
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	audit, err := a.auditTable(ctx, table)
+	if err != nil {
+		return nil, fmt.Errorf("audit - Log: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT id, operation, old_row, new_row, coalesce(actor, ''), txid, changed_at FROM %s`, audit)
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY id LIMIT $%d", len(args))
+
+	rows, err := a.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("audit - Log: %w", err)
+	}
+
+	records, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (AuditRecord, error) {
+		var r AuditRecord
+		err := row.Scan(&r.ID, &r.Operation, &r.Old, &r.New, &r.Actor, &r.TxID, &r.ChangedAt)
+		return r, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("audit - Log: %w", err)
+	}
+
+	return records, nil
+}
+
+// auditTable возвращает имя таблицы журнала для table в той же схеме, где table на самом деле
+// лежит (триггер пишет в TG_TABLE_SCHEMA), а не в первой схеме search_path.
+func (a *Audit) auditTable(ctx context.Context, table string) (string, error) {
+	var schema, name string
+	err := a.db.QueryRow(ctx, `
+		SELECT n.nspname, c.relname
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.oid = to_regclass($1)`, quoteTable(table)).Scan(&schema, &name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("table %s not found", table)
+	}
+	if err != nil {
+		return "", fmt.Errorf("resolve table: %w", err)
+	}
+	return quoteIdent(schema) + "." + quoteIdent(name+"_audit"), nil
+}
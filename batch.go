@@ -0,0 +1,78 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Stmt — один оператор пакета RunBatch.
+type Stmt struct {
+	SQL  string
+	Args []any
+}
+
+// BatchResult — результат одного оператора пакета.
+type BatchResult struct {
+	Tag pgconn.CommandTag
+	Err error
+}
+
+// batcher реализуется *pgxpool.Pool, pgx.Tx и TransactionalPool.
+type batcher interface {
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// RunBatch отправляет stmts одним пакетом (pgx.Batch) и возвращает результат каждого оператора
+// в том же порядке, не останавливаясь на первой ошибке. Ошибка функции — первая ошибка
+// оператора (с его номером) либо ошибка завершения пакета; results при этом заполнены.
+//
+// Вне транзакции сервер выполняет пакет в неявной транзакции: после ошибки оператора
+// последующие тоже завершатся ошибкой, а предыдущие будут откачены.
+//
+// Если db не поддерживает пакеты, операторы выполняются по очереди через Exec.
+func RunBatch(ctx context.Context, db QueryExecutor, stmts []Stmt) ([]BatchResult, error) {
+	results := make([]BatchResult, len(stmts))
+	if len(stmts) == 0 {
+		return results, nil
+	}
+
+	b, ok := db.(batcher)
+	if !ok {
+		for i, s := range stmts {
+			results[i].Tag, results[i].Err = db.Exec(ctx, s.SQL, s.Args...)
+		}
+		return results, firstBatchError(results)
+	}
+
+	batch := &pgx.Batch{}
+	for _, s := range stmts {
+		batch.Queue(s.SQL, s.Args...)
+	}
+
+	br := b.SendBatch(ctx, batch)
+	for i := range stmts {
+		results[i].Tag, results[i].Err = br.Exec()
+	}
+
+	if err := firstBatchError(results); err != nil {
+		_ = br.Close()
+		return results, err
+	}
+	if err := br.Close(); err != nil {
+		return results, fmt.Errorf("batch: close: %w", err)
+	}
+
+	return results, nil
+}
+
+func firstBatchError(results []BatchResult) error {
+	for i, r := range results {
+		if r.Err != nil {
+			return fmt.Errorf("batch: statement %d: %w", i, r.Err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrTxBudgetExceeded — причина отмены контекста транзакции, исчерпавшей бюджет WithBudget
+// (доступна через context.Cause).
+var ErrTxBudgetExceeded = errors.New("transaction time budget exceeded")
+
+// WithBudget ограничивает время транзакции двумя уровнями: вся транзакция, включая BEGIN
+// и COMMIT, укладывается в total, а каждый запрос — в min(остаток бюджета, statement).
+// Ограничение запроса действует и на сервере (SET LOCAL statement_timeout), и на клиенте
+// (дедлайн контекста запроса), поэтому зависшее соединение тоже не удерживает запрос
+// дольше положенного. Нулевое значение отключает соответствующий уровень.
+//
+// Для вложенного вызова, присоединяющегося к уже активной транзакции, опция игнорируется.
+func WithBudget(total, statement time.Duration) TxOption {
+	return func(c *txConfig) {
+		c.budget = total
+		c.stmtBudget = statement
+	}
+}
+
+// WithTimeout ограничивает всю транзакцию, включая BEGIN и COMMIT, длительностью d:
+//
+//	err := tm.ReadCommitted(ctx, handler, pgfx.WithTimeout(5*time.Second))
+//
+// Контекст обработчика получает дедлайн, и запросы после него завершаются ошибкой. Если обработчик
+// превысил время, не обращаясь к базе, транзакция всё равно откатывается, а не фиксируется.
+// Причина отмены (context.Cause) — ErrTxBudgetExceeded; ошибка такой транзакции удовлетворяет
+// errors.Is(err, ErrTxBudgetExceeded). Это сокращение для WithBudget(d, 0).
+//
+// Для вложенного вызова, присоединяющегося к уже активной транзакции, опция игнорируется.
+func WithTimeout(d time.Duration) TxOption {
+	return WithBudget(d, 0)
+}
+
+// _rollbackTimeout ограничивает ROLLBACK, выполняемый после отмены контекста транзакции.
+const _rollbackTimeout = 5 * time.Second
+
+// rollbackTx откатывает tx на контексте без отмены ctx: на истёкшем контексте pgx не отправляет
+// ROLLBACK, а закрывает соединение.
+func rollbackTx(ctx context.Context, tx pgx.Tx) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), _rollbackTimeout)
+	defer cancel()
+
+	return tx.Rollback(ctx)
+}
+
+// budgetErr добавляет к err причину ErrTxBudgetExceeded, если бюджет транзакции исчерпан:
+// прерванный дедлайном запрос возвращает лишь context.DeadlineExceeded.
+func budgetErr(ctx context.Context, cfg txConfig, err error) error {
+	if cfg.budget == 0 || errors.Is(err, ErrTxBudgetExceeded) || !errors.Is(context.Cause(ctx), ErrTxBudgetExceeded) {
+		return err
+	}
+	return fmt.Errorf("transaction exceeded its time budget: %w", errors.Join(ErrTxBudgetExceeded, err))
+}
+
+// setStatementTimeout задаёт statement_timeout до конца транзакции: d, но не больше остатка
+// дедлайна ctx. Значение не меньше 1 мс, потому что 0 отключает ограничение.
+func setStatementTimeout(ctx context.Context, tx pgx.Tx, d time.Duration) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		d = min(d, time.Until(deadline))
+	}
+	ms := max(d.Milliseconds(), 1)
+
+	_, err := tx.Exec(ctx, `SELECT set_config('statement_timeout', $1, true)`, strconv.FormatInt(ms, 10))
+	return err
+}
+
+// budgetTx ограничивает каждый запрос транзакции дедлайном stmt; дедлайн бюджета всей транзакции
+// уже содержится в контексте, поэтому запрос получает меньший из двух.
+type budgetTx struct {
+	pgx.Tx
+	stmt time.Duration
+}
+
+func (t budgetTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	tx, err := t.Tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return budgetTx{Tx: tx, stmt: t.stmt}, nil
+}
+
+// start готовит запрос: если остаток бюджета транзакции (дедлайн ctx) меньше t.stmt,
+// statement_timeout на сервере снижается до него, чтобы запрос не пережил бюджет и на сервере,
+// а контекст запроса получает дедлайн t.stmt. Остаток убывает, поэтому после этого порога
+// statement_timeout обновляется перед каждым запросом.
+func (t budgetTx) start(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < t.stmt {
+		if err := setStatementTimeout(ctx, t.Tx, t.stmt); err != nil {
+			return nil, nil, fmt.Errorf("can't set statement timeout: %w", err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, t.stmt)
+	return ctx, cancel, nil
+}
+
+func (t budgetTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	ctx, cancel, err := t.start(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer cancel()
+
+	return t.Tx.Exec(ctx, sql, args...)
+}
+
+func (t budgetTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	ctx, cancel, err := t.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := t.Tx.Query(ctx, sql, args...)
+	if err != nil {
+		cancel()
+		return rows, err
+	}
+	return &cancelRows{Rows: rows, cancel: cancel}, nil
+}
+
+func (t budgetTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	ctx, cancel, err := t.start(ctx)
+	if err != nil {
+		return errRow{err}
+	}
+	return cancelRow{Row: t.Tx.QueryRow(ctx, sql, args...), cancel: cancel}
+}
+
+func (t budgetTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	ctx, cancel, err := t.start(ctx)
+	if err != nil {
+		return errBatchResults{err}
+	}
+	return gatedBatchResults{BatchResults: t.Tx.SendBatch(ctx, b), release: cancel}
+}
+
+func (t budgetTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	ctx, cancel, err := t.start(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer cancel()
+
+	return t.Tx.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (t budgetTx) copyTo(ctx context.Context, w io.Writer, sql string) (pgconn.CommandTag, error) {
+	ctx, cancel, err := t.start(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer cancel()
+
+	return copyToTx(ctx, t.Tx, w, sql)
+}
@@ -0,0 +1,135 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// rollbackRecorderTx — транзакция, которая, как pgx, не выполняет ROLLBACK на отменённом контексте.
+type rollbackRecorderTx struct {
+	pgx.Tx
+	rolledBack bool
+}
+
+func (t *rollbackRecorderTx) Rollback(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	t.rolledBack = true
+	return nil
+}
+
+func (t *rollbackRecorderTx) Commit(context.Context) error {
+	return errors.New("unexpected commit")
+}
+
+type stubTransactor struct {
+	tx pgx.Tx
+}
+
+func (s stubTransactor) BeginTx(context.Context, pgx.TxOptions) (pgx.Tx, error) {
+	return s.tx, nil
+}
+
+func TestBudgetExceeded(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  TxOption
+		fn   Handler
+	}{
+		{
+			name: "WithBudget query interrupted",
+			opt:  WithBudget(10*time.Millisecond, 0),
+			fn: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+		{
+			name: "WithTimeout query interrupted",
+			opt:  WithTimeout(10 * time.Millisecond),
+			fn: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+		{
+			name: "WithTimeout handler without queries",
+			opt:  WithTimeout(10 * time.Millisecond),
+			fn: func(ctx context.Context) error {
+				time.Sleep(20 * time.Millisecond)
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &rollbackRecorderTx{}
+			m := newTransactionManager(stubTransactor{tx: tx}, nil)
+
+			err := m.ReadCommitted(context.Background(), tt.fn, tt.opt)
+			if !errors.Is(err, ErrTxBudgetExceeded) {
+				t.Fatalf("got %v, want ErrTxBudgetExceeded", err)
+			}
+			if !tx.rolledBack {
+				t.Fatal("transaction was not rolled back")
+			}
+		})
+	}
+}
+
+// copyRecorderTx запоминает, был ли у COPY дедлайн.
+type copyRecorderTx struct {
+	pgx.Tx
+	deadline bool
+}
+
+func (t *copyRecorderTx) copyTo(ctx context.Context, _ io.Writer, _ string) (pgconn.CommandTag, error) {
+	_, t.deadline = ctx.Deadline()
+	return pgconn.CommandTag{}, nil
+}
+
+func TestCopyToInBudgetedTx(t *testing.T) {
+	inner := &copyRecorderTx{}
+	key := &instanceTxKey{}
+	ctx := withTx(context.Background(), key, newGuardedTx(budgetTx{Tx: inner, stmt: time.Second}))
+
+	if _, err := (pgTransactor{key: key}).CopyTo(ctx, io.Discard, "COPY t TO STDOUT"); err != nil {
+		t.Fatal(err)
+	}
+	if !inner.deadline {
+		t.Error("COPY inside a budgeted transaction has no deadline")
+	}
+}
+
+func TestBudgetTxClampsStatementTimeout(t *testing.T) {
+	inner := &execRecorderTx{}
+	tx := budgetTx{Tx: inner, stmt: time.Second}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := tx.Exec(ctx, "SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.sql) != 1 {
+		t.Fatalf("budget above the cap: got %q, want only the statement", inner.sql)
+	}
+
+	inner.sql = nil
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := tx.Exec(ctx, "SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.sql) != 2 || !strings.Contains(inner.sql[0], "statement_timeout") {
+		t.Fatalf("budget below the cap: got %q, want statement_timeout before the statement", inner.sql)
+	}
+}
@@ -0,0 +1,92 @@
+package pgfx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// UpdateMany обновляет строки items одним UPDATE ... FROM вместо цикла Update:
+// значения загружаются через COPY во временную таблицу с типами колонок целевой таблицы,
+// после чего выполняется
+//
+//	UPDATE t SET col = tmp.col, ... FROM tmp WHERE t.pk = tmp.pk
+//
+// Обновляются те же колонки, что и в Update (все, кроме pk и auto); v при этом не перечитывается.
+// Всё выполняется в одной транзакции (или на savepoint уже активной транзакции из контекста).
+// Возвращает количество обновлённых строк; элементы, ключей которых нет в таблице, пропускаются.
+func (r *Repository[T]) UpdateMany(ctx context.Context, items []T) (int64, error) {
+	fields := r.meta.filter(func(f structField) bool { return !f.has("pk") && !f.has("auto") })
+	if len(fields) == 0 || len(items) == 0 {
+		return 0, nil
+	}
+	columns := append(append([]structField(nil), r.pk...), fields...)
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return 0, fmt.Errorf("repository - UpdateMany: %w", err)
+	}
+	tmpName := "pgfx_bulk_" + hex.EncodeToString(suffix)
+	tmp := quoteIdent(tmpName)
+
+	rows := make([][]any, len(items))
+	names := make([]string, len(columns))
+	for i, f := range columns {
+		names[i] = f.column
+	}
+	for i := range items {
+		values, err := r.values(reflect.ValueOf(&items[i]).Elem(), columns)
+		if err != nil {
+			return 0, fmt.Errorf("repository - UpdateMany: %w", err)
+		}
+		rows[i] = values
+	}
+
+	sets := make([]string, len(fields))
+	for i, f := range fields {
+		sets[i] = fmt.Sprintf("%s = %s.%s", quoteIdent(f.column), tmp, quoteIdent(f.column))
+	}
+	conds := make([]string, len(r.pk))
+	for i, f := range r.pk {
+		conds[i] = fmt.Sprintf("%s.%s = %s.%s", r.table, quoteIdent(f.column), tmp, quoteIdent(f.column))
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("repository - UpdateMany - begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// CREATE TABLE AS ... WITH NO DATA копирует только типы колонок, без ограничений и значений по умолчанию.
+	create := fmt.Sprintf(`CREATE TEMP TABLE %s ON COMMIT DROP AS SELECT %s FROM %s WITH NO DATA`,
+		tmp, columnList(columns), r.table)
+	if _, err := tx.Exec(ctx, create); err != nil {
+		return 0, fmt.Errorf("repository - UpdateMany - create temp table: %w", err)
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{tmpName}, names, pgx.CopyFromRows(rows)); err != nil {
+		return 0, fmt.Errorf("repository - UpdateMany - copy: %w", err)
+	}
+
+	update := fmt.Sprintf(`UPDATE %s SET %s FROM %s WHERE %s`,
+		r.table, strings.Join(sets, ", "), tmp, strings.Join(conds, " AND "))
+	tag, err := tx.Exec(ctx, update)
+	if err != nil {
+		return 0, fmt.Errorf("repository - UpdateMany: %w", err)
+	}
+
+	// Внутри внешней транзакции ON COMMIT DROP сработает только при её фиксации.
+	if _, err := tx.Exec(ctx, "DROP TABLE "+tmp); err != nil {
+		return 0, fmt.Errorf("repository - UpdateMany - drop temp table: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("repository - UpdateMany - commit: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
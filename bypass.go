@@ -0,0 +1,34 @@
+package pgfx
+
+import (
+	"context"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TxBypassHandler вызывается, когда запрос выполнен мимо активной транзакции.
+type TxBypassHandler func(ctx context.Context, sql string)
+
+// bypassTracer находит запросы, которые выполняются с контекстом активной транзакции,
+// но на другом соединении. Обычно это значит, что код обратился к pg.Pool напрямую
+// вместо TransactionalPool, и запрос не попадёт в транзакцию (не откатится вместе с ней,
+// не увидит её незакоммиченные изменения или вовсе заблокируется на её же строках).
+type bypassTracer struct {
+	handler TxBypassHandler
+	key     any
+}
+
+func (t bypassTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	tx, ok := txFrom(ctx, t.key)
+	if ok && tx.Conn() != conn {
+		t.handler(ctx, data.SQL)
+	}
+	return ctx
+}
+
+func (t bypassTracer) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
+
+func logTxBypass(_ context.Context, sql string) {
+	log.Printf("pgfx: query executed outside of the active transaction (use TransactionalPool instead of Pool): %s", sql)
+}
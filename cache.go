@@ -0,0 +1,178 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// _tableChangePrefix — префикс ключей инвалидации, которые отправляют триггеры NotifyTableChanges.
+const _tableChangePrefix = "table:"
+
+// _tableChangeFunction — триггерная функция NotifyTableChanges. Канал передаётся аргументом
+// триггера, поэтому одна функция обслуживает все шины.
+const _tableChangeFunction = `
+CREATE OR REPLACE FUNCTION pgfx_notify_table_change() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+	PERFORM pg_notify(TG_ARGV[0], 'table:' || TG_TABLE_NAME);
+	RETURN NULL;
+END
+$$`
+
+// TableChangeKey — ключ инвалидации, который публикуется при изменении таблицы table
+// (без схемы), если для неё включён NotifyTableChanges.
+func TableChangeKey(table string) string {
+	return _tableChangePrefix + unqualifiedTable(table)
+}
+
+func unqualifiedTable(table string) string {
+	if dot := strings.LastIndexByte(table, '.'); dot >= 0 {
+		table = table[dot+1:]
+	}
+	return strings.ToLower(table)
+}
+
+// NotifyTableChanges устанавливает на таблицы tables триггеры, которые после каждой изменяющей
+// команды (INSERT, UPDATE, DELETE, TRUNCATE) публикуют в канал шины ключ TableChangeKey(table).
+// Триггеры уровня команды срабатывают один раз на команду, а уведомления доставляются после
+// коммита и схлопываются в рамках транзакции, поэтому массовые изменения не порождают шторм.
+// Повторный вызов пересоздаёт триггеры.
+func (b *InvalidationBus) NotifyTableChanges(ctx context.Context, tables ...string) error {
+	tx, err := b.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("invalidation bus - NotifyTableChanges - begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, _tableChangeFunction); err != nil {
+		return fmt.Errorf("invalidation bus - NotifyTableChanges - create function: %w", err)
+	}
+
+	trigger := quoteIdent("pgfx_notify_" + b.channel)
+	for _, table := range tables {
+		if _, err := tx.Exec(ctx, fmt.Sprintf(`DROP TRIGGER IF EXISTS %s ON %s`, trigger, quoteTable(table))); err != nil {
+			return fmt.Errorf("invalidation bus - NotifyTableChanges - %s: %w", table, err)
+		}
+		create := fmt.Sprintf(`CREATE TRIGGER %s AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON %s
+			FOR EACH STATEMENT EXECUTE FUNCTION pgfx_notify_table_change(%s)`,
+			trigger, quoteTable(table), quoteLiteral(b.channel))
+		if _, err := tx.Exec(ctx, create); err != nil {
+			return fmt.Errorf("invalidation bus - NotifyTableChanges - %s: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("invalidation bus - NotifyTableChanges - commit: %w", err)
+	}
+	return nil
+}
+
+// QueryCache — кэш результатов запросов к редко меняющимся (справочным) таблицам, который
+// сбрасывается уведомлениями об изменении таблиц, а не по TTL:
+//
+//	bus := pg.NewInvalidationBus("cache")
+//	_ = bus.NotifyTableChanges(ctx, "countries", "currencies")
+//	countries := pgfx.NewQueryCache[[]Country]()
+//	go bus.Subscribe(ctx, countries.Invalidate)
+//
+//	list, err := countries.Get(ctx, "all", []string{"countries"}, func(ctx context.Context) ([]Country, error) {
+//	    return repo.ListCountries(ctx)
+//	})
+//
+// Между коммитом изменения и доставкой уведомления кэш может отдавать старое значение
+// (обычно миллисекунды). После переподключения подписки кэш сбрасывается целиком.
+type QueryCache[T any] struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry[T]
+	// gen — поколения таблиц и всего кэша: значение, загрузка которого началась до
+	// инвалидации, уже устарело и не сохраняется.
+	gen    map[string]uint64
+	allGen uint64
+}
+
+type cacheEntry[T any] struct {
+	value  T
+	tables []string
+}
+
+// NewQueryCache создаёт пустой кэш.
+func NewQueryCache[T any]() *QueryCache[T] {
+	return &QueryCache[T]{
+		entries: make(map[string]cacheEntry[T]),
+		gen:     make(map[string]uint64),
+	}
+}
+
+// Get возвращает значение по ключу key, при отсутствии загружая его через load. tables —
+// таблицы, от которых зависит значение: его изменение (TableChangeKey) сбрасывает запись.
+// Ошибки load не кэшируются. Значение, загруженное внутри транзакции, тоже не сохраняется:
+// оно может содержать незафиксированные изменения этой транзакции.
+func (c *QueryCache[T]) Get(ctx context.Context, key string, tables []string, load func(ctx context.Context) (T, error)) (T, error) {
+	names := make([]string, len(tables))
+	for i, t := range tables {
+		names[i] = unqualifiedTable(t)
+	}
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+		return e.value, nil
+	}
+	gens := c.generations(names)
+	c.mu.Unlock()
+
+	v, err := load(ctx)
+	if err != nil || InTransaction(ctx) {
+		return v, err
+	}
+
+	c.mu.Lock()
+	if c.generations(names) == gens {
+		c.entries[key] = cacheEntry[T]{value: v, tables: names}
+	}
+	c.mu.Unlock()
+
+	return v, nil
+}
+
+// generations возвращает сводное поколение таблиц; вызывается под c.mu.
+func (c *QueryCache[T]) generations(tables []string) uint64 {
+	sum := c.allGen
+	for _, t := range tables {
+		sum += c.gen[t]
+	}
+	return sum
+}
+
+// Invalidate обрабатывает ключ инвалидации: TableChangeKey сбрасывает записи, зависящие от таблицы,
+// InvalidateAll — весь кэш, любой другой ключ — запись с этим ключом. Подходит как обработчик
+// InvalidationBus.Subscribe.
+func (c *QueryCache[T]) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case key == InvalidateAll:
+		c.allGen++
+		clear(c.entries)
+	case strings.HasPrefix(key, _tableChangePrefix):
+		table := strings.TrimPrefix(key, _tableChangePrefix)
+		c.gen[table]++
+		for k, e := range c.entries {
+			for _, t := range e.tables {
+				if t == table {
+					delete(c.entries, k)
+					break
+				}
+			}
+		}
+	default:
+		// Загрузка, начатая до инвалидации ключа, всё равно сохранится — как и при
+		// ручном удалении; для значений, зависящих от таблиц, используйте TableChangeKey.
+		delete(c.entries, key)
+	}
+}
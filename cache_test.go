@@ -0,0 +1,77 @@
+package pgfx
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQueryCacheInvalidation(t *testing.T) {
+	ctx := context.Background()
+	c := NewQueryCache[int]()
+
+	loads := 0
+	load := func(context.Context) (int, error) {
+		loads++
+		return loads, nil
+	}
+
+	get := func(key string, tables ...string) int {
+		t.Helper()
+		v, err := c.Get(ctx, key, tables, load)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	if get("countries", "public.countries") != 1 || get("countries", "public.countries") != 1 {
+		t.Fatal("value must be cached")
+	}
+	get("currencies", "currencies")
+
+	c.Invalidate(TableChangeKey("countries"))
+	if get("countries", "public.countries") != 3 {
+		t.Error("table change must invalidate dependent entries")
+	}
+	if get("currencies", "currencies") != 2 {
+		t.Error("table change must not invalidate unrelated entries")
+	}
+
+	c.Invalidate(InvalidateAll)
+	if get("currencies", "currencies") != 4 {
+		t.Error("InvalidateAll must clear the cache")
+	}
+}
+
+func TestQueryCacheStaleLoad(t *testing.T) {
+	ctx := context.Background()
+	c := NewQueryCache[string]()
+
+	_, _ = c.Get(ctx, "k", []string{"t"}, func(context.Context) (string, error) {
+		// Таблица изменилась, пока значение загружалось.
+		c.Invalidate(TableChangeKey("t"))
+		return "stale", nil
+	})
+
+	v, _ := c.Get(ctx, "k", []string{"t"}, func(context.Context) (string, error) { return "fresh", nil })
+	if v != "fresh" {
+		t.Errorf("Get() = %q, stale value must not be cached", v)
+	}
+}
+
+func TestQueryCacheSkipsTransactionLoads(t *testing.T) {
+	c := NewQueryCache[int]()
+	loads := 0
+	load := func(context.Context) (int, error) {
+		loads++
+		return loads, nil
+	}
+
+	txCtx := MakeContextTx(context.Background(), &stubTx{})
+	if v, _ := c.Get(txCtx, "countries", []string{"countries"}, load); v != 1 {
+		t.Fatalf("got %d, want 1", v)
+	}
+	if v, _ := c.Get(context.Background(), "countries", []string{"countries"}, load); v != 2 {
+		t.Error("value loaded inside a transaction must not be cached")
+	}
+}
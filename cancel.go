@@ -0,0 +1,107 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CancelFilter отбирает сессии пула для CancelAll.
+type CancelFilter struct {
+	// MinDuration — отменять только запросы, выполняющиеся не меньше указанного времени.
+	MinDuration time.Duration
+	// QueryLike — шаблон ILIKE для текста запроса ("%FROM orders%"); пусто — любые запросы.
+	QueryLike string
+	// IncludeIdleInTransaction — отменять также сессии в состоянии "idle in transaction".
+	IncludeIdleInTransaction bool
+	// Terminate завершает сессии целиком (pg_terminate_backend) вместо отмены текущего
+	// запроса (pg_cancel_backend). Соединения пула при этом пересоздаются.
+	Terminate bool
+}
+
+// backendSet — PID серверных процессов соединений пула.
+type backendSet struct {
+	mu   sync.Mutex
+	pids map[uint32]struct{}
+}
+
+func (s *backendSet) add(pid uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pids == nil {
+		s.pids = make(map[uint32]struct{})
+	}
+	s.pids[pid] = struct{}{}
+}
+
+func (s *backendSet) remove(pid uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pids, pid)
+}
+
+func (s *backendSet) list() []uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]uint32, 0, len(s.pids))
+	for pid := range s.pids {
+		out = append(out, pid)
+	}
+	return out
+}
+
+// CancelAll отменяет запросы (или завершает сессии) соединений этого пула, подходящие
+// под filter, и возвращает количество сессий, которым отправлен сигнал. Предназначен
+// для восстановления во время инцидентов с "убегающими" запросами.
+//
+// Сессии пула определяются по PID серверных процессов, которые pgfx запоминает при
+// создании соединений: это точнее, чем application_name, который совпадает у всех реплик
+// сервиса и меняется на время именованных транзакций (WithTxName).
+// Запрос выполняется на отдельном соединении, поэтому работает и при исчерпанном пуле.
+// За пулером соединений PID подменены, и CancelAll возвращает ErrBehindPooler.
+func (p *Postgres) CancelAll(ctx context.Context, filter CancelFilter) (int, error) {
+	if p.behindPooler.Load() {
+		return 0, fmt.Errorf("postgres - CancelAll: %w", ErrBehindPooler)
+	}
+
+	pids := p.backends.list()
+	if len(pids) == 0 {
+		return 0, nil
+	}
+
+	conn, err := pgx.ConnectConfig(ctx, p.Pool.Config().ConnConfig.Copy())
+	if err != nil {
+		return 0, fmt.Errorf("postgres - CancelAll - connect: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	signal := "pg_cancel_backend(pid)"
+	if filter.Terminate {
+		signal = "pg_terminate_backend(pid)"
+	}
+
+	states := []string{"active"}
+	if filter.IncludeIdleInTransaction {
+		states = append(states, "idle in transaction", "idle in transaction (aborted)")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT count(*) FILTER (WHERE %s)
+		FROM pg_stat_activity
+		WHERE pid = ANY($1)
+		  AND pid <> pg_backend_pid()
+		  AND state = ANY($2)
+		  AND now() - coalesce(query_start, now()) >= make_interval(secs => $3)
+		  AND ($4 = '' OR query ILIKE $4)`, signal)
+
+	var n int
+	if err := conn.QueryRow(ctx, query, pids, states, filter.MinDuration.Seconds(), filter.QueryLike).Scan(&n); err != nil {
+		return 0, fmt.Errorf("postgres - CancelAll: %w", err)
+	}
+	return n, nil
+}
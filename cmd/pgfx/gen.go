@@ -0,0 +1,342 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// genQuery — запрос из SQL-файла, размеченный комментарием "-- name: GetUser :one".
+type genQuery struct {
+	Name    string
+	Kind    string // one, many, exec, execrows
+	SQL     string
+	Params  []genField
+	Columns []genField
+	Source  string
+}
+
+type genField struct {
+	Name    string // имя поля/параметра в коде
+	Column  string // имя колонки
+	Type    string // Go-тип
+	Imports []string
+}
+
+var nameRe = regexp.MustCompile(`^--\s*name:\s*(\w+)\s+:(one|many|exec|execrows)\s*$`)
+
+// runGen читает SQL-файлы, описывает каждый запрос на живой базе (Prepare возвращает типы
+// параметров и колонок, а nullability колонок берётся из pg_attribute) и генерирует
+// типизированные методы поверх pgfx.QueryExecutor. Поскольку методы используют
+// QueryExecutor, при передаче pg.TransactionalPool они участвуют в транзакциях TxManager.
+func runGen(args []string) error {
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	dsn := fs.String("dsn", os.Getenv("PGFX_DSN"), "строка подключения к базе со схемой (по умолчанию $PGFX_DSN)")
+	pkg := fs.String("pkg", "queries", "имя пакета генерируемого файла")
+	out := fs.String("out", "", "файл результата (по умолчанию stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no SQL files")
+	}
+	if *dsn == "" {
+		return fmt.Errorf("-dsn is required")
+	}
+
+	var queries []*genQuery
+	for _, file := range fs.Args() {
+		qs, err := parseQueryFile(file)
+		if err != nil {
+			return err
+		}
+		queries = append(queries, qs...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	conn, err := pgx.Connect(ctx, *dsn)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	for _, q := range queries {
+		if err := describeQuery(ctx, conn, q); err != nil {
+			return fmt.Errorf("%s: %s: %w", q.Source, q.Name, err)
+		}
+	}
+
+	src, err := render(*pkg, queries)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*out, src, 0o644)
+}
+
+func parseQueryFile(path string) ([]*genQuery, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		queries []*genQuery
+		cur     *genQuery
+		body    strings.Builder
+	)
+	flush := func() {
+		if cur != nil {
+			cur.SQL = strings.TrimSuffix(strings.TrimSpace(body.String()), ";")
+			queries = append(queries, cur)
+		}
+		body.Reset()
+	}
+
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		if m := nameRe.FindStringSubmatch(strings.TrimSpace(text)); m != nil {
+			flush()
+			cur = &genQuery{Name: m[1], Kind: m[2], Source: fmt.Sprintf("%s:%d", path, line)}
+			continue
+		}
+		if cur != nil {
+			body.WriteString(text)
+			body.WriteByte('\n')
+		}
+	}
+	flush()
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
+
+func describeQuery(ctx context.Context, conn *pgx.Conn, q *genQuery) error {
+	sd, err := conn.PgConn().Prepare(ctx, "", q.SQL, nil)
+	if err != nil {
+		return err
+	}
+
+	for i, oid := range sd.ParamOIDs {
+		t, imports := goType(oid, true)
+		q.Params = append(q.Params, genField{Name: fmt.Sprintf("arg%d", i+1), Type: t, Imports: imports})
+	}
+
+	if (q.Kind == "one" || q.Kind == "many") && len(sd.Fields) == 0 {
+		return fmt.Errorf(":%s query returns no columns", q.Kind)
+	}
+
+	for _, fd := range sd.Fields {
+		notNull, err := columnNotNull(ctx, conn, fd)
+		if err != nil {
+			return err
+		}
+		t, imports := goType(fd.DataTypeOID, notNull)
+		q.Columns = append(q.Columns, genField{Name: exportedName(fd.Name), Column: fd.Name, Type: t, Imports: imports})
+	}
+	return nil
+}
+
+// columnNotNull сообщает, объявлена ли колонка результата NOT NULL. Для вычисляемых
+// выражений (TableOID == 0) nullability неизвестна, и поле считается nullable.
+func columnNotNull(ctx context.Context, conn *pgx.Conn, fd pgconn.FieldDescription) (bool, error) {
+	if fd.TableOID == 0 {
+		return false, nil
+	}
+
+	var notNull bool
+	err := conn.QueryRow(ctx, `SELECT attnotnull FROM pg_attribute WHERE attrelid = $1 AND attnum = $2`,
+		fd.TableOID, int16(fd.TableAttributeNumber)).Scan(&notNull)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	return notNull, err
+}
+
+var _goTypes = map[uint32]string{
+	pgtype.BoolOID:        "bool",
+	pgtype.Int2OID:        "int16",
+	pgtype.Int4OID:        "int32",
+	pgtype.Int8OID:        "int64",
+	pgtype.Float4OID:      "float32",
+	pgtype.Float8OID:      "float64",
+	pgtype.TextOID:        "string",
+	pgtype.VarcharOID:     "string",
+	pgtype.BPCharOID:      "string",
+	pgtype.NameOID:        "string",
+	pgtype.ByteaOID:       "[]byte",
+	pgtype.JSONOID:        "[]byte",
+	pgtype.JSONBOID:       "[]byte",
+	pgtype.UUIDOID:        "[16]byte",
+	pgtype.DateOID:        "time.Time",
+	pgtype.TimestampOID:   "time.Time",
+	pgtype.TimestamptzOID: "time.Time",
+	pgtype.NumericOID:     "pgtype.Numeric",
+	pgtype.IntervalOID:    "pgtype.Interval",
+	pgtype.BoolArrayOID:   "[]bool",
+	pgtype.Int2ArrayOID:   "[]int16",
+	pgtype.Int4ArrayOID:   "[]int32",
+	pgtype.Int8ArrayOID:   "[]int64",
+	pgtype.TextArrayOID:   "[]string",
+}
+
+// goType возвращает Go-тип для OID; nullable скаляры становятся указателями.
+// Остальные типы (перечисления, составные, диапазоны) отображаются в any.
+func goType(oid uint32, notNull bool) (string, []string) {
+	t, ok := _goTypes[oid]
+	if !ok {
+		return "any", nil
+	}
+
+	var imports []string
+	switch {
+	case strings.HasPrefix(t, "time."):
+		imports = []string{"time"}
+	case strings.HasPrefix(t, "pgtype."):
+		imports = []string{"github.com/jackc/pgx/v5/pgtype"}
+	}
+
+	// pgtype.* уже различают NULL, срезы представляют NULL как nil.
+	if !notNull && !strings.HasPrefix(t, "pgtype.") && !strings.HasPrefix(t, "[]") {
+		t = "*" + t
+	}
+	return t, imports
+}
+
+var _initialisms = map[string]string{"id": "ID", "url": "URL", "uuid": "UUID", "api": "API", "json": "JSON", "sql": "SQL", "http": "HTTP", "ip": "IP"}
+
+func exportedName(column string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(column, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if v, ok := _initialisms[strings.ToLower(part)]; ok {
+			b.WriteString(v)
+			continue
+		}
+		r := []rune(part)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	if b.Len() == 0 || unicode.IsDigit([]rune(b.String())[0]) {
+		return "Col" + b.String()
+	}
+	return b.String()
+}
+
+var genTemplate = template.Must(template.New("gen").Parse(`// Code generated by pgfx gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+{{range .Imports}}	"{{.}}"
+{{end}}
+	"github.com/fr11nik/pgfx"
+	"github.com/jackc/pgx/v5"
+)
+
+// Queries — типизированные запросы поверх pgfx.QueryExecutor. Передайте pg.TransactionalPool,
+// чтобы запросы выполнялись в транзакциях TxManager из контекста.
+type Queries struct {
+	db pgfx.QueryExecutor
+}
+
+func New(db pgfx.QueryExecutor) *Queries {
+	return &Queries{db: db}
+}
+{{range .Queries}}
+const sql{{.Name}} = {{printf "%q" .SQL}}
+{{if or (eq .Kind "one") (eq .Kind "many")}}
+type {{.Name}}Row struct {
+{{- range .Columns}}
+	{{.Name}} {{.Type}} ` + "`db:\"{{.Column}}\"`" + `
+{{- end}}
+}
+{{end}}
+// {{.Name}} — {{.Source}}.
+{{- if eq .Kind "one"}}
+func (q *Queries) {{.Name}}(ctx context.Context{{range .Params}}, {{.Name}} {{.Type}}{{end}}) ({{.Name}}Row, error) {
+	rows, err := q.db.Query(ctx, sql{{.Name}}{{range .Params}}, {{.Name}}{{end}})
+	if err != nil {
+		return {{.Name}}Row{}, err
+	}
+	return pgx.CollectOneRow(rows, pgx.RowToStructByPos[{{.Name}}Row])
+}
+{{- else if eq .Kind "many"}}
+func (q *Queries) {{.Name}}(ctx context.Context{{range .Params}}, {{.Name}} {{.Type}}{{end}}) ([]{{.Name}}Row, error) {
+	rows, err := q.db.Query(ctx, sql{{.Name}}{{range .Params}}, {{.Name}}{{end}})
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[{{.Name}}Row])
+}
+{{- else if eq .Kind "execrows"}}
+func (q *Queries) {{.Name}}(ctx context.Context{{range .Params}}, {{.Name}} {{.Type}}{{end}}) (int64, error) {
+	tag, err := q.db.Exec(ctx, sql{{.Name}}{{range .Params}}, {{.Name}}{{end}})
+	return tag.RowsAffected(), err
+}
+{{- else}}
+func (q *Queries) {{.Name}}(ctx context.Context{{range .Params}}, {{.Name}} {{.Type}}{{end}}) error {
+	_, err := q.db.Exec(ctx, sql{{.Name}}{{range .Params}}, {{.Name}}{{end}})
+	return err
+}
+{{- end}}
+{{end}}`))
+
+func render(pkg string, queries []*genQuery) ([]byte, error) {
+	seen := make(map[string]bool)
+	var imports []string
+	usesPgx := false
+	for _, q := range queries {
+		if q.Kind == "one" || q.Kind == "many" {
+			usesPgx = true
+		}
+		for _, f := range append(append([]genField(nil), q.Params...), q.Columns...) {
+			for _, imp := range f.Imports {
+				if !seen[imp] {
+					seen[imp] = true
+					imports = append(imports, imp)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	err := genTemplate.Execute(&buf, map[string]any{"Package": pkg, "Queries": queries, "Imports": imports})
+	if err != nil {
+		return nil, err
+	}
+
+	src := buf.Bytes()
+	if !usesPgx {
+		src = bytes.Replace(src, []byte("\t\"github.com/jackc/pgx/v5\"\n"), nil, 1)
+	}
+
+	formatted, err := format.Source(src)
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w\n%s", err, src)
+	}
+	return formatted, nil
+}
@@ -0,0 +1,39 @@
+// Команда pgfx — инструменты разработчика для пакета pgfx.
+//
+// Использование:
+//
+//	pgfx gen -dsn postgres://... -pkg queries -out queries.gen.go queries/*.sql
+//
+// Подходит для go:generate:
+//
+//	//go:generate go run github.com/fr11nik/pgfx/cmd/pgfx gen -dsn $PGFX_DSN -pkg store -out queries.gen.go queries.sql
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "gen":
+		err = runGen(os.Args[2:])
+	default:
+		usage()
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pgfx %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: pgfx gen [flags] files...")
+	os.Exit(2)
+}
@@ -0,0 +1,154 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// _defaultColumnarBatch — размер пакета QueryColumnar, если batchSize <= 0.
+const _defaultColumnarBatch = 10000
+
+// Column — одна колонка пакета строк в колоночном виде.
+type Column struct {
+	// Name — имя колонки, OID — OID её типа в PostgreSQL.
+	Name string
+	OID  uint32
+	// Values — срез значений колонки. Тип среза зависит от типа колонки:
+	// []int64 (int2, int4, int8), []float64 (float4, float8), []bool, []string (text, varchar,
+	// bpchar, name, uuid), []time.Time (date, timestamp, timestamptz), иначе []any со значениями,
+	// как их возвращает pgx.
+	Values any
+	// Nulls[i] == true, если значение в строке i — NULL (в Values при этом нулевое значение).
+	Nulls []bool
+}
+
+// ColumnBatch — пакет строк результата в колоночном виде (struct-of-slices).
+// Срезы колонок легко передаются в DataFrame-библиотеки или в Arrow-билдеры без построчной конвертации.
+type ColumnBatch struct {
+	Len     int
+	Columns []Column
+}
+
+// QueryColumnar выполняет запрос и передаёт результат в fn пакетами по batchSize строк
+// (по умолчанию 10000) в колоночном виде. Срезы пакетов не переиспользуются, поэтому fn
+// может сохранить их после возврата.
+//
+// Возвращает общее количество строк.
+func QueryColumnar(ctx context.Context, db QueryExecutor, batchSize int, fn func(*ColumnBatch) error, sql string, args ...any) (int64, error) {
+	if batchSize <= 0 {
+		batchSize = _defaultColumnarBatch
+	}
+
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("query columnar: %w", err)
+	}
+	defer rows.Close()
+
+	fields := rows.FieldDescriptions()
+	builders := make([]*columnBuilder, len(fields))
+	newBatch := func() {
+		for i, fd := range fields {
+			builders[i] = newColumnBuilder(fd.DataTypeOID, batchSize)
+		}
+	}
+	emit := func(n int) error {
+		batch := &ColumnBatch{Len: n, Columns: make([]Column, len(fields))}
+		for i, fd := range fields {
+			batch.Columns[i] = Column{Name: fd.Name, OID: fd.DataTypeOID, Values: builders[i].values(), Nulls: builders[i].nulls}
+		}
+		return fn(batch)
+	}
+
+	newBatch()
+	var total int64
+	n := 0
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return total, fmt.Errorf("query columnar: read row: %w", err)
+		}
+		for i, v := range values {
+			if err := builders[i].append(v); err != nil {
+				return total, fmt.Errorf("query columnar: column %q: %w", fields[i].Name, err)
+			}
+		}
+
+		n++
+		total++
+		if n == batchSize {
+			if err := emit(n); err != nil {
+				return total, err
+			}
+			newBatch()
+			n = 0
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return total, fmt.Errorf("query columnar: %w", err)
+	}
+
+	if n > 0 {
+		if err := emit(n); err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+type columnBuilder struct {
+	nulls  []bool
+	append func(v any) error
+	values func() any
+}
+
+func newColumnBuilder(oid uint32, capacity int) *columnBuilder {
+	b := &columnBuilder{nulls: make([]bool, 0, capacity)}
+
+	switch oid {
+	case pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID:
+		s := make([]int64, 0, capacity)
+		bindColumn(b, &s, func(v any) (int64, bool) { return toInt64(v) })
+	case pgtype.Float4OID, pgtype.Float8OID:
+		s := make([]float64, 0, capacity)
+		bindColumn(b, &s, func(v any) (float64, bool) { return toFloat64(v) })
+	case pgtype.BoolOID:
+		s := make([]bool, 0, capacity)
+		bindColumn(b, &s, func(v any) (bool, bool) { x, ok := v.(bool); return x, ok })
+	case pgtype.TextOID, pgtype.VarcharOID, pgtype.BPCharOID, pgtype.NameOID, pgtype.UUIDOID:
+		s := make([]string, 0, capacity)
+		bindColumn(b, &s, func(v any) (string, bool) { return toProtoString(v), true })
+	case pgtype.DateOID, pgtype.TimestampOID, pgtype.TimestamptzOID:
+		s := make([]time.Time, 0, capacity)
+		bindColumn(b, &s, func(v any) (time.Time, bool) { x, ok := v.(time.Time); return x, ok })
+	default:
+		s := make([]any, 0, capacity)
+		bindColumn(b, &s, func(v any) (any, bool) { return v, true })
+	}
+
+	return b
+}
+
+// bindColumn — generic-часть columnBuilder; вынесена в функцию, так как методы не могут быть generic.
+func bindColumn[T any](b *columnBuilder, s *[]T, conv func(any) (T, bool)) {
+	b.append = func(v any) error {
+		var zero T
+		if v == nil {
+			*s = append(*s, zero)
+			b.nulls = append(b.nulls, true)
+			return nil
+		}
+		x, ok := conv(v)
+		if !ok {
+			return fmt.Errorf("unexpected value type %T for %T column", v, zero)
+		}
+		*s = append(*s, x)
+		b.nulls = append(b.nulls, false)
+		return nil
+	}
+	b.values = func() any { return *s }
+}
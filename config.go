@@ -0,0 +1,141 @@
+package pgfx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// ErrImmutableSetting возвращается ApplyConfig при попытке изменить настройку,
+// которая фиксируется при создании пула.
+var ErrImmutableSetting = errors.New("setting cannot be changed at runtime")
+
+// Config — настройки, которые можно менять без перезапуска сервиса через ApplyConfig.
+// Нулевые значения полей означают "оставить как есть".
+type Config struct {
+	// MaxPoolSize — размер пула. pgxpool не позволяет менять его у работающего пула,
+	// поэтому значение, отличное от текущего, приводит к ErrImmutableSetting.
+	// Поле оставлено, чтобы один и тот же файл конфигурации мог описывать все настройки.
+	MaxPoolSize int32
+	// ConnTimeout — таймаут установки новых соединений.
+	ConnTimeout time.Duration
+	// Tracing включает или выключает трейсинг запросов (см. WithTracer). Метрики пула
+	// регистрируются при первом включении и после выключения продолжают собираться.
+	Tracing *bool
+}
+
+// ApplyConfig применяет изменяемые настройки к работающему экземпляру.
+//
+// Новый ConnTimeout действует для соединений, открытых после вызова; уже
+// установленные соединения не переоткрываются. Переключение трейсинга влияет
+// на запросы, начатые после вызова. Изменение MaxPoolSize не поддерживается: остальные
+// настройки cfg всё равно применяются, а затем возвращается ErrImmutableSetting.
+func (p *Postgres) ApplyConfig(cfg Config) error {
+	if cfg.ConnTimeout > 0 {
+		p.liveConnTimeout.Store(int64(cfg.ConnTimeout))
+	}
+
+	if cfg.Tracing != nil {
+		if *cfg.Tracing {
+			if err := p.recordStats(); err != nil {
+				return fmt.Errorf("postgres - ApplyConfig - record stats: %w", err)
+			}
+		}
+		p.qt.SetEnabled(*cfg.Tracing)
+	}
+
+	if cfg.MaxPoolSize != 0 && cfg.MaxPoolSize != p.maxPoolSize {
+		return fmt.Errorf("postgres - ApplyConfig - MaxPoolSize %d -> %d: %w", p.maxPoolSize, cfg.MaxPoolSize, ErrImmutableSetting)
+	}
+	return nil
+}
+
+// ConfigSource — источник изменяемых настроек (файл, Consul, etcd и т.п.).
+type ConfigSource interface {
+	Load(ctx context.Context) (Config, error)
+}
+
+// WatchConfig периодически читает настройки из src и применяет их через ApplyConfig,
+// если они изменились с прошлого успешно применённого чтения. Ошибки чтения и применения
+// логируются, наблюдение продолжается до отмены ctx.
+//
+// Пример:
+//
+//	go pg.WatchConfig(ctx, pgfx.FileConfig("/etc/app/db.json"), 10*time.Second)
+func (p *Postgres) WatchConfig(ctx context.Context, src ConfigSource, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last *Config
+	for {
+		cfg, err := src.Load(ctx)
+		switch {
+		case err != nil:
+			log.Printf("Postgres config watcher: load: %v", err)
+		case last == nil || !sameConfig(*last, cfg):
+			if err := p.ApplyConfig(cfg); err != nil {
+				// last не обновляется: отклонённое изменение применяется повторно при следующем чтении.
+				log.Printf("Postgres config watcher: apply: %v", err)
+				break
+			}
+			last = &cfg
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func sameConfig(a, b Config) bool {
+	if a.MaxPoolSize != b.MaxPoolSize || a.ConnTimeout != b.ConnTimeout {
+		return false
+	}
+	if a.Tracing == nil || b.Tracing == nil {
+		return a.Tracing == b.Tracing
+	}
+	return *a.Tracing == *b.Tracing
+}
+
+// FileConfig — ConfigSource, читающий настройки из JSON-файла вида:
+//
+//	{"max_pool_size": 10, "conn_timeout": "500ms", "tracing": true}
+type FileConfig string
+
+type fileConfig struct {
+	MaxPoolSize int32  `json:"max_pool_size"`
+	ConnTimeout string `json:"conn_timeout"`
+	Tracing     *bool  `json:"tracing"`
+}
+
+// Load читает и разбирает файл конфигурации.
+func (f FileConfig) Load(_ context.Context) (Config, error) {
+	b, err := os.ReadFile(string(f))
+	if err != nil {
+		return Config{}, err
+	}
+
+	var fc fileConfig
+	if err := json.Unmarshal(b, &fc); err != nil {
+		return Config{}, fmt.Errorf("parse %s: %w", string(f), err)
+	}
+
+	cfg := Config{
+		MaxPoolSize: fc.MaxPoolSize,
+		Tracing:     fc.Tracing,
+	}
+	if fc.ConnTimeout != "" {
+		cfg.ConnTimeout, err = time.ParseDuration(fc.ConnTimeout)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse %s: conn_timeout: %w", string(f), err)
+		}
+	}
+
+	return cfg, nil
+}
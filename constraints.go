@@ -0,0 +1,75 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrConstraintNotFound возвращается DeferConstraints, если ограничения нет в схемах search_path.
+	ErrConstraintNotFound = errors.New("constraint not found")
+	// ErrConstraintNotDeferrable возвращается DeferConstraints для ограничения без DEFERRABLE:
+	// SET CONSTRAINTS его молча не откладывает.
+	ErrConstraintNotDeferrable = errors.New("constraint is not deferrable")
+)
+
+// DeferConstraints откладывает проверку ограничений names до COMMIT текущей транзакции
+// (SET CONSTRAINTS ... DEFERRED) — например, чтобы вставить граф строк с циклическими
+// внешними ключами в любом порядке. Без names откладываются все отложенные (DEFERRABLE) ограничения.
+//
+// Имена можно указывать со схемой ("billing.invoices_customer_fk"). Перед откладыванием
+// проверяется, что каждое ограничение существует и объявлено DEFERRABLE: иначе SET CONSTRAINTS
+// либо падает на опечатке, либо молча оставляет проверку немедленной.
+// Работает только внутри транзакции db (обычно TransactionalPool), иначе возвращает ErrNoTransaction.
+func DeferConstraints(ctx context.Context, db QueryExecutor, names ...string) error {
+	tx, ok := txFrom(ctx, executorTxKey(db))
+	if !ok {
+		return fmt.Errorf("defer constraints: %w", ErrNoTransaction)
+	}
+
+	if len(names) == 0 {
+		if _, err := tx.Exec(ctx, `SET CONSTRAINTS ALL DEFERRED`); err != nil {
+			return fmt.Errorf("defer constraints: %w", err)
+		}
+		return nil
+	}
+
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		schema, conname := "", name
+		if dot := strings.LastIndexByte(name, '.'); dot >= 0 {
+			schema, conname = name[:dot], name[dot+1:]
+		}
+
+		var found, deferrable bool
+		err := tx.QueryRow(ctx, `
+			SELECT count(*) > 0, coalesce(bool_and(c.condeferrable), false)
+			FROM pg_constraint c
+			JOIN pg_namespace n ON n.oid = c.connamespace
+			WHERE c.conname = $1
+			  AND (n.nspname = $2 OR $2 = '' AND n.nspname = ANY(current_schemas(false)))`,
+			conname, schema).Scan(&found, &deferrable)
+		if err != nil {
+			return fmt.Errorf("defer constraints: %s: %w", name, err)
+		}
+		switch {
+		case !found:
+			return fmt.Errorf("defer constraints: %w: %s", ErrConstraintNotFound, name)
+		case !deferrable:
+			return fmt.Errorf("defer constraints: %w: %s", ErrConstraintNotDeferrable, name)
+		}
+
+		if schema != "" {
+			quoted[i] = quoteIdent(schema) + "." + quoteIdent(conname)
+		} else {
+			quoted[i] = quoteIdent(conname)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `SET CONSTRAINTS `+strings.Join(quoted, ", ")+` DEFERRED`); err != nil {
+		return fmt.Errorf("defer constraints: %w", err)
+	}
+	return nil
+}
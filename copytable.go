@@ -0,0 +1,200 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// _defaultCopyChunk — размер пачки CopyTable с ключом по умолчанию.
+const _defaultCopyChunk = 100000
+
+// CopyTableOptions настраивает CopyTable.
+type CopyTableOptions struct {
+	// Columns — копируемые колонки; пусто — все колонки таблицы-источника в порядке объявления.
+	Columns []string
+	// KeyColumn — колонка с уникальным упорядоченным ключом (обычно первичный ключ). С ним таблица
+	// копируется пачками по ChunkRows строк в порядке ключа, каждая пачка фиксируется в приёмнике
+	// отдельно, и прерванное копирование можно продолжить с CopyProgress.LastKey.
+	// Без ключа таблица копируется одним COPY.
+	KeyColumn string
+	// After — ключ (в текстовом виде), после которого продолжить копирование: LastKey
+	// из прогресса прерванного запуска.
+	After string
+	// ChunkRows — размер пачки при KeyColumn (по умолчанию 100000).
+	ChunkRows int
+	// Progress вызывается после каждой зафиксированной пачки.
+	Progress func(CopyProgress)
+}
+
+// CopyProgress — состояние CopyTable.
+type CopyProgress struct {
+	Rows  int64
+	Bytes int64
+	// LastKey — ключ последней скопированной строки (в текстовом виде), пусто без KeyColumn.
+	LastKey string
+	Elapsed time.Duration
+}
+
+// RowsPerSecond и BytesPerSecond — средняя пропускная способность с начала копирования.
+func (p CopyProgress) RowsPerSecond() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Rows) / p.Elapsed.Seconds()
+}
+
+func (p CopyProgress) BytesPerSecond() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Bytes) / p.Elapsed.Seconds()
+}
+
+// CopyTable переносит строки таблицы table из src в dst (например, при миграции между кластерами)
+// без dblink и промежуточных файлов: COPY ... TO STDOUT на источнике передаётся потоком
+// в COPY ... FROM STDIN на приёмнике в двоичном формате. Двоичный формат требует совпадения
+// типов колонок в обеих базах; таблица в приёмнике должна уже существовать.
+//
+// Возвращает итоговый прогресс; при ошибке он описывает уже зафиксированную часть,
+// и его LastKey можно передать в After для продолжения.
+func CopyTable(ctx context.Context, src, dst *Postgres, table string, opts CopyTableOptions) (CopyProgress, error) {
+	var progress CopyProgress
+	start := time.Now()
+
+	srcConn, err := src.Pool.Acquire(ctx)
+	if err != nil {
+		return progress, fmt.Errorf("copy table: acquire source: %w", err)
+	}
+	defer srcConn.Release()
+
+	dstConn, err := dst.Pool.Acquire(ctx)
+	if err != nil {
+		return progress, fmt.Errorf("copy table: acquire destination: %w", err)
+	}
+	defer dstConn.Release()
+
+	columns := opts.Columns
+	if len(columns) == 0 {
+		if columns, err = tableColumnNames(ctx, srcConn.Conn(), table); err != nil {
+			return progress, fmt.Errorf("copy table: columns: %w", err)
+		}
+	}
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdent(c)
+	}
+	list := strings.Join(quoted, ", ")
+	copyFrom := fmt.Sprintf("COPY %s (%s) FROM STDIN (FORMAT binary)", quoteTable(table), list)
+
+	chunk := func(selectSQL string) (int64, int64, error) {
+		pr, pw := io.Pipe()
+		var bytes atomic.Int64
+
+		srcErr := make(chan error, 1)
+		go func() {
+			_, err := srcConn.Conn().PgConn().CopyTo(ctx, countingWriter{w: pw, n: &bytes}, "COPY ("+selectSQL+") TO STDOUT (FORMAT binary)")
+			pw.CloseWithError(err)
+			srcErr <- err
+		}()
+
+		tag, err := dstConn.Conn().PgConn().CopyFrom(ctx, pr, copyFrom)
+		// Если приёмник завершился раньше, разблокируем источник и дожидаемся его:
+		// соединение источника нужно следующей пачке.
+		pr.CloseWithError(io.ErrClosedPipe)
+		if serr := <-srcErr; serr != nil && !errors.Is(serr, io.ErrClosedPipe) {
+			return 0, 0, fmt.Errorf("source: %w", serr)
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("destination: %w", err)
+		}
+		return tag.RowsAffected(), bytes.Load(), nil
+	}
+
+	report := func() {
+		progress.Elapsed = time.Since(start)
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+	}
+
+	if opts.KeyColumn == "" {
+		rows, bytes, err := chunk(fmt.Sprintf("SELECT %s FROM %s", list, quoteTable(table)))
+		if err != nil {
+			return progress, fmt.Errorf("copy table: %w", err)
+		}
+		progress.Rows, progress.Bytes = rows, bytes
+		report()
+		return progress, nil
+	}
+
+	size := opts.ChunkRows
+	if size <= 0 {
+		size = _defaultCopyChunk
+	}
+	key := quoteIdent(opts.KeyColumn)
+	progress.LastKey = opts.After
+	// На первой пачке без After нижней границы нет; сравнение с ключом делается в типе колонки,
+	// поэтому текстовое представление ключа из прогресса подходит и для чисел, и для uuid, и для дат.
+	hasLower := opts.After != ""
+
+	for {
+		where := "true"
+		if hasLower {
+			where = fmt.Sprintf("%s > %s", key, quoteLiteral(progress.LastKey))
+		}
+
+		var upper *string
+		err := srcConn.QueryRow(ctx, fmt.Sprintf(
+			`SELECT max(k)::text FROM (SELECT %s AS k FROM %s WHERE %s ORDER BY %s LIMIT %d) s`,
+			key, quoteTable(table), where, key, size)).Scan(&upper)
+		if err != nil {
+			return progress, fmt.Errorf("copy table: chunk bounds: %w", err)
+		}
+		if upper == nil {
+			return progress, nil
+		}
+
+		rows, bytes, err := chunk(fmt.Sprintf("SELECT %s FROM %s WHERE %s AND %s <= %s ORDER BY %s",
+			list, quoteTable(table), where, key, quoteLiteral(*upper), key))
+		if err != nil {
+			return progress, fmt.Errorf("copy table: after key %q: %w", progress.LastKey, err)
+		}
+
+		progress.Rows += rows
+		progress.Bytes += bytes
+		progress.LastKey = *upper
+		hasLower = true
+		report()
+	}
+}
+
+// tableColumnNames возвращает колонки таблицы в порядке объявления.
+func tableColumnNames(ctx context.Context, conn *pgx.Conn, table string) ([]string, error) {
+	rows, err := conn.Query(ctx, `
+		SELECT attname FROM pg_attribute
+		WHERE attrelid = $1::regclass AND attnum > 0 AND NOT attisdropped
+		ORDER BY attnum`, quoteTable(table))
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// countingWriter считает записанные байты.
+type countingWriter struct {
+	w io.Writer
+	n *atomic.Int64
+}
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(int64(n))
+	return n, err
+}
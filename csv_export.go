@@ -0,0 +1,164 @@
+package pgfx
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// CSVOptions настраивает формат выгрузки QueryToCSVWithOptions.
+type CSVOptions struct {
+	// Delimiter — разделитель колонок. По умолчанию ','.
+	Delimiter rune
+	// Null — строка, которой представляется NULL. По умолчанию пустая строка.
+	Null string
+	// NoHeader отключает первую строку с именами колонок.
+	NoHeader bool
+}
+
+// ErrInvalidCSVDelimiter возвращается, если разделитель не является однобайтовым символом
+// или совпадает с кавычкой/переводом строки.
+var ErrInvalidCSVDelimiter = errors.New("invalid csv delimiter")
+
+// copyToer реализуется исполнителями, которые умеют выполнять COPY ... TO STDOUT
+// на том же соединении, что и остальные запросы (в том числе внутри транзакции).
+type copyToer interface {
+	CopyTo(ctx context.Context, w io.Writer, sql string) (pgconn.CommandTag, error)
+}
+
+// sqlRewriter реализуется исполнителями, переписывающими запросы (см. QueryRewriter). CopyTo
+// получает готовую команду COPY, поэтому запрос внутри неё переписывается заранее.
+type sqlRewriter interface {
+	rewrite(ctx context.Context, sql string) string
+}
+
+// QueryToCSV выполняет запрос и пишет результат в w в формате CSV с заголовком,
+// разделителем ',' и пустой строкой вместо NULL.
+//
+// Подробности — в QueryToCSVWithOptions.
+func QueryToCSV(ctx context.Context, db QueryExecutor, w io.Writer, sql string, args ...any) (int64, error) {
+	return QueryToCSVWithOptions(ctx, db, w, CSVOptions{}, sql, args...)
+}
+
+// QueryToCSVWithOptions выполняет запрос и пишет результат в w в формате CSV.
+//
+// Если запрос не содержит параметров, а db — это TransactionalPool, выгрузка
+// выполняется через COPY (...) TO STDOUT: сервер сам формирует CSV, а данные
+// передаются в w потоком. В остальных случаях строки читаются обычным запросом
+// и кодируются на стороне клиента; текстовое представление значений в обоих
+// случаях совпадает с тем, что возвращает PostgreSQL.
+//
+// Возвращает количество выгруженных строк (без заголовка).
+func QueryToCSVWithOptions(ctx context.Context, db QueryExecutor, w io.Writer, opts CSVOptions, sql string, args ...any) (int64, error) {
+	if opts.Delimiter == 0 {
+		opts.Delimiter = ','
+	}
+	if opts.Delimiter >= utf8.RuneSelf || opts.Delimiter == '"' || opts.Delimiter == '\n' || opts.Delimiter == '\r' {
+		return 0, fmt.Errorf("query to csv: %w: %q", ErrInvalidCSVDelimiter, opts.Delimiter)
+	}
+
+	if c, ok := db.(copyToer); ok && len(args) == 0 {
+		if r, ok := db.(sqlRewriter); ok {
+			sql = r.rewrite(ctx, sql)
+		}
+		tag, err := c.CopyTo(ctx, w, copyToCSVStatement(sql, opts))
+		if err != nil {
+			return 0, fmt.Errorf("query to csv: copy: %w", err)
+		}
+		return tag.RowsAffected(), nil
+	}
+
+	return queryToCSV(ctx, db, w, opts, sql, args...)
+}
+
+func copyToCSVStatement(sql string, opts CSVOptions) string {
+	sql = strings.TrimRight(strings.TrimSpace(sql), ";")
+
+	return fmt.Sprintf("COPY (%s) TO STDOUT WITH (FORMAT csv, HEADER %t, DELIMITER %s, NULL %s)",
+		sql, !opts.NoHeader, quoteLiteral(string(opts.Delimiter)), quoteLiteral(opts.Null))
+}
+
+func queryToCSV(ctx context.Context, db QueryExecutor, w io.Writer, opts CSVOptions, sql string, args ...any) (int64, error) {
+	// Простой протокол возвращает значения в текстовом формате, который
+	// совпадает с тем, что отдал бы COPY.
+	rows, err := db.Query(ctx, sql, append([]any{pgx.QueryExecModeSimpleProtocol}, args...)...)
+	if err != nil {
+		return 0, fmt.Errorf("query to csv: %w", err)
+	}
+	defer rows.Close()
+
+	cw := &csvWriter{w: bufio.NewWriter(w), delimiter: opts.Delimiter, null: opts.Null}
+
+	fields := rows.FieldDescriptions()
+	record := make([]string, len(fields))
+	nulls := make([]bool, len(fields))
+
+	if !opts.NoHeader {
+		for i, fd := range fields {
+			record[i] = fd.Name
+		}
+		cw.write(record, nulls)
+	}
+
+	var n int64
+	for rows.Next() {
+		for i, raw := range rows.RawValues() {
+			record[i], nulls[i] = string(raw), raw == nil
+		}
+		cw.write(record, nulls)
+		n++
+	}
+
+	if err := rows.Err(); err != nil {
+		return n, fmt.Errorf("query to csv: %w", err)
+	}
+
+	if err := cw.w.Flush(); err != nil {
+		return n, fmt.Errorf("query to csv: %w", err)
+	}
+
+	return n, nil
+}
+
+// csvWriter кодирует записи так же, как COPY ... (FORMAT csv): NULL пишется строкой null без
+// кавычек, а значение берётся в кавычки, если содержит разделитель, кавычку или перевод строки
+// либо совпадает со строкой null — поэтому пустая строка и NULL (по умолчанию тоже пустой)
+// различимы. Ошибки записи накапливаются в bufio.Writer и возвращаются Flush.
+type csvWriter struct {
+	w         *bufio.Writer
+	delimiter rune
+	null      string
+}
+
+func (c *csvWriter) write(record []string, nulls []bool) {
+	for i, field := range record {
+		if i > 0 {
+			c.w.WriteRune(c.delimiter)
+		}
+		if nulls[i] {
+			c.w.WriteString(c.null)
+			continue
+		}
+		if !c.needsQuotes(field, len(record)) {
+			c.w.WriteString(field)
+			continue
+		}
+		c.w.WriteByte('"')
+		c.w.WriteString(strings.ReplaceAll(field, `"`, `""`))
+		c.w.WriteByte('"')
+	}
+	c.w.WriteByte('\n')
+}
+
+func (c *csvWriter) needsQuotes(field string, columns int) bool {
+	// "\." в единственной колонке COPY тоже берёт в кавычки: иначе это маркер конца данных.
+	return field == c.null || columns == 1 && field == `\.` ||
+		strings.ContainsRune(field, c.delimiter) || strings.ContainsAny(field, "\"\r\n")
+}
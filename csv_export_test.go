@@ -0,0 +1,24 @@
+package pgfx
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestCSVWriterQuoting(t *testing.T) {
+	var b strings.Builder
+	cw := &csvWriter{w: bufio.NewWriter(&b), delimiter: ','}
+
+	cw.write([]string{"id", "name", "note"}, []bool{false, false, false})
+	cw.write([]string{"1", "", ""}, []bool{false, false, true})
+	cw.write([]string{"2", `say "hi"`, "a,b\nc"}, []bool{false, false, false})
+	if err := cw.w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "id,name,note\n1,\"\",\n2,\"say \"\"hi\"\"\",\"a,b\nc\"\n"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
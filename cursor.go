@@ -0,0 +1,107 @@
+package pgfx
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCursor возвращается, если токен пагинации повреждён или подпись не совпадает.
+	ErrInvalidCursor = errors.New("invalid pagination cursor")
+	// ErrCursorExpired возвращается для токена с истёкшим сроком действия.
+	ErrCursorExpired = errors.New("pagination cursor expired")
+)
+
+// CursorSigner кодирует состояние keyset-пагинации (значения ключа последней строки страницы)
+// в непрозрачный токен для внешних клиентов API: base64url(JSON) + "." + base64url(HMAC-SHA256).
+// Клиент не может подменить состояние, не зная секрета, а срок действия ограничивает
+// повторное использование старых токенов.
+//
+//	type usersCursor struct {
+//	    CreatedAt time.Time `json:"c"`
+//	    ID        int64     `json:"i"`
+//	}
+//
+//	token, _ := signer.Encode(usersCursor{last.CreatedAt, last.ID}, time.Hour)
+//	...
+//	var cur usersCursor
+//	if err := signer.Decode(token, &cur); err != nil { ... }
+//	rows, _ := db.Query(ctx, `SELECT ... WHERE (created_at, id) > ($1, $2) ORDER BY created_at, id LIMIT 50`, cur.CreatedAt, cur.ID)
+type CursorSigner struct {
+	key []byte
+	now func() time.Time
+}
+
+// NewCursorSigner создаёт CursorSigner с секретом key (рекомендуется не короче 32 байт).
+func NewCursorSigner(key []byte) *CursorSigner {
+	return &CursorSigner{key: key, now: time.Now}
+}
+
+type cursorPayload struct {
+	Value   json.RawMessage `json:"v"`
+	Expires int64           `json:"e,omitempty"`
+}
+
+// Encode сериализует v в JSON и подписывает. ttl <= 0 — без срока действия.
+func (s *CursorSigner) Encode(v any, ttl time.Duration) (string, error) {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("encode cursor: %w", err)
+	}
+
+	payload := cursorPayload{Value: value}
+	if ttl > 0 {
+		payload.Expires = s.now().Add(ttl).Unix()
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("encode cursor: %w", err)
+	}
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(body) + "." + enc.EncodeToString(s.sign(body)), nil
+}
+
+// Decode проверяет подпись и срок действия токена и десериализует состояние в v.
+func (s *CursorSigner) Decode(token string, v any) error {
+	enc := base64.RawURLEncoding
+
+	bodyPart, sigPart, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidCursor
+	}
+	body, err := enc.DecodeString(bodyPart)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	sig, err := enc.DecodeString(sigPart)
+	if err != nil || !hmac.Equal(sig, s.sign(body)) {
+		return ErrInvalidCursor
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ErrInvalidCursor
+	}
+	if payload.Expires != 0 && s.now().Unix() >= payload.Expires {
+		return ErrCursorExpired
+	}
+
+	if err := json.Unmarshal(payload.Value, v); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+	return nil
+}
+
+func (s *CursorSigner) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,47 @@
+package pgfx
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCursorSigner(t *testing.T) {
+	type cursor struct {
+		ID   int64  `json:"i"`
+		Name string `json:"n"`
+	}
+
+	now := time.Unix(1_700_000_000, 0)
+	s := NewCursorSigner([]byte("0123456789abcdef0123456789abcdef"))
+	s.now = func() time.Time { return now }
+
+	token, err := s.Encode(cursor{ID: 42, Name: "bob"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got cursor
+	if err := s.Decode(token, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != (cursor{ID: 42, Name: "bob"}) {
+		t.Fatalf("Decode() = %+v", got)
+	}
+
+	tampered := []byte(token)
+	tampered[3] ^= 1
+	if err := s.Decode(string(tampered), &got); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("tampered token: err = %v, want ErrInvalidCursor", err)
+	}
+
+	other := NewCursorSigner([]byte("another secret"))
+	if err := other.Decode(token, &got); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("foreign key: err = %v, want ErrInvalidCursor", err)
+	}
+
+	now = now.Add(time.Minute)
+	if err := s.Decode(token, &got); !errors.Is(err, ErrCursorExpired) {
+		t.Fatalf("expired token: err = %v, want ErrCursorExpired", err)
+	}
+}
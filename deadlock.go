@@ -0,0 +1,75 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DeadlockRetryFunc вызывается перед каждым повтором транзакции после взаимоблокировки:
+// attempt — номер следующей попытки (начиная с 2), err — ошибка предыдущей, delay — пауза перед повтором.
+type DeadlockRetryFunc func(ctx context.Context, attempt int, err error, delay time.Duration)
+
+// IsDeadlock сообщает, завершилась ли операция взаимоблокировкой (SQLSTATE 40P01 deadlock_detected).
+func IsDeadlock(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "40P01"
+}
+
+// WithDeadlockRetry повторяет транзакцию целиком, если она была прервана взаимоблокировкой
+// (см. IsDeadlock) — всего не более attempts раз, с экспоненциальной паузой со случайным
+// разбросом, чтобы столкнувшиеся транзакции не повторились одновременно. onRetry (может быть nil)
+// вызывается перед каждым повтором, например для логирования.
+//
+// Обработчик должен быть готов к повторному выполнению: взаимоблокировка откатывает транзакцию
+// целиком, но побочные эффекты вне базы не откатываются. Для вложенного вызова, присоединяющегося
+// к уже активной транзакции, опция игнорируется: повторить можно только внешнюю транзакцию.
+func WithDeadlockRetry(attempts int, onRetry DeadlockRetryFunc) TxOption {
+	return func(c *txConfig) {
+		c.deadlockAttempts = attempts
+		c.onDeadlockRetry = onRetry
+	}
+}
+
+// deadlockDelay — пауза перед попыткой attempt (2, 3, ...): _retryBaseDelay·2^(attempt-2) ±50%,
+// но не больше _retryMaxDelay ±50%.
+func deadlockDelay(attempt int) time.Duration {
+	base := retryBackoff(attempt - 2)
+	return base/2 + rand.N(base)
+}
+
+// retryDeadlocks выполняет транзакцию с повтором при взаимоблокировке (см. WithDeadlockRetry).
+func (m *Manager) retryDeadlocks(ctx context.Context, opts pgx.TxOptions, cfg txConfig, fn func(ctx context.Context) error) error {
+	attempts := cfg.deadlockAttempts
+	cfg.deadlockAttempts = 0
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			delay := deadlockDelay(attempt)
+			if cfg.onDeadlockRetry != nil {
+				cfg.onDeadlockRetry(ctx, attempt, err, delay)
+			}
+			if m.metrics != nil {
+				m.metrics.TxRetried(cfg.name, "deadlock")
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("deadlock retry aborted after %d attempts: %w (last error: %w)", attempt-1, ctx.Err(), err)
+			case <-time.After(delay):
+			}
+		}
+
+		err = m.transaction(ctx, opts, cfg, fn)
+		if !IsDeadlock(err) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("deadlock persisted after %d attempts: %w", attempts, err)
+}
@@ -0,0 +1,11 @@
+package pgfx
+
+import "testing"
+
+func TestDeadlockDelayCapped(t *testing.T) {
+	for _, attempt := range []int{2, 8, 40, 100} {
+		if d := deadlockDelay(attempt); d <= 0 || d > _retryMaxDelay*3/2 {
+			t.Errorf("deadlockDelay(%d) = %s, want (0, %s]", attempt, d, _retryMaxDelay*3/2)
+		}
+	}
+}
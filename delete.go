@@ -0,0 +1,54 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+)
+
+// _defaultDeleteChunk — размер пачки DeleteByKeys по умолчанию.
+const _defaultDeleteChunk = 1000
+
+// DeleteOption настраивает DeleteByKeys.
+type DeleteOption func(*deleteOptions)
+
+type deleteOptions struct {
+	chunkSize int
+}
+
+// WithChunkSize задаёт, сколько ключей удаляется одним запросом (по умолчанию 1000).
+func WithChunkSize(n int) DeleteOption {
+	return func(o *deleteOptions) {
+		o.chunkSize = n
+	}
+}
+
+// DeleteByKeys удаляет из table строки, у которых keyColumn входит в keys, пачками
+// DELETE ... WHERE keyColumn = ANY($1) и возвращает общее число удалённых строк.
+//
+// Вне транзакции каждая пачка фиксируется отдельно, поэтому блокировки удерживаются
+// недолго; при ошибке уже удалённые пачки остаются удалёнными, а возвращённое число
+// учитывает их. Внутри транзакции из контекста все пачки выполняются в ней.
+func DeleteByKeys[K any](ctx context.Context, db QueryExecutor, table, keyColumn string, keys []K, opts ...DeleteOption) (int64, error) {
+	o := deleteOptions{chunkSize: _defaultDeleteChunk}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.chunkSize <= 0 {
+		o.chunkSize = _defaultDeleteChunk
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE %s = ANY($1)`, quoteTable(table), quoteIdent(keyColumn))
+
+	var total int64
+	for start := 0; start < len(keys); start += o.chunkSize {
+		end := min(start+o.chunkSize, len(keys))
+
+		tag, err := db.Exec(ctx, query, keys[start:end])
+		if err != nil {
+			return total, fmt.Errorf("delete by keys: %w", err)
+		}
+		total += tag.RowsAffected()
+	}
+
+	return total, nil
+}
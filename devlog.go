@@ -0,0 +1,146 @@
+package pgfx
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DevQueryLogEnv — переменная окружения, включающая DevQueryLog ("1", "true").
+const DevQueryLogEnv = "PGFX_DEV_QUERIES"
+
+// devQueryLogTracer печатает каждый запрос в удобном для чтения виде: с подставленными
+// параметрами и оценкой стоимости из EXPLAIN (см. DevQueryLog).
+type devQueryLogTracer struct {
+	p *Postgres
+}
+
+type devQueryLogKey struct{}
+
+func (t devQueryLogTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, devQueryLogKey{}, &queryLogEntry{
+		start: time.Now(),
+		sql:   data.SQL,
+		args:  t.p.redactor.Redact(ctx, data.SQL, data.Args),
+		tag:   QueryTag(ctx),
+	})
+}
+
+func (t devQueryLogTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	e, ok := ctx.Value(devQueryLogKey{}).(*queryLogEntry)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(e.start)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "pgfx: [%s]", elapsed.Round(time.Microsecond))
+	if e.tag != "" {
+		fmt.Fprintf(&b, " %s", e.tag)
+	}
+	if data.Err != nil {
+		fmt.Fprintf(&b, " error: %v", data.Err)
+	} else {
+		fmt.Fprintf(&b, " %s", data.CommandTag)
+		if cost := t.explainCost(ctx, conn, e.sql, len(e.args) > 0); cost != "" {
+			fmt.Fprintf(&b, " %s", cost)
+		}
+	}
+	b.WriteString("\n    ")
+	b.WriteString(strings.ReplaceAll(strings.TrimSpace(interpolateSQL(e.sql, e.args)), "\n", "\n    "))
+
+	log.Print(b.String())
+}
+
+var explainCostRe = regexp.MustCompile(`\(cost=(\S+) rows=(\d+)`)
+
+// explainCost возвращает оценку "cost=0.00..8.27 rows=1" верхнего узла плана или пустую строку.
+// EXPLAIN без ANALYZE не выполняет запрос, поэтому безопасен и для INSERT/UPDATE/DELETE.
+// Запрос с параметрами объясняется через GENERIC_PLAN, если сервер его поддерживает.
+func (t devQueryLogTracer) explainCost(ctx context.Context, conn *pgx.Conn, sql string, hasArgs bool) string {
+	ts := significant(scanSQL(sql))
+	if len(ts) == 0 || !ts[0].isAny("select", "insert", "update", "delete", "with", "values", "table") {
+		return ""
+	}
+	// В прерванной транзакции любой запрос, включая EXPLAIN, завершится ошибкой.
+	if conn.PgConn().TxStatus() == 'E' {
+		return ""
+	}
+
+	explain := "EXPLAIN "
+	if hasArgs {
+		if !t.p.Supports(FeatureGenericPlan) {
+			return ""
+		}
+		explain = "EXPLAIN (GENERIC_PLAN) "
+	}
+
+	// Простой протокол pgconn не проходит через трейсеры и не попадает в логи и статистику.
+	results, err := conn.PgConn().Exec(ctx, explain+sql).ReadAll()
+	if err != nil || len(results) == 0 || len(results[0].Rows) == 0 {
+		return ""
+	}
+
+	m := explainCostRe.FindSubmatch(results[0].Rows[0][0])
+	if m == nil {
+		return ""
+	}
+	return fmt.Sprintf("cost=%s rows=%s", m[1], m[2])
+}
+
+// interpolateSQL подставляет параметры в текст запроса только для отображения:
+// результат не предназначен для выполнения.
+func interpolateSQL(sql string, args []any) string {
+	args = args[queryOptions(args):]
+	if len(args) == 0 {
+		return sql
+	}
+
+	var b strings.Builder
+	for _, t := range scanSQL(sql) {
+		if t.kind == tokParam {
+			if n, err := strconv.Atoi(t.text[1:]); err == nil && n >= 1 && n <= len(args) {
+				b.WriteString(displayLiteral(args[n-1]))
+				continue
+			}
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+func displayLiteral(v any) string {
+	const maxLen = 64
+
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	case []byte:
+		s = `\x` + hex.EncodeToString(v)
+	case time.Time:
+		s = v.Format(time.RFC3339Nano)
+	default:
+		s = fmt.Sprintf("%v", v)
+	}
+
+	if len(s) > maxLen {
+		s = s[:maxLen] + "…"
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func devQueryLogEnabled() bool {
+	on, _ := strconv.ParseBool(os.Getenv(DevQueryLogEnv))
+	return on
+}
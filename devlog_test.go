@@ -0,0 +1,49 @@
+package pgfx
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInterpolateSQL(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		sql  string
+		args []any
+		want string
+	}{
+		{
+			name: "no args",
+			sql:  `SELECT 1`,
+			want: `SELECT 1`,
+		},
+		{
+			name: "literals",
+			sql:  `SELECT * FROM t WHERE a = $1 AND b = $2 AND c = $3 AND d = $4 AND e = $1`,
+			args: []any{42, "O'Brien", nil, ts},
+			want: `SELECT * FROM t WHERE a = 42 AND b = 'O''Brien' AND c = NULL AND d = '2024-05-01T12:00:00Z' AND e = 42`,
+		},
+		{
+			name: "params inside literals are untouched",
+			sql:  `SELECT '$1', $$ $1 $$, $1`,
+			args: []any{true},
+			want: `SELECT '$1', $$ $1 $$, true`,
+		},
+		{
+			name: "bytes and missing params",
+			sql:  `UPDATE t SET b = $1 WHERE id = $2`,
+			args: []any{[]byte{0xde, 0xad}},
+			want: `UPDATE t SET b = '\xdead' WHERE id = $2`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := interpolateSQL(tt.sql, tt.args); got != tt.want {
+				t.Errorf("interpolateSQL() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
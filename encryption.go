@@ -0,0 +1,221 @@
+package pgfx
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// Формат зашифрованного значения (envelope encryption):
+//
+//	version(1) | len(keyID)(1) | keyID | wrapNonce(12) | wrappedDEK(32+16) | dataNonce(12) | ciphertext
+//
+// Для каждого значения генерируется случайный ключ данных (DEK), которым шифруются данные.
+// DEK шифруется мастер-ключом (KEK) из Keyring, его идентификатор хранится рядом, поэтому
+// после ротации старые значения по-прежнему расшифровываются, а Rewrap перешифровывает
+// их текущим ключом без расшифровки самих данных.
+//
+// Версия 2 — данные зашифрованы с дополнительными данными (AAD, см. EncryptWithAAD);
+// версия 1 — без них, такие значения расшифровываются при любых AAD.
+const (
+	_envelopeVersion    = 1
+	_envelopeVersionAAD = 2
+	_dekSize            = 32
+	_gcmNonceSize       = 12
+	_gcmTagSize         = 16
+)
+
+var (
+	// ErrUnknownKey возвращается при расшифровке значения, зашифрованного ключом, которого нет в Keyring.
+	ErrUnknownKey = errors.New("unknown encryption key")
+	// ErrInvalidCiphertext возвращается, если значение повреждено или не является результатом Encrypt.
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// Keyring — набор мастер-ключей шифрования AES-256 с одним текущим ключом.
+type Keyring struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyring создаёт Keyring. keys — идентификатор → 32-байтовый ключ,
+// current — идентификатор ключа, которым шифруются новые значения.
+// Для ротации добавьте новый ключ, сделайте его текущим и оставьте старые для чтения.
+func NewKeyring(current string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("keyring: current key %q: %w", current, ErrUnknownKey)
+	}
+
+	k := &Keyring{current: current, keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if len(id) == 0 || len(id) > 255 {
+			return nil, fmt.Errorf("keyring: key id %q must be 1..255 bytes", id)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("keyring: key %q must be 32 bytes, got %d", id, len(key))
+		}
+
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, fmt.Errorf("keyring: key %q: %w", id, err)
+		}
+		k.keys[id] = aead
+	}
+
+	return k, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt шифрует plaintext текущим ключом. Значение не привязано ни к какому месту хранения:
+// скопированное в другую строку или колонку, оно расшифруется так же. Для привязки см. EncryptWithAAD.
+func (k *Keyring) Encrypt(plaintext []byte) ([]byte, error) {
+	return k.encrypt(_envelopeVersion, plaintext, nil)
+}
+
+// EncryptWithAAD шифрует plaintext текущим ключом, аутентифицируя вместе с ним aad — например,
+// таблицу и колонку значения. Расшифровать его можно только DecryptWithAAD с теми же aad,
+// поэтому значение, скопированное в место с другими aad, не расшифруется.
+func (k *Keyring) EncryptWithAAD(plaintext, aad []byte) ([]byte, error) {
+	return k.encrypt(_envelopeVersionAAD, plaintext, aad)
+}
+
+func (k *Keyring) encrypt(version byte, plaintext, aad []byte) ([]byte, error) {
+	dek := make([]byte, _dekSize)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, err
+	}
+
+	data, err := newGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	out := k.header(version, k.current)
+	out, err = k.wrap(out, k.current, dek)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, _gcmNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out = append(out, nonce...)
+
+	return data.Seal(out, nonce, plaintext, aad), nil
+}
+
+// Decrypt расшифровывает значение, полученное от Encrypt любым ключом из Keyring.
+func (k *Keyring) Decrypt(ciphertext []byte) ([]byte, error) {
+	return k.DecryptWithAAD(ciphertext, nil)
+}
+
+// DecryptWithAAD расшифровывает значение, полученное от EncryptWithAAD с теми же aad.
+// Значения, зашифрованные Encrypt, расшифровываются без проверки aad.
+func (k *Keyring) DecryptWithAAD(ciphertext, aad []byte) ([]byte, error) {
+	id, dek, rest, err := k.unwrap(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := newGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) < _gcmNonceSize+_gcmTagSize {
+		return nil, ErrInvalidCiphertext
+	}
+
+	if ciphertext[0] == _envelopeVersion {
+		aad = nil
+	}
+	plaintext, err := data.Open(nil, rest[:_gcmNonceSize], rest[_gcmNonceSize:], aad)
+	if err != nil {
+		return nil, fmt.Errorf("%w: key %q: %w", ErrInvalidCiphertext, id, err)
+	}
+	return plaintext, nil
+}
+
+// NeedsRewrap сообщает, зашифровано ли значение не текущим ключом.
+func (k *Keyring) NeedsRewrap(ciphertext []byte) bool {
+	id, _, ok := parseHeader(ciphertext)
+	return ok && id != k.current
+}
+
+// Rewrap перешифровывает ключ данных значения текущим мастер-ключом.
+// Сами данные не расшифровываются, поэтому операция дешёвая и подходит
+// для фоновой миграции после ротации ключа.
+func (k *Keyring) Rewrap(ciphertext []byte) ([]byte, error) {
+	_, dek, rest, err := k.unwrap(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := k.wrap(k.header(ciphertext[0], k.current), k.current, dek)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, rest...), nil
+}
+
+func (k *Keyring) header(version byte, id string) []byte {
+	out := make([]byte, 0, 2+len(id))
+	out = append(out, version, byte(len(id)))
+	return append(out, id...)
+}
+
+func (k *Keyring) wrap(out []byte, id string, dek []byte) ([]byte, error) {
+	nonce := make([]byte, _gcmNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out = append(out, nonce...)
+
+	// Идентификатор ключа аутентифицируется вместе с DEK.
+	return k.keys[id].Seal(out, nonce, dek, []byte(id)), nil
+}
+
+func (k *Keyring) unwrap(ciphertext []byte) (id string, dek, rest []byte, err error) {
+	id, body, ok := parseHeader(ciphertext)
+	if !ok {
+		return "", nil, nil, ErrInvalidCiphertext
+	}
+
+	kek, ok := k.keys[id]
+	if !ok {
+		return "", nil, nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+
+	wrappedLen := _gcmNonceSize + _dekSize + _gcmTagSize
+	if len(body) < wrappedLen {
+		return "", nil, nil, ErrInvalidCiphertext
+	}
+
+	dek, err = kek.Open(nil, body[:_gcmNonceSize], body[_gcmNonceSize:wrappedLen], []byte(id))
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("%w: key %q: %w", ErrInvalidCiphertext, id, err)
+	}
+
+	return id, dek, body[wrappedLen:], nil
+}
+
+func parseHeader(ciphertext []byte) (id string, body []byte, ok bool) {
+	if len(ciphertext) < 2 || ciphertext[0] != _envelopeVersion && ciphertext[0] != _envelopeVersionAAD {
+		return "", nil, false
+	}
+
+	n := int(ciphertext[1])
+	if len(ciphertext) < 2+n {
+		return "", nil, false
+	}
+	return string(ciphertext[2 : 2+n]), ciphertext[2+n:], true
+}
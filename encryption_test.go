@@ -0,0 +1,91 @@
+package pgfx
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestKeyringRotation(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	k1, err := NewKeyring("k1", map[string][]byte{"k1": oldKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertext, err := k1.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k2, err := NewKeyring("k2", map[string][]byte{"k1": oldKey, "k2": newKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !k2.NeedsRewrap(ciphertext) {
+		t.Fatal("value encrypted with k1 should need rewrap")
+	}
+
+	rewrapped, err := k2.Rewrap(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k2.NeedsRewrap(rewrapped) {
+		t.Fatal("rewrapped value should use the current key")
+	}
+
+	for _, c := range [][]byte{ciphertext, rewrapped} {
+		plaintext, err := k2.Decrypt(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(plaintext) != "secret" {
+			t.Fatalf("got %q", plaintext)
+		}
+	}
+
+	if _, err := k1.Decrypt(rewrapped); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+
+	rewrapped[len(rewrapped)-1] ^= 0xff
+	if _, err := k2.Decrypt(rewrapped); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("expected ErrInvalidCiphertext, got %v", err)
+	}
+}
+
+func TestKeyringAAD(t *testing.T) {
+	k, err := NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, orders := []byte(`"users"."email"`), []byte(`"orders"."email"`)
+
+	ciphertext, err := k.EncryptWithAAD([]byte("secret"), users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.DecryptWithAAD(ciphertext, orders); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("other aad: expected ErrInvalidCiphertext, got %v", err)
+	}
+
+	rewrapped, err := k.Rewrap(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := k.DecryptWithAAD(rewrapped, users); err != nil || string(plaintext) != "secret" {
+		t.Fatalf("same aad after rewrap: got %q, %v", plaintext, err)
+	}
+
+	// Значения без AAD остаются читаемыми.
+	legacy, err := k.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := k.DecryptWithAAD(legacy, users); err != nil || string(plaintext) != "secret" {
+		t.Fatalf("legacy value: got %q, %v", plaintext, err)
+	}
+}
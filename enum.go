@@ -0,0 +1,171 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownEnumValue возвращается ParseEnum для значения, не входящего в зарегистрированный набор.
+var ErrUnknownEnumValue = errors.New("unknown enum value")
+
+type enumRegistry struct {
+	mu     sync.RWMutex
+	byName map[string][]string           // тип PostgreSQL -> значения
+	byType map[reflect.Type]enumTypeInfo // Go-тип -> описание
+}
+
+type enumTypeInfo struct {
+	pgType string
+	values map[string]bool
+}
+
+var defaultEnums = &enumRegistry{
+	byName: make(map[string][]string),
+	byType: make(map[reflect.Type]enumTypeInfo),
+}
+
+// RegisterEnum связывает строковый Go-тип E с типом-перечислением PostgreSQL pgType
+// и набором допустимых значений. Обычно вызывается из init или при объявлении переменной пакета:
+//
+//	type OrderStatus string
+//
+//	const (
+//	    OrderNew  OrderStatus = "new"
+//	    OrderPaid OrderStatus = "paid"
+//	)
+//
+//	var _ = pgfx.RegisterEnum("order_status", OrderNew, OrderPaid)
+//
+// Наборы сверяются с pg_enum через VerifyEnums или опцию VerifyEnumsOnStart, а ParseEnum
+// проверяет входные значения. Повторная регистрация pgType с другим набором вызывает панику.
+func RegisterEnum[E ~string](pgType string, values ...E) bool {
+	set := make(map[string]bool, len(values))
+	list := make([]string, len(values))
+	for i, v := range values {
+		list[i] = string(v)
+		set[string(v)] = true
+	}
+
+	defaultEnums.mu.Lock()
+	defer defaultEnums.mu.Unlock()
+
+	if prev, ok := defaultEnums.byName[pgType]; ok && !slices.Equal(sortedCopy(prev), sortedCopy(list)) {
+		panic(fmt.Sprintf("pgfx: enum %q registered twice with different values", pgType))
+	}
+	defaultEnums.byName[pgType] = list
+	defaultEnums.byType[reflect.TypeFor[E]()] = enumTypeInfo{pgType: pgType, values: set}
+
+	return true
+}
+
+// ParseEnum проверяет, что s — допустимое значение зарегистрированного типа E.
+func ParseEnum[E ~string](s string) (E, error) {
+	defaultEnums.mu.RLock()
+	info, ok := defaultEnums.byType[reflect.TypeFor[E]()]
+	defaultEnums.mu.RUnlock()
+
+	if !ok {
+		return "", fmt.Errorf("pgfx: enum type %s is not registered", reflect.TypeFor[E]())
+	}
+	if !info.values[s] {
+		return "", fmt.Errorf("%w %q for %s", ErrUnknownEnumValue, s, info.pgType)
+	}
+	return E(s), nil
+}
+
+// EnumMismatch описывает расхождение одного перечисления.
+type EnumMismatch struct {
+	// Missing — тип не найден в базе.
+	Missing bool
+	// NotInDB — значения, известные приложению, но отсутствующие в pg_enum.
+	NotInDB []string
+	// NotInGo — значения pg_enum, которых нет в RegisterEnum (например, добавлены миграцией).
+	NotInGo []string
+}
+
+// EnumVerificationError перечисляет перечисления, наборы значений которых разошлись с базой.
+type EnumVerificationError struct {
+	Mismatches map[string]EnumMismatch
+}
+
+func (e *EnumVerificationError) Error() string {
+	names := make([]string, 0, len(e.Mismatches))
+	for name := range e.Mismatches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, len(names))
+	for i, name := range names {
+		m := e.Mismatches[name]
+		switch {
+		case m.Missing:
+			lines[i] = fmt.Sprintf("%s: type not found", name)
+		default:
+			lines[i] = fmt.Sprintf("%s: not in db %v, not in go %v", name, m.NotInDB, m.NotInGo)
+		}
+	}
+
+	return fmt.Sprintf("%d enums differ from database:\n%s", len(names), strings.Join(lines, "\n"))
+}
+
+// VerifyEnums сверяет наборы значений, зарегистрированные через RegisterEnum, с pg_enum
+// и возвращает *EnumVerificationError при расхождениях.
+func (p *Postgres) VerifyEnums(ctx context.Context) error {
+	defaultEnums.mu.RLock()
+	expected := make(map[string][]string, len(defaultEnums.byName))
+	for name, values := range defaultEnums.byName {
+		expected[name] = values
+	}
+	defaultEnums.mu.RUnlock()
+
+	mismatches := make(map[string]EnumMismatch)
+	for name, values := range expected {
+		var actual []string
+		err := p.Pool.QueryRow(ctx, `
+			SELECT coalesce(array_agg(e.enumlabel ORDER BY e.enumsortorder), '{}')
+			FROM pg_enum e
+			WHERE e.enumtypid = to_regtype($1)`, name).Scan(&actual)
+		if err != nil {
+			return fmt.Errorf("postgres - VerifyEnums - %s: %w", name, err)
+		}
+
+		if len(actual) == 0 {
+			mismatches[name] = EnumMismatch{Missing: true}
+			continue
+		}
+
+		m := EnumMismatch{NotInDB: difference(values, actual), NotInGo: difference(actual, values)}
+		if len(m.NotInDB) > 0 || len(m.NotInGo) > 0 {
+			mismatches[name] = m
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return &EnumVerificationError{Mismatches: mismatches}
+	}
+	return nil
+}
+
+// difference возвращает элементы a, которых нет в b.
+func difference(a, b []string) []string {
+	var out []string
+	for _, v := range a {
+		if !slices.Contains(b, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func sortedCopy(s []string) []string {
+	out := slices.Clone(s)
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,57 @@
+package pgfx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// EstimateCount возвращает приблизительное число строк запроса sql по оценке планировщика
+// (EXPLAIN) вместо COUNT(*), который на больших таблицах читает их целиком. Подходит для
+// интерфейсов, которым нужен порядок величины ("≈ 1.2 млн"), а не точное число.
+//
+// Для полного чтения одной таблицы без условий используется reltuples из pg_class — та же
+// статистика, что обновляют VACUUM и ANALYZE. Точность оценки зависит от свежести статистики;
+// для таблицы, которую ещё не анализировали, возвращается оценка планировщика по её размеру.
+func EstimateCount(ctx context.Context, db QueryExecutor, sql string, args ...any) (int64, error) {
+	sql = strings.TrimRight(strings.TrimSpace(sql), ";")
+
+	var raw []byte
+	if err := db.QueryRow(ctx, "EXPLAIN (FORMAT JSON, VERBOSE) "+sql, args...).Scan(&raw); err != nil {
+		return 0, fmt.Errorf("estimate count: explain: %w", err)
+	}
+
+	var plans []struct {
+		Plan struct {
+			NodeType string  `json:"Node Type"`
+			Rows     float64 `json:"Plan Rows"`
+			Relation string  `json:"Relation Name"`
+			Schema   string  `json:"Schema"`
+			Filter   string  `json:"Filter"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(raw, &plans); err != nil || len(plans) == 0 {
+		return 0, fmt.Errorf("estimate count: unexpected explain output: %s", raw)
+	}
+	plan := plans[0].Plan
+
+	if plan.NodeType == "Seq Scan" && plan.Filter == "" && plan.Relation != "" {
+		var reltuples float64
+		err := db.QueryRow(ctx, `
+			SELECT c.reltuples FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1 AND c.relname = $2`,
+			plan.Schema, plan.Relation).Scan(&reltuples)
+		if err != nil {
+			return 0, fmt.Errorf("estimate count: reltuples: %w", err)
+		}
+		// -1 — таблицу ещё не анализировали.
+		if reltuples >= 0 {
+			return int64(math.Round(reltuples)), nil
+		}
+	}
+
+	return int64(math.Round(plan.Rows)), nil
+}
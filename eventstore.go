@@ -0,0 +1,205 @@
+package pgfx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	// AnyVersion отключает проверку ожидаемой версии потока при добавлении событий.
+	AnyVersion int64 = -1
+	// NoStream означает, что поток ещё не должен существовать.
+	NoStream int64 = 0
+
+	_pgUniqueViolation = "23505"
+)
+
+// ErrVersionConflict возвращается (через VersionConflictError), если версия потока
+// не совпала с ожидаемой: кто-то успел дописать события раньше.
+var ErrVersionConflict = errors.New("stream version conflict")
+
+// VersionConflictError описывает конфликт оптимистичной блокировки при добавлении событий.
+type VersionConflictError struct {
+	StreamID string
+	Expected int64
+	Actual   int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("stream %q: expected version %d, actual %d", e.StreamID, e.Expected, e.Actual)
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
+// NewEvent — событие, которое нужно добавить в поток.
+type NewEvent struct {
+	Type     string
+	Data     json.RawMessage
+	Metadata json.RawMessage
+}
+
+// Event — сохранённое событие.
+type Event struct {
+	// StreamID — идентификатор агрегата (потока).
+	StreamID string
+	// Version — порядковый номер события внутри потока, начиная с 1.
+	Version int64
+	// Position — глобальная позиция события. Позиции выдаются без пропусков
+	// и в порядке коммита, поэтому читатель, запомнивший последнюю позицию,
+	// никогда не пропустит событие.
+	Position  int64
+	Type      string
+	Data      json.RawMessage
+	Metadata  json.RawMessage
+	CreatedAt time.Time
+}
+
+// EventStore — хранилище событий для event sourcing поверх PostgreSQL.
+//
+// Все операции выполняются через TransactionalPool и TxManager, поэтому Append
+// можно вызывать внутри уже открытой транзакции вместе с обновлением проекций.
+type EventStore struct {
+	db       QueryExecutor
+	tm       *Manager
+	table    string
+	posTable string
+}
+
+// NewEventStore создаёт EventStore, который хранит события в таблице table,
+// а счётчик глобальной позиции — в таблице table + "_position".
+// Таблицы можно создать методом EventStore.CreateTables.
+func (p *Postgres) NewEventStore(table string) *EventStore {
+	return &EventStore{
+		db:       p.TransactionalPool,
+		tm:       p.NewTransactionManager(),
+		table:    quoteTable(table),
+		posTable: quoteTable(table + "_position"),
+	}
+}
+
+// CreateTables создаёт таблицы событий и счётчика позиции, если их ещё нет.
+func (s *EventStore) CreateTables(ctx context.Context) error {
+	return s.tm.ReadCommitted(ctx, func(ctx context.Context) error {
+		queries := []string{
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	position   BIGINT PRIMARY KEY,
+	stream_id  TEXT NOT NULL,
+	version    BIGINT NOT NULL,
+	type       TEXT NOT NULL,
+	data       JSONB NOT NULL,
+	metadata   JSONB,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	UNIQUE (stream_id, version)
+)`, s.table),
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id       BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+	position BIGINT NOT NULL
+)`, s.posTable),
+			fmt.Sprintf(`INSERT INTO %s (position) VALUES (0) ON CONFLICT DO NOTHING`, s.posTable),
+		}
+
+		for _, query := range queries {
+			if _, err := s.db.Exec(ctx, query); err != nil {
+				return fmt.Errorf("event store - CreateTables: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// Append добавляет события в конец потока streamID и возвращает новую версию потока.
+//
+// expectedVersion — версия потока, на основе которой были приняты решения
+// (NoStream для нового потока, AnyVersion — без проверки). Если фактическая версия
+// отличается, возвращается *VersionConflictError, и ни одно событие не записывается.
+//
+// Выдача глобальных позиций сериализует конкурентные Append, поэтому позиции
+// не имеют пропусков и видны читателям строго по возрастанию.
+func (s *EventStore) Append(ctx context.Context, streamID string, expectedVersion int64, events ...NewEvent) (int64, error) {
+	var version int64
+
+	err := s.tm.ReadCommitted(ctx, func(ctx context.Context) error {
+		var position int64
+		query := fmt.Sprintf(`UPDATE %s SET position = position + $1 RETURNING position`, s.posTable)
+		if err := s.db.QueryRow(ctx, query, len(events)).Scan(&position); err != nil {
+			return fmt.Errorf("event store - reserve positions: %w", err)
+		}
+		position -= int64(len(events))
+
+		query = fmt.Sprintf(`SELECT coalesce(max(version), 0) FROM %s WHERE stream_id = $1`, s.table)
+		if err := s.db.QueryRow(ctx, query, streamID).Scan(&version); err != nil {
+			return fmt.Errorf("event store - current version: %w", err)
+		}
+
+		if expectedVersion != AnyVersion && expectedVersion != version {
+			return &VersionConflictError{StreamID: streamID, Expected: expectedVersion, Actual: version}
+		}
+
+		query = fmt.Sprintf(`INSERT INTO %s (position, stream_id, version, type, data, metadata) VALUES ($1, $2, $3, $4, $5, $6)`, s.table)
+		for _, e := range events {
+			position++
+			version++
+
+			if _, err := s.db.Exec(ctx, query, position, streamID, version, e.Type, e.Data, e.Metadata); err != nil {
+				var pgErr *pgconn.PgError
+				if errors.As(err, &pgErr) && pgErr.Code == _pgUniqueViolation {
+					return &VersionConflictError{StreamID: streamID, Expected: expectedVersion, Actual: version}
+				}
+				return fmt.Errorf("event store - insert event: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// ReadStream возвращает до limit событий потока streamID, начиная с версии fromVersion включительно.
+func (s *EventStore) ReadStream(ctx context.Context, streamID string, fromVersion int64, limit int) ([]Event, error) {
+	query := fmt.Sprintf(`SELECT position, stream_id, version, type, data, metadata, created_at
+FROM %s WHERE stream_id = $1 AND version >= $2 ORDER BY version LIMIT $3`, s.table)
+
+	events, err := s.query(ctx, query, streamID, fromVersion, limit)
+	if err != nil {
+		return nil, fmt.Errorf("event store - ReadStream: %w", err)
+	}
+	return events, nil
+}
+
+// ReadAll возвращает до limit событий всех потоков с глобальной позицией больше afterPosition.
+// Используется для построения проекций: следующая страница читается с позиции последнего события.
+func (s *EventStore) ReadAll(ctx context.Context, afterPosition int64, limit int) ([]Event, error) {
+	query := fmt.Sprintf(`SELECT position, stream_id, version, type, data, metadata, created_at
+FROM %s WHERE position > $1 ORDER BY position LIMIT $2`, s.table)
+
+	events, err := s.query(ctx, query, afterPosition, limit)
+	if err != nil {
+		return nil, fmt.Errorf("event store - ReadAll: %w", err)
+	}
+	return events, nil
+}
+
+func (s *EventStore) query(ctx context.Context, query string, args ...any) ([]Event, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Event, error) {
+		var e Event
+		err := row.Scan(&e.Position, &e.StreamID, &e.Version, &e.Type, &e.Data, &e.Metadata, &e.CreatedAt)
+		return e, err
+	})
+}
@@ -0,0 +1,112 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrExtensionNotInstalled — расширения нет на сервере (не установлен пакет), создать его нельзя.
+	ErrExtensionNotInstalled = errors.New("extension is not installed on the server")
+	// ErrExtensionPrivilege — у пользователя нет прав на CREATE EXTENSION
+	// (нужен суперпользователь или доверенное расширение и право CREATE на базу).
+	ErrExtensionPrivilege = errors.New("insufficient privilege to create extension")
+)
+
+// extensionSet — расширения, созданные в базе: имя → версия (см. EnsureExtensions).
+type extensionSet struct {
+	mu       sync.RWMutex
+	versions map[string]string
+}
+
+// EnsureExtensions создаёт расширения names (CREATE EXTENSION IF NOT EXISTS), например
+//
+//	err := pg.EnsureExtensions(ctx, "uuid-ossp", "pg_trgm", "vector")
+//
+// и запоминает, какие расширения есть в базе, для HasExtension. Ошибка одного расширения не мешает
+// создать остальные; все ошибки возвращаются вместе, причины отсутствующего пакета
+// и недостатка прав различимы через errors.Is(err, ErrExtensionNotInstalled / ErrExtensionPrivilege).
+func (p *Postgres) EnsureExtensions(ctx context.Context, names ...string) error {
+	var errs []error
+	for _, name := range names {
+		if _, err := p.Pool.Exec(ctx, `CREATE EXTENSION IF NOT EXISTS `+quoteIdent(name)); err != nil {
+			errs = append(errs, fmt.Errorf("extension %s: %w", name, classifyExtensionError(err)))
+		}
+	}
+
+	if err := p.loadExtensions(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("postgres - EnsureExtensions: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func classifyExtensionError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.Code {
+	case "42501":
+		return fmt.Errorf("%w: %w", ErrExtensionPrivilege, err)
+	case "58P01", "0A000":
+		// 58P01 — нет управляющего файла расширения, 0A000 — пакет не поддерживает эту версию сервера.
+		return fmt.Errorf("%w: %w", ErrExtensionNotInstalled, err)
+	}
+	return err
+}
+
+func (p *Postgres) loadExtensions(ctx context.Context) error {
+	rows, err := p.Pool.Query(ctx, `SELECT extname, extversion FROM pg_extension`)
+	if err != nil {
+		return fmt.Errorf("list extensions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make(map[string]string)
+	for rows.Next() {
+		var name, version string
+		if err := rows.Scan(&name, &version); err != nil {
+			return fmt.Errorf("list extensions: %w", err)
+		}
+		versions[name] = version
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("list extensions: %w", err)
+	}
+
+	p.extensions.mu.Lock()
+	p.extensions.versions = versions
+	p.extensions.mu.Unlock()
+	return nil
+}
+
+// HasExtension сообщает, создано ли расширение name в базе по данным последнего EnsureExtensions —
+// например, чтобы включать поиск по сходству только при наличии pg_trgm.
+func (p *Postgres) HasExtension(name string) bool {
+	p.extensions.mu.RLock()
+	defer p.extensions.mu.RUnlock()
+
+	_, ok := p.extensions.versions[name]
+	return ok
+}
+
+// Extensions возвращает расширения базы (имя → версия) по данным последнего EnsureExtensions.
+func (p *Postgres) Extensions() map[string]string {
+	p.extensions.mu.RLock()
+	defer p.extensions.mu.RUnlock()
+
+	out := make(map[string]string, len(p.extensions.versions))
+	for name, version := range p.extensions.versions {
+		out[name] = version
+	}
+	return out
+}
@@ -0,0 +1,220 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ForeignServer описывает удалённую базу PostgreSQL, подключаемую через postgres_fdw.
+type ForeignServer struct {
+	// Name — имя сервера (CREATE SERVER name).
+	Name   string
+	Host   string
+	Port   int
+	DBName string
+	// User и Password — учётные данные на удалённом сервере для текущего пользователя (USER MAPPING).
+	User     string
+	Password string
+	// Options — дополнительные параметры сервера postgres_fdw (fetch_size, use_remote_estimate и т.п.).
+	Options map[string]string
+}
+
+// ForeignSchema описывает импорт таблиц удалённой схемы в локальную (IMPORT FOREIGN SCHEMA).
+type ForeignSchema struct {
+	Server string
+	// Remote — схема на удалённом сервере, Local — локальная схема для внешних таблиц
+	// (создаётся при необходимости; удобно отдельная, например "remote_billing").
+	Remote string
+	Local  string
+	// Tables ограничивает импорт перечисленными таблицами; пусто — все таблицы схемы.
+	Tables []string
+}
+
+// EnsureForeignServer создаёт или обновляет сервер postgres_fdw и сопоставление текущего
+// пользователя с удалённым по описанию s, при необходимости устанавливая расширение postgres_fdw.
+// Повторный вызов с изменённой конфигурацией обновляет параметры существующего сервера,
+// не пересоздавая его, поэтому зависимые внешние таблицы и представления сохраняются.
+func (p *Postgres) EnsureForeignServer(ctx context.Context, s ForeignServer) error {
+	options := make(map[string]string, len(s.Options)+3)
+	for k, v := range s.Options {
+		options[k] = v
+	}
+	if s.Host != "" {
+		options["host"] = s.Host
+	}
+	if s.Port != 0 {
+		options["port"] = strconv.Itoa(s.Port)
+	}
+	if s.DBName != "" {
+		options["dbname"] = s.DBName
+	}
+
+	server := quoteIdent(s.Name)
+	err := p.NewTransactionManager().ReadCommitted(ctx, func(ctx context.Context) error {
+		db := p.TransactionalPool
+
+		if _, err := db.Exec(ctx, `CREATE EXTENSION IF NOT EXISTS postgres_fdw`); err != nil {
+			return fmt.Errorf("create extension: %w", err)
+		}
+
+		var current []string
+		err := db.QueryRow(ctx, `SELECT coalesce(srvoptions, '{}') FROM pg_foreign_server WHERE srvname = $1`, s.Name).Scan(&current)
+		switch {
+		case err == nil:
+			if alter := alterOptions(current, options); alter != "" {
+				if _, err := db.Exec(ctx, `ALTER SERVER `+server+` OPTIONS (`+alter+`)`); err != nil {
+					return fmt.Errorf("alter server: %w", err)
+				}
+			}
+		case errors.Is(err, pgx.ErrNoRows):
+			if _, err := db.Exec(ctx, `CREATE SERVER `+server+` FOREIGN DATA WRAPPER postgres_fdw`+createOptions(options)); err != nil {
+				return fmt.Errorf("create server: %w", err)
+			}
+		default:
+			return fmt.Errorf("read server: %w", err)
+		}
+
+		// Сопоставление не имеет зависимостей, поэтому его проще пересоздать.
+		if _, err := db.Exec(ctx, `DROP USER MAPPING IF EXISTS FOR CURRENT_USER SERVER `+server); err != nil {
+			return fmt.Errorf("drop user mapping: %w", err)
+		}
+		mapping := make(map[string]string, 2)
+		if s.User != "" {
+			mapping["user"] = s.User
+		}
+		if s.Password != "" {
+			mapping["password"] = s.Password
+		}
+		if _, err := db.Exec(ctx, `CREATE USER MAPPING FOR CURRENT_USER SERVER `+server+createOptions(mapping)); err != nil {
+			return fmt.Errorf("create user mapping: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("postgres - EnsureForeignServer %s: %w", s.Name, err)
+	}
+	return nil
+}
+
+// RefreshForeignSchema (пере)импортирует таблицы удалённой схемы: внешние таблицы сервера
+// в локальной схеме удаляются и импортируются заново, так что изменения удалённой схемы
+// (новые колонки, типы) подхватываются. После этого межбазовые запросы пишутся как обычные:
+//
+//	SELECT o.id, c.name FROM orders o JOIN remote_billing.customers c ON c.id = o.customer_id
+//
+// Если от внешних таблиц зависят представления, удаление завершится ошибкой — их нужно
+// пересоздать отдельно.
+func (p *Postgres) RefreshForeignSchema(ctx context.Context, s ForeignSchema) error {
+	local := quoteIdent(s.Local)
+
+	err := p.NewTransactionManager().ReadCommitted(ctx, func(ctx context.Context) error {
+		db := p.TransactionalPool
+
+		if _, err := db.Exec(ctx, `CREATE SCHEMA IF NOT EXISTS `+local); err != nil {
+			return fmt.Errorf("create schema: %w", err)
+		}
+
+		rows, err := db.Query(ctx, `
+			SELECT c.relname
+			FROM pg_foreign_table ft
+			JOIN pg_class c ON c.oid = ft.ftrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			JOIN pg_foreign_server s ON s.oid = ft.ftserver
+			WHERE s.srvname = $1 AND n.nspname = $2
+			  AND (cardinality($3::text[]) = 0 OR c.relname = ANY($3))`,
+			s.Server, s.Local, s.Tables)
+		if err != nil {
+			return fmt.Errorf("list foreign tables: %w", err)
+		}
+		var existing []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return fmt.Errorf("list foreign tables: %w", err)
+			}
+			existing = append(existing, local+"."+quoteIdent(name))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("list foreign tables: %w", err)
+		}
+
+		if len(existing) > 0 {
+			if _, err := db.Exec(ctx, `DROP FOREIGN TABLE `+strings.Join(existing, ", ")); err != nil {
+				return fmt.Errorf("drop foreign tables: %w", err)
+			}
+		}
+
+		limit := ""
+		if len(s.Tables) > 0 {
+			tables := make([]string, len(s.Tables))
+			for i, t := range s.Tables {
+				tables[i] = quoteIdent(t)
+			}
+			limit = ` LIMIT TO (` + strings.Join(tables, ", ") + `)`
+		}
+		if _, err := db.Exec(ctx, `IMPORT FOREIGN SCHEMA `+quoteIdent(s.Remote)+limit+` FROM SERVER `+quoteIdent(s.Server)+` INTO `+local); err != nil {
+			return fmt.Errorf("import foreign schema: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("postgres - RefreshForeignSchema %s.%s: %w", s.Server, s.Remote, err)
+	}
+	return nil
+}
+
+// createOptions формирует " OPTIONS (name 'value', ...)" в стабильном порядке
+// или пустую строку, если параметров нет.
+func createOptions(options map[string]string) string {
+	if len(options) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = quoteIdent(name) + " " + quoteLiteral(options[name])
+	}
+	return " OPTIONS (" + strings.Join(parts, ", ") + ")"
+}
+
+// alterOptions формирует OPTIONS для ALTER SERVER: ADD для новых параметров, SET для изменённых.
+// current — srvoptions в виде "name=value". Параметры, которых нет в options, не трогаются.
+func alterOptions(current []string, options map[string]string) string {
+	have := make(map[string]string, len(current))
+	for _, o := range current {
+		name, value, _ := strings.Cut(o, "=")
+		have[name] = value
+	}
+
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parts []string
+	for _, name := range names {
+		value, ok := have[name]
+		switch {
+		case !ok:
+			parts = append(parts, "ADD "+quoteIdent(name)+" "+quoteLiteral(options[name]))
+		case value != options[name]:
+			parts = append(parts, "SET "+quoteIdent(name)+" "+quoteLiteral(options[name]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,95 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ItemErrors перечисляет элементы ForEachWithSavepoint, обработка которых завершилась ошибкой.
+type ItemErrors struct {
+	// Failed — индекс элемента → ошибка его обработчика.
+	Failed map[int]error
+}
+
+func (e *ItemErrors) Error() string {
+	idx := make([]int, 0, len(e.Failed))
+	for i := range e.Failed {
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+
+	errs := make([]error, len(idx))
+	for j, i := range idx {
+		errs[j] = fmt.Errorf("item %d: %w", i, e.Failed[i])
+	}
+
+	return fmt.Sprintf("%d items failed:\n%v", len(idx), errors.Join(errs...))
+}
+
+// ForEachWithSavepoint вызывает fn для каждого элемента items на отдельном savepoint транзакции
+// db (обычно TransactionalPool): если fn для элемента вернул ошибку, откатывается только его работа, а обработка
+// остальных продолжается. Ошибки элементов собираются в *ItemErrors, которую удобно залогировать
+// и решить, фиксировать ли транзакцию с успешными элементами:
+//
+//	err := tm.ReadCommitted(ctx, func(ctx context.Context) error {
+//	    err := pgfx.ForEachWithSavepoint(ctx, pg.TransactionalPool, rows, importRow)
+//	    var itemErrs *pgfx.ItemErrors
+//	    if errors.As(err, &itemErrs) {
+//	        report(itemErrs.Failed)
+//	        return nil // фиксируем успешные строки
+//	    }
+//	    return err
+//	})
+//
+// Вне транзакции возвращает ErrNoTransaction. Ошибки самих savepoint (например, отменённый
+// контекст) прерывают обход и возвращаются как есть.
+func ForEachWithSavepoint[T any](ctx context.Context, db QueryExecutor, items []T, fn func(ctx context.Context, item T) error) error {
+	key := executorTxKey(db)
+	tx, ok := txFrom(ctx, key)
+	if !ok {
+		return fmt.Errorf("for each with savepoint: %w", ErrNoTransaction)
+	}
+	depth := txDepth(ctx) + 1
+	// Обработчики элемента копятся отдельно, как у savepoint менеджера (см. OnCommit).
+	parent, _ := ctx.Value(txHooksKey{}).(*txHooks)
+
+	failed := make(map[int]error)
+	for i, item := range items {
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("for each with savepoint: item %d: savepoint: %w", i, err)
+		}
+
+		itemCtx := ctx
+		var hooks *txHooks
+		if parent != nil {
+			itemCtx, hooks = withTxHooks(ctx)
+		}
+
+		if err := fn(withTxDepth(withTx(itemCtx, key, sp), depth), item); err != nil {
+			failed[i] = err
+			errRollback := sp.Rollback(ctx)
+			if hooks != nil {
+				hooks.runRollback()
+			}
+			if errRollback != nil {
+				return fmt.Errorf("for each with savepoint: item %d: rollback to savepoint: %w", i, errRollback)
+			}
+			continue
+		}
+
+		if err := sp.Commit(ctx); err != nil {
+			return fmt.Errorf("for each with savepoint: item %d: release savepoint: %w", i, err)
+		}
+		if parent != nil {
+			parent.merge(hooks)
+		}
+	}
+
+	if len(failed) > 0 {
+		return &ItemErrors{Failed: failed}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TSQueryMode — способ превращения пользовательского ввода в tsquery.
+type TSQueryMode int
+
+const (
+	// WebSearchQuery — websearch_to_tsquery: синтаксис поисковиков ("фраза", -исключение, or).
+	// Никогда не возвращает синтаксическую ошибку, поэтому подходит для ввода пользователя.
+	WebSearchQuery TSQueryMode = iota
+	// PlainQuery — plainto_tsquery: все слова через AND, операторы в вводе игнорируются.
+	PlainQuery
+	// PhraseQuery — phraseto_tsquery: слова должны идти подряд.
+	PhraseQuery
+	// PrefixQuery — каждое слово ищется как префикс (автодополнение), см. PrefixTSQuery.
+	PrefixQuery
+)
+
+// TSQuery возвращает SQL-выражение tsquery, где configParam и inputParam — номера параметров
+// с конфигурацией (regconfig) и пользовательским вводом. Для PrefixQuery ввод нужно
+// предварительно пропустить через PrefixTSQuery.
+func TSQuery(mode TSQueryMode, configParam, inputParam int) string {
+	fn := map[TSQueryMode]string{
+		WebSearchQuery: "websearch_to_tsquery",
+		PlainQuery:     "plainto_tsquery",
+		PhraseQuery:    "phraseto_tsquery",
+		PrefixQuery:    "to_tsquery",
+	}[mode]
+	return fmt.Sprintf("%s($%d::regconfig, $%d)", fn, configParam, inputParam)
+}
+
+// PrefixTSQuery строит из пользовательского ввода безопасную строку для to_tsquery,
+// в которой каждое слово ищется как префикс: "post gre" → "'post':* & 'gre':*".
+// Все символы, кроме букв и цифр, считаются разделителями, поэтому операторы tsquery
+// во вводе не интерпретируются и не приводят к синтаксическим ошибкам.
+func PrefixTSQuery(input string) string {
+	words := strings.FieldsFunc(input, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, len(words))
+	for i, w := range words {
+		terms[i] = "'" + w + "':*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+// TextSearch описывает полнотекстовый поиск по таблице.
+type TextSearch struct {
+	// Table — таблица, Vector — колонка tsvector или выражение (например,
+	// to_tsvector('russian', title || ' ' || body)). Vector подставляется в SQL как есть
+	// и не должен приходить из пользовательского ввода.
+	Table  string
+	Vector string
+	// Document — текстовая колонка или выражение для фрагментов с подсветкой; пусто — без фрагментов.
+	Document string
+	// Config — конфигурация текстового поиска (regconfig), по умолчанию "simple".
+	Config string
+	Mode   TSQueryMode
+	// StartSel и StopSel обрамляют найденные слова во фрагменте (по умолчанию <b> и </b>),
+	// MaxFragments - сколько фрагментов собирать (0 — весь документ с подсветкой).
+	StartSel     string
+	StopSel      string
+	MaxFragments int
+}
+
+// SearchResult — строка, найденная Search, с её релевантностью и фрагментом.
+type SearchResult[T any] struct {
+	Row     T
+	Rank    float64
+	Snippet string
+}
+
+// Search ищет строки таблицы по пользовательскому вводу input и возвращает не более limit
+// самых релевантных (ts_rank_cd) вместе с фрагментами Document (ts_headline).
+// Колонки выбираются по полям T (см. правила тегов db у Repository).
+// Пустой или состоящий из стоп-слов ввод возвращает пустой результат.
+func Search[T any](ctx context.Context, db QueryExecutor, s TextSearch, input string, limit int) ([]SearchResult[T], error) {
+	meta, err := structMetaOf(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+
+	if s.Config == "" {
+		s.Config = "simple"
+	}
+	if s.Mode == PrefixQuery {
+		input = PrefixTSQuery(input)
+		if input == "" {
+			return nil, nil
+		}
+	}
+
+	snippet := "''"
+	if s.Document != "" {
+		opts := fmt.Sprintf("StartSel=%s, StopSel=%s", headlineOpt(s.StartSel, "<b>"), headlineOpt(s.StopSel, "</b>"))
+		if s.MaxFragments > 0 {
+			opts += fmt.Sprintf(", MaxFragments=%d", s.MaxFragments)
+		}
+		snippet = fmt.Sprintf("ts_headline($1::regconfig, %s, q, %s)", s.Document, quoteLiteral(opts))
+	}
+
+	query := fmt.Sprintf(`SELECT %s, ts_rank_cd(%s, q) AS pgfx_rank, %s AS pgfx_snippet
+		FROM %s, %s AS q
+		WHERE %s @@ q
+		ORDER BY pgfx_rank DESC
+		LIMIT $3`,
+		columnList(meta.fields), s.Vector, snippet, quoteTable(s.Table), TSQuery(s.Mode, 1, 2), s.Vector)
+
+	rows, err := db.Query(ctx, query, s.Config, input, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+
+	results, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (SearchResult[T], error) {
+		var res SearchResult[T]
+		rv := reflect.ValueOf(&res.Row).Elem()
+
+		targets := make([]any, 0, len(meta.fields)+2)
+		for _, f := range meta.fields {
+			targets = append(targets, rv.FieldByIndex(f.index).Addr().Interface())
+		}
+		targets = append(targets, &res.Rank, &res.Snippet)
+
+		return res, row.Scan(targets...)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	return results, nil
+}
+
+// headlineOpt экранирует значение опции ts_headline двойными кавычками.
+func headlineOpt(v, def string) string {
+	if v == "" {
+		v = def
+	}
+	return `"` + strings.ReplaceAll(v, `"`, `""`) + `"`
+}
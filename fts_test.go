@@ -0,0 +1,18 @@
+package pgfx
+
+import "testing"
+
+func TestPrefixTSQuery(t *testing.T) {
+	tests := map[string]string{
+		"post gre":           "'post':* & 'gre':*",
+		"o'reilly & (x | !y": "'o':* & 'reilly':* & 'x':* & 'y':*",
+		"Привет, мир":        "'Привет':* & 'мир':*",
+		"  ":                 "",
+	}
+
+	for in, want := range tests {
+		if got := PrefixTSQuery(in); got != want {
+			t.Errorf("PrefixTSQuery(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+)
+
+var (
+	// ErrNoTransaction возвращается хелперами, которые работают только внутри транзакции,
+	// если в контексте нет активной транзакции pgfx.
+	ErrNoTransaction = errors.New("no active transaction in context")
+	// ErrInvalidSettingName возвращается, если имя параметра не похоже на имя GUC.
+	ErrInvalidSettingName = errors.New("invalid setting name")
+	// ErrSettingNotFound возвращается CurrentSetting, если параметр не задан.
+	ErrSettingNotFound = errors.New("setting not found")
+)
+
+// settingNameRe — имя параметра PostgreSQL: "statement_timeout" или пользовательское "app.tenant_id".
+var settingNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*)*$`)
+
+func validateSettingName(name string) error {
+	if !settingNameRe.MatchString(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidSettingName, name)
+	}
+	return nil
+}
+
+// SetLocal устанавливает параметр name в value до конца текущей транзакции (аналог SET LOCAL).
+//
+// Работает только внутри транзакции db (обычно TransactionalPool), запущенной через TxManager:
+// без транзакции SET LOCAL не имеет эффекта, поэтому в этом случае возвращается ErrNoTransaction.
+// Значение передаётся параметром запроса (set_config), а не подставляется в SQL.
+//
+// Пример:
+//
+//	err := txManager.ReadCommitted(ctx, func(ctx context.Context) error {
+//	    if err := pgfx.SetLocal(ctx, pg.TransactionalPool, "app.tenant_id", tenantID); err != nil {
+//	        return err
+//	    }
+//	    return repo.DoSomething(ctx)
+//	})
+func SetLocal(ctx context.Context, db QueryExecutor, name, value string) error {
+	if err := validateSettingName(name); err != nil {
+		return err
+	}
+
+	tx, ok := txFrom(ctx, executorTxKey(db))
+	if !ok {
+		return ErrNoTransaction
+	}
+
+	if _, err := tx.Exec(ctx, `SELECT set_config($1, $2, true)`, name, value); err != nil {
+		return fmt.Errorf("set local %s: %w", name, err)
+	}
+	return nil
+}
+
+// SettingValue — типы, в которые CurrentSetting умеет разбирать значение параметра.
+type SettingValue interface {
+	~string | ~int64 | ~float64 | ~bool
+}
+
+// CurrentSetting возвращает текущее значение параметра name (current_setting), разобранное в T.
+// Если параметр не задан, возвращается ErrSettingNotFound.
+//
+// Булевы параметры принимают значения on/off/true/false, как их показывает PostgreSQL.
+//
+// Пример:
+//
+//	tenantID, err := pgfx.CurrentSetting[string](ctx, db, "app.tenant_id")
+func CurrentSetting[T SettingValue](ctx context.Context, db QueryExecutor, name string) (T, error) {
+	var zero T
+
+	if err := validateSettingName(name); err != nil {
+		return zero, err
+	}
+
+	var raw *string
+	if err := db.QueryRow(ctx, `SELECT current_setting($1, true)`, name).Scan(&raw); err != nil {
+		return zero, fmt.Errorf("current setting %s: %w", name, err)
+	}
+	if raw == nil {
+		return zero, fmt.Errorf("%w: %s", ErrSettingNotFound, name)
+	}
+
+	v, err := parseSetting[T](*raw)
+	if err != nil {
+		return zero, fmt.Errorf("current setting %s: %w", name, err)
+	}
+	return v, nil
+}
+
+func parseSetting[T SettingValue](raw string) (T, error) {
+	var v T
+
+	rv := reflect.ValueOf(&v).Elem()
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(raw)
+	case reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return v, err
+		}
+		rv.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return v, err
+		}
+		rv.SetFloat(f)
+	case reflect.Bool:
+		switch raw {
+		case "on", "true", "yes", "1":
+			rv.SetBool(true)
+		case "off", "false", "no", "0":
+			rv.SetBool(false)
+		default:
+			return v, fmt.Errorf("invalid boolean %q", raw)
+		}
+	}
+
+	return v, nil
+}
@@ -0,0 +1,137 @@
+package pgfx
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HealthState — состояние базы по данным фоновой проверки (см. HealthCheck).
+type HealthState int32
+
+const (
+	// HealthUnknown — проверка ещё не выполнялась или выключена.
+	HealthUnknown HealthState = iota
+	// HealthHealthy — база отвечает быстро, пул не перегружен.
+	HealthHealthy
+	// HealthDegraded — база отвечает, но медленно, или пул соединений перегружен (см. Overloaded).
+	HealthDegraded
+	// HealthUnreachable — база не отвечает.
+	HealthUnreachable
+)
+
+func (s HealthState) String() string {
+	switch s {
+	case HealthHealthy:
+		return "healthy"
+	case HealthDegraded:
+		return "degraded"
+	case HealthUnreachable:
+		return "unreachable"
+	}
+	return "unknown"
+}
+
+// HealthChangeFunc получает смену состояния базы (см. OnHealthChange).
+type HealthChangeFunc func(old, new HealthState)
+
+// healthChecker периодически проверяет базу и оповещает подписчиков о смене состояния.
+type healthChecker struct {
+	interval time.Duration
+	slow     time.Duration
+	state    atomic.Int32
+
+	mu        sync.Mutex
+	callbacks []HealthChangeFunc
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// Health возвращает последнее известное состояние базы (HealthUnknown без опции HealthCheck).
+func (p *Postgres) Health() HealthState {
+	if p.health == nil {
+		return HealthUnknown
+	}
+	return HealthState(p.health.state.Load())
+}
+
+// OnHealthChange регистрирует fn, который фоновая проверка (см. HealthCheck) вызывает при каждой
+// смене состояния базы, например чтобы переключить readiness-пробу или приостановить консьюмеров.
+// Обработчики вызываются последовательно в горутине проверки и не должны надолго блокироваться.
+// Без опции HealthCheck обработчики никогда не вызываются.
+func (p *Postgres) OnHealthChange(fn HealthChangeFunc) {
+	if p.health == nil {
+		return
+	}
+
+	p.health.mu.Lock()
+	p.health.callbacks = append(p.health.callbacks, fn)
+	p.health.mu.Unlock()
+}
+
+func (p *Postgres) startHealthCheck() {
+	h := p.health
+	go func() {
+		defer close(h.done)
+
+		t := time.NewTicker(h.interval)
+		defer t.Stop()
+
+		for {
+			h.set(p.checkHealth())
+
+			select {
+			case <-h.stop:
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+func (p *Postgres) stopHealthCheck() {
+	if p.health == nil {
+		return
+	}
+	p.health.stopOnce.Do(func() { close(p.health.stop) })
+	<-p.health.done
+}
+
+// checkHealth выполняет одну проверку: Ping с таймаутом в интервал проверки.
+func (p *Postgres) checkHealth() HealthState {
+	ctx, cancel := context.WithTimeout(context.Background(), p.health.interval)
+	defer cancel()
+
+	start := time.Now()
+	err := p.Pool.Ping(ctx)
+	elapsed := time.Since(start)
+
+	switch {
+	case err != nil && p.Overloaded():
+		// Ping не дождался свободного соединения: база жива, но пул исчерпан.
+		return HealthDegraded
+	case err != nil:
+		return HealthUnreachable
+	case p.Overloaded() || p.health.slow > 0 && elapsed > p.health.slow:
+		return HealthDegraded
+	}
+	return HealthHealthy
+}
+
+func (h *healthChecker) set(state HealthState) {
+	old := HealthState(h.state.Swap(int32(state)))
+	if old == state {
+		return
+	}
+
+	h.mu.Lock()
+	callbacks := append([]HealthChangeFunc(nil), h.callbacks...)
+	h.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(old, state)
+	}
+}
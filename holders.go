@@ -0,0 +1,155 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ConnHolder — соединение, взятое из пула и ещё не возвращённое (см. TrackConnectionHolders).
+type ConnHolder struct {
+	// Caller — первая строка кода вне pgx и pgfx, взявшая соединение ("pkg.Func file.go:42").
+	Caller string
+	// InTransaction — соединение взято под транзакцию; TxName — её имя (WithTxName).
+	InTransaction bool
+	TxName        string
+	Since         time.Time
+	Held          time.Duration
+}
+
+// CloseReport — соединения, не возвращённые в пул к началу закрытия: обычно это
+// незакрытые Rows, забытые транзакции или запросы, пережившие остановку сервиса.
+type CloseReport struct {
+	Holders []ConnHolder
+}
+
+func (r CloseReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d connections still acquired", len(r.Holders))
+	for _, h := range r.Holders {
+		fmt.Fprintf(&b, "\n  %s held for %s", h.Caller, h.Held.Round(time.Millisecond))
+		if h.InTransaction {
+			fmt.Fprintf(&b, " in transaction %q", txDisplayName(h.TxName))
+		}
+	}
+	return b.String()
+}
+
+type beginTxKey struct{}
+
+// holderTracer запоминает, кто и когда взял каждое соединение пула. Реализует
+// pgxpool.AcquireTracer и pgxpool.ReleaseTracer; методы pgx.QueryTracer — заглушки.
+type holderTracer struct {
+	mu      sync.Mutex
+	holders map[*pgx.Conn]ConnHolder
+}
+
+func newHolderTracer() *holderTracer {
+	return &holderTracer{holders: make(map[*pgx.Conn]ConnHolder)}
+}
+
+func (t *holderTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return ctx
+}
+
+func (t *holderTracer) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
+
+func (t *holderTracer) TraceAcquireStart(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	return ctx
+}
+
+func (t *holderTracer) TraceAcquireEnd(ctx context.Context, _ *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	if data.Err != nil || data.Conn == nil {
+		return
+	}
+
+	inTx, _ := ctx.Value(beginTxKey{}).(bool)
+	h := ConnHolder{Caller: externalCaller(), InTransaction: inTx, Since: time.Now()}
+	if inTx {
+		h.TxName = TxName(ctx)
+	}
+
+	t.mu.Lock()
+	t.holders[data.Conn] = h
+	t.mu.Unlock()
+}
+
+func (t *holderTracer) TraceRelease(_ *pgxpool.Pool, data pgxpool.TraceReleaseData) {
+	t.mu.Lock()
+	delete(t.holders, data.Conn)
+	t.mu.Unlock()
+}
+
+func (t *holderTracer) report() CloseReport {
+	now := time.Now()
+
+	t.mu.Lock()
+	r := CloseReport{Holders: make([]ConnHolder, 0, len(t.holders))}
+	for _, h := range t.holders {
+		h.Held = now.Sub(h.Since)
+		r.Holders = append(r.Holders, h)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(r.Holders, func(i, j int) bool { return r.Holders[i].Since.Before(r.Holders[j].Since) })
+	return r
+}
+
+// externalCaller возвращает первый кадр стека вне pgx, pgxpool и pgfx.
+func externalCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "github.com/jackc/") &&
+			!strings.HasPrefix(f.Function, "github.com/fr11nik/pgfx.") &&
+			!strings.HasPrefix(f.Function, "runtime.") {
+			return fmt.Sprintf("%s %s:%d", f.Function, f.File, f.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// Shutdown закрывает Postgres, как Close, и возвращает соединения, которые к началу закрытия
+// ещё не были возвращены в пул (с опцией TrackConnectionHolders; без неё отчёт пуст).
+// Закрытие пула ждёт возврата всех соединений; если ctx завершился раньше, Shutdown возвращает
+// отчёт и ошибку контекста, а закрытие продолжается в фоне.
+func (p *Postgres) Shutdown(ctx context.Context) (CloseReport, error) {
+	var report CloseReport
+	if p.holders != nil {
+		report = p.holders.report()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.close()
+	}()
+
+	select {
+	case <-done:
+		return report, nil
+	case <-ctx.Done():
+		return report, fmt.Errorf("postgres - Shutdown: %w", ctx.Err())
+	}
+}
+
+func (p *Postgres) logHolders() {
+	if p.holders == nil {
+		return
+	}
+	if r := p.holders.report(); len(r.Holders) > 0 {
+		log.Printf("pgfx: closing pool: %s", r)
+	}
+}
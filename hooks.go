@@ -0,0 +1,140 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// txHooks — обработчики, которые менеджер выполняет по завершении транзакции (или savepoint).
+type txHooks struct {
+	mu       sync.Mutex
+	before   []func(ctx context.Context) error
+	commit   []func()
+	rollback []func()
+}
+
+type txHooksKey struct{}
+
+func withTxHooks(ctx context.Context) (context.Context, *txHooks) {
+	h := &txHooks{}
+	return context.WithValue(ctx, txHooksKey{}, h), h
+}
+
+// runCommit выполняет обработчики после успешного коммита в порядке регистрации.
+func (h *txHooks) runCommit() {
+	h.mu.Lock()
+	fns := h.commit
+	h.before, h.commit, h.rollback = nil, nil, nil
+	h.mu.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// runBeforeCommit выполняет обработчики BeforeCommit в порядке регистрации, включая
+// зарегистрированные другими обработчиками, и останавливается на первой ошибке.
+func (h *txHooks) runBeforeCommit(ctx context.Context) error {
+	for {
+		h.mu.Lock()
+		if len(h.before) == 0 {
+			h.mu.Unlock()
+			return nil
+		}
+		fn := h.before[0]
+		h.before = h.before[1:]
+		h.mu.Unlock()
+
+		if err := fn(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// runRollback выполняет обработчики после отката в обратном порядке регистрации:
+// компенсирующие действия отменяют шаги от последнего к первому.
+func (h *txHooks) runRollback() {
+	h.mu.Lock()
+	fns := h.rollback
+	h.before, h.commit, h.rollback = nil, nil, nil
+	h.mu.Unlock()
+
+	for i := len(fns) - 1; i >= 0; i-- {
+		fns[i]()
+	}
+}
+
+// merge переносит обработчики зафиксированного savepoint в транзакцию уровнем выше.
+func (h *txHooks) merge(child *txHooks) {
+	child.mu.Lock()
+	before, commit, rollback := child.before, child.commit, child.rollback
+	child.mu.Unlock()
+
+	h.mu.Lock()
+	h.before = append(h.before, before...)
+	h.commit = append(h.commit, commit...)
+	h.rollback = append(h.rollback, rollback...)
+	h.mu.Unlock()
+}
+
+// OnCommit регистрирует fn, который менеджер выполнит после успешного коммита текущей транзакции —
+// например, чтобы опубликовать событие или сбросить кэш только когда изменения действительно
+// зафиксированы. При откате fn не выполняется. Обработчики выполняются в порядке регистрации,
+// синхронно, до возврата из ReadCommitted; изменения базы в них уже не входят в транзакцию.
+//
+// Обработчик, зарегистрированный на savepoint (NestSavepoint), переносится во внешнюю
+// транзакцию при его успешном завершении и отбрасывается при откате к savepoint.
+// Вне транзакции менеджера возвращает ErrNoTransaction.
+func OnCommit(ctx context.Context, fn func()) error {
+	h, ok := ctx.Value(txHooksKey{}).(*txHooks)
+	if !ok {
+		return fmt.Errorf("on commit: %w", ErrNoTransaction)
+	}
+
+	h.mu.Lock()
+	h.commit = append(h.commit, fn)
+	h.mu.Unlock()
+	return nil
+}
+
+// OnRollback регистрирует fn, который менеджер выполнит после отката текущей транзакции
+// (ошибка обработчика, паника или неудачный коммит) — например, чтобы вернуть зарезервированный
+// в памяти остаток товара. Обработчики выполняются в обратном порядке регистрации, синхронно,
+// до возврата из ReadCommitted.
+//
+// Обработчик, зарегистрированный на savepoint (NestSavepoint), выполняется при откате
+// к этому savepoint, а при его успешном завершении переносится во внешнюю транзакцию.
+// Вне транзакции менеджера возвращает ErrNoTransaction.
+func OnRollback(ctx context.Context, fn func()) error {
+	h, ok := ctx.Value(txHooksKey{}).(*txHooks)
+	if !ok {
+		return fmt.Errorf("on rollback: %w", ErrNoTransaction)
+	}
+
+	h.mu.Lock()
+	h.rollback = append(h.rollback, fn)
+	h.mu.Unlock()
+	return nil
+}
+
+// BeforeCommit регистрирует fn, который менеджер выполнит непосредственно перед COMMIT текущей
+// транзакции, ещё внутри неё (ctx содержит транзакцию), — например, чтобы репозиторий выполнил
+// итоговую проверку согласованности или записал строку аудита, которая должна попасть в ту же
+// транзакцию. Ошибка fn откатывает транзакцию и возвращается из ReadCommitted. Обработчики
+// выполняются в порядке регистрации; обработчик может зарегистрировать ещё один.
+//
+// Обработчик, зарегистрированный на savepoint (NestSavepoint), переносится во внешнюю
+// транзакцию при его успешном завершении и отбрасывается при откате к savepoint.
+// Вне транзакции менеджера возвращает ErrNoTransaction.
+func BeforeCommit(ctx context.Context, fn func(ctx context.Context) error) error {
+	h, ok := ctx.Value(txHooksKey{}).(*txHooks)
+	if !ok {
+		return fmt.Errorf("before commit: %w", ErrNoTransaction)
+	}
+
+	h.mu.Lock()
+	h.before = append(h.before, fn)
+	h.mu.Unlock()
+	return nil
+}
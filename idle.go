@@ -0,0 +1,206 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// IdleTransactionError — транзакция откачена сторожем, потому что дольше порога не выполняла
+// ни одного запроса (см. WatchIdleTransactions). Возвращается всеми последующими запросами
+// транзакции и её коммитом, а также доступен обработчику через context.Cause(ctx).
+type IdleTransactionError struct {
+	TxName    string
+	Idle      time.Duration
+	Threshold time.Duration
+}
+
+func (e *IdleTransactionError) Error() string {
+	return fmt.Sprintf("transaction %q rolled back: idle in transaction for %s (threshold %s)", txDisplayName(e.TxName), e.Idle.Round(time.Millisecond), e.Threshold)
+}
+
+// idleWatch отслеживает активность транзакции: число выполняющихся запросов и время
+// завершения последнего.
+type idleWatch struct {
+	mu       sync.Mutex
+	inflight int
+	last     time.Time
+	warned   bool
+	// err — причина отката; после него все операции транзакции возвращают err.
+	err error
+}
+
+func (w *idleWatch) begin() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return w.err
+	}
+	w.inflight++
+	return nil
+}
+
+func (w *idleWatch) end() {
+	w.mu.Lock()
+	w.inflight--
+	w.last = time.Now()
+	w.warned = false
+	w.mu.Unlock()
+}
+
+// watchIdle запускает сторожа транзакции tx. При rollback транзакция, простаивающая дольше
+// threshold, откатывается, а контекст обработчика отменяется с *IdleTransactionError;
+// иначе простой только логируется. Возвращает транзакцию, через которую нужно выполнять
+// запросы, и функцию остановки сторожа.
+func (m *Manager) watchIdle(ctx context.Context, tx pgx.Tx, name string, cancel context.CancelCauseFunc) (pgx.Tx, func()) {
+	w := &idleWatch{last: time.Now()}
+	done := make(chan struct{})
+
+	tick := m.idleTx / 4
+	if tick < 10*time.Millisecond {
+		tick = 10 * time.Millisecond
+	}
+
+	go func() {
+		t := time.NewTicker(tick)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+
+			w.mu.Lock()
+			idle := time.Since(w.last)
+			if w.inflight > 0 || w.err != nil || idle < m.idleTx {
+				w.mu.Unlock()
+				continue
+			}
+
+			if !m.idleRollback {
+				if !w.warned {
+					w.warned = true
+					log.Printf("pgfx: transaction %q idle in transaction for %s (threshold %s)", txDisplayName(name), idle.Round(time.Millisecond), m.idleTx)
+				}
+				w.mu.Unlock()
+				continue
+			}
+
+			// Откатываем под блокировкой: новые запросы обработчика ждут и получают ошибку,
+			// а не уходят на соединение одновременно с ROLLBACK.
+			idleErr := &IdleTransactionError{TxName: name, Idle: idle, Threshold: m.idleTx}
+			w.err = idleErr
+			if err := tx.Rollback(context.WithoutCancel(ctx)); err != nil {
+				log.Printf("pgfx: rollback of idle transaction %q failed: %v", txDisplayName(name), err)
+			}
+			w.mu.Unlock()
+
+			cancel(idleErr)
+			return
+		}
+	}()
+
+	return watchedTx{Tx: tx, w: w}, func() { close(done) }
+}
+
+// watchedTx отмечает в idleWatch начало и конец каждого запроса транзакции.
+type watchedTx struct {
+	pgx.Tx
+	w *idleWatch
+}
+
+func (t watchedTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	if err := t.w.begin(); err != nil {
+		return nil, err
+	}
+	defer t.w.end()
+
+	tx, err := t.Tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return watchedTx{Tx: tx, w: t.w}, nil
+}
+
+func (t watchedTx) Commit(ctx context.Context) error {
+	if err := t.w.begin(); err != nil {
+		return err
+	}
+	defer t.w.end()
+
+	return t.Tx.Commit(ctx)
+}
+
+// Rollback после отката сторожем ничего не делает: транзакция уже завершена.
+func (t watchedTx) Rollback(ctx context.Context) error {
+	if err := t.w.begin(); err != nil {
+		return nil
+	}
+	defer t.w.end()
+
+	return t.Tx.Rollback(ctx)
+}
+
+func (t watchedTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if err := t.w.begin(); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer t.w.end()
+
+	return t.Tx.Exec(ctx, sql, args...)
+}
+
+func (t watchedTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if err := t.w.begin(); err != nil {
+		return nil, err
+	}
+
+	rows, err := t.Tx.Query(ctx, sql, args...)
+	if err != nil {
+		t.w.end()
+		return rows, err
+	}
+	// Запрос выполняется, пока rows не дочитаны или не закрыты.
+	return &cancelRows{Rows: rows, cancel: sync.OnceFunc(t.w.end)}, nil
+}
+
+func (t watchedTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if err := t.w.begin(); err != nil {
+		return errRow{err}
+	}
+	return cancelRow{Row: t.Tx.QueryRow(ctx, sql, args...), cancel: sync.OnceFunc(t.w.end)}
+}
+
+func (t watchedTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	if err := t.w.begin(); err != nil {
+		return errBatchResults{err}
+	}
+	return gatedBatchResults{BatchResults: t.Tx.SendBatch(ctx, b), release: sync.OnceFunc(t.w.end)}
+}
+
+func (t watchedTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	if err := t.w.begin(); err != nil {
+		return 0, err
+	}
+	defer t.w.end()
+
+	return t.Tx.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (t watchedTx) copyTo(ctx context.Context, w io.Writer, sql string) (pgconn.CommandTag, error) {
+	if err := t.w.begin(); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer t.w.end()
+
+	return copyToTx(ctx, t.Tx, w, sql)
+}
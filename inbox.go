@@ -0,0 +1,93 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrAlreadyProcessed возвращается Inbox.Process, если сообщение с таким идентификатором
+// уже было успешно обработано ранее. Обработчик в этом случае не вызывается.
+var ErrAlreadyProcessed = errors.New("message already processed")
+
+// Inbox реализует паттерн "inbox" (таблица дедупликации) для потребителей очередей.
+//
+// Идентификатор сообщения записывается в таблицу inbox в той же транзакции, в которой
+// выполняется обработчик. Если обработчик завершился ошибкой, транзакция откатывается
+// вместе с отметкой об обработке, и сообщение можно обработать повторно. Если транзакция
+// закоммичена, повторная доставка того же сообщения будет пропущена — так достигается
+// эффект exactly-once поверх at-least-once доставки (например, Kafka).
+type Inbox struct {
+	db    QueryExecutor
+	tm    *Manager
+	table string
+}
+
+// NewInbox создаёт Inbox, который хранит идентификаторы обработанных сообщений в таблице table.
+// Таблицу можно создать методом Inbox.CreateTable.
+func (p *Postgres) NewInbox(table string) *Inbox {
+	return &Inbox{
+		db:    p.TransactionalPool,
+		tm:    p.NewTransactionManager(),
+		table: quoteTable(table),
+	}
+}
+
+// CreateTable создаёт таблицу inbox, если её ещё нет.
+func (i *Inbox) CreateTable(ctx context.Context) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	message_id   TEXT PRIMARY KEY,
+	processed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`, i.table)
+
+	if _, err := i.db.Exec(ctx, query); err != nil {
+		return fmt.Errorf("inbox - CreateTable: %w", err)
+	}
+	return nil
+}
+
+// Process выполняет fn ровно один раз для каждого messageID.
+//
+// Отметка об обработке и все запросы fn, выполненные через TransactionalPool,
+// попадают в одну транзакцию. Если сообщение уже обработано, fn не вызывается
+// и возвращается ErrAlreadyProcessed. Конкурентная обработка одного и того же
+// сообщения сериализуется уникальным индексом: второй обработчик дождётся
+// завершения первого и получит ErrAlreadyProcessed, если первый закоммитился.
+//
+// Пример:
+//
+//	err := inbox.Process(ctx, msg.Key, func(ctx context.Context) error {
+//	    return orders.Apply(ctx, msg)
+//	})
+//	if err != nil && !errors.Is(err, pgfx.ErrAlreadyProcessed) {
+//	    return err
+//	}
+//	consumer.Commit(msg)
+func (i *Inbox) Process(ctx context.Context, messageID string, fn func(ctx context.Context) error) error {
+	query := fmt.Sprintf(`INSERT INTO %s (message_id) VALUES ($1) ON CONFLICT (message_id) DO NOTHING`, i.table)
+
+	return i.tm.ReadCommitted(ctx, func(ctx context.Context) error {
+		tag, err := i.db.Exec(ctx, query, messageID)
+		if err != nil {
+			return fmt.Errorf("inbox - mark processed: %w", err)
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrAlreadyProcessed
+		}
+
+		return fn(ctx)
+	})
+}
+
+// Forget удаляет отметки об обработке, сделанные раньше olderThan (интервал PostgreSQL,
+// например "7 days"), чтобы таблица inbox не росла бесконечно. Возвращает количество удалённых строк.
+func (i *Inbox) Forget(ctx context.Context, olderThan string) (int64, error) {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE processed_at < now() - $1::interval`, i.table)
+
+	tag, err := i.db.Exec(ctx, query, olderThan)
+	if err != nil {
+		return 0, fmt.Errorf("inbox - Forget: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,112 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// InvalidateAll — ключ, с которым вызывается обработчик подписки после переподключения:
+// уведомления, отправленные пока соединения не было, потеряны, поэтому локальный кэш
+// следует сбросить целиком.
+const InvalidateAll = "*"
+
+const _invalidationReconnectDelay = time.Second
+
+// InvalidationBus рассылает ключи инвалидации кэша между экземплярами сервиса через LISTEN/NOTIFY.
+//
+// Публикация выполняется через TransactionalPool. Внутри транзакции PostgreSQL доставляет
+// уведомления только после коммита (и не доставляет при откате), поэтому подписчики
+// никогда не сбросят кэш раньше, чем изменения станут видны, и не сбросят его зря.
+// Одинаковые ключи в рамках одной транзакции схлопываются сервером.
+type InvalidationBus struct {
+	pool    *pgxpool.Pool
+	db      QueryExecutor
+	channel string
+}
+
+// NewInvalidationBus создаёт шину инвалидации поверх канала NOTIFY channel.
+func (p *Postgres) NewInvalidationBus(channel string) *InvalidationBus {
+	return &InvalidationBus{
+		pool:    p.Pool,
+		db:      p.TransactionalPool,
+		channel: channel,
+	}
+}
+
+// Publish отправляет ключи инвалидации подписчикам.
+//
+// Пример:
+//
+//	err := txManager.ReadCommitted(ctx, func(ctx context.Context) error {
+//	    if err := repo.UpdateProduct(ctx, p); err != nil {
+//	        return err
+//	    }
+//	    return bus.Publish(ctx, "product:"+p.ID)
+//	})
+func (b *InvalidationBus) Publish(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if _, err := b.db.Exec(ctx, `SELECT pg_notify($1, k) FROM unnest($2::text[]) AS k`, b.channel, keys); err != nil {
+		return fmt.Errorf("invalidation bus - Publish: %w", err)
+	}
+	return nil
+}
+
+// Subscribe слушает канал и вызывает handler для каждого полученного ключа, пока не отменён ctx.
+//
+// Подписка держит одно соединение пула. При потере соединения Subscribe переподключается
+// и, как только LISTEN снова выполнен, вызывает handler с ключом InvalidateAll: значение,
+// загруженное после этого вызова, уже не пропустит уведомление о своём изменении.
+// Возвращает ctx.Err() после отмены контекста.
+func (b *InvalidationBus) Subscribe(ctx context.Context, handler func(key string)) error {
+	for first := true; ; first = false {
+		err := b.listen(ctx, handler, !first)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		log.Printf("pgfx: invalidation bus %q: %v, reconnecting", b.channel, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(_invalidationReconnectDelay):
+		}
+	}
+}
+
+// listen выполняет LISTEN на отдельном соединении и передаёт уведомления handler;
+// при reconnect после LISTEN вызывает handler(InvalidateAll).
+func (b *InvalidationBus) listen(ctx context.Context, handler func(key string), reconnect bool) error {
+	conn, err := b.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire: %w", err)
+	}
+	defer func() {
+		// Соединение в режиме LISTEN нельзя возвращать в пул: закрываем его,
+		// и пул откроет новое при необходимости.
+		_ = conn.Conn().Close(context.Background())
+		conn.Release()
+	}()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+quoteIdent(b.channel)); err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	if reconnect {
+		handler(InvalidateAll)
+	}
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("wait for notification: %w", err)
+		}
+		handler(n.Payload)
+	}
+}
@@ -0,0 +1,80 @@
+package pgfx
+
+import (
+	"context"
+	"database/sql"
+	"iter"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Iterate выполняет запрос и возвращает итератор по строкам результата.
+// Строки сканируются по одной, поэтому весь набор никогда не материализуется в памяти.
+//
+// Если T — структура (кроме time.Time и типов, реализующих sql.Scanner), колонки сопоставляются с полями по имени (как в pgx.RowToStructByName,
+// с учётом тегов `db`), иначе запрос должен возвращать одну колонку, которая сканируется в T.
+//
+// Ошибка запроса или сканирования передаётся вторым значением итерации, после чего
+// итерация завершается. При досрочном выходе из цикла (break/return) rows закрываются.
+//
+// Пример:
+//
+//	for u, err := range pgfx.Iterate[User](ctx, db, "SELECT id, name FROM users") {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    ...
+//	}
+func Iterate[T any](ctx context.Context, db QueryExecutor, sql string, args ...any) iter.Seq2[T, error] {
+	return IterateFunc(ctx, db, rowScanFunc[T](), sql, args...)
+}
+
+// rowScanFunc выбирает функцию сканирования строки в T: по именам колонок для структур,
+// по единственной колонке — для остальных типов.
+func rowScanFunc[T any]() pgx.RowToFunc[T] {
+	if isRowStruct(reflect.TypeFor[T]()) {
+		return pgx.RowToStructByName[T]
+	}
+	return pgx.RowTo[T]
+}
+
+// isRowStruct сообщает, нужно ли сканировать t как строку целиком, а не как значение одной колонки.
+// Структуры-значения (time.Time, типы, реализующие sql.Scanner) сканируются как одна колонка.
+func isRowStruct(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t == reflect.TypeFor[time.Time]() {
+		return false
+	}
+	return !reflect.PointerTo(t).Implements(reflect.TypeFor[sql.Scanner]())
+}
+
+// IterateFunc аналогичен Iterate, но использует переданную функцию сканирования строки.
+func IterateFunc[T any](ctx context.Context, db QueryExecutor, scan pgx.RowToFunc[T], sql string, args ...any) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		rows, err := db.Query(ctx, sql, args...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			v, err := scan(rows)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			if !yield(v, nil) {
+				return
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package pgfx
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// JSONFormat определяет формат, в котором QueryToJSON пишет строки результата.
+type JSONFormat int
+
+const (
+	// JSONArray — один JSON-массив объектов: [{"id":1},{"id":2}].
+	JSONArray JSONFormat = iota
+	// NDJSON — по одному JSON-объекту на строку (newline-delimited JSON).
+	NDJSON
+)
+
+// _jsonFlushEvery — через сколько строк буфер принудительно сбрасывается в writer.
+const _jsonFlushEvery = 1000
+
+// QueryToJSON выполняет запрос и пишет результат в w построчно, не накапливая
+// весь набор строк в памяти. Каждая строка кодируется как JSON-объект, ключи
+// которого — имена колонок в порядке их следования в запросе.
+//
+// Если w реализует http.Flusher (например, http.ResponseWriter), данные
+// периодически отправляются клиенту, что позволяет отдавать большие выгрузки
+// без ожидания окончания запроса.
+//
+// Возвращает количество записанных строк.
+//
+// Пример:
+//
+//	func (h *handler) Export(w http.ResponseWriter, r *http.Request) {
+//	    w.Header().Set("Content-Type", "application/x-ndjson")
+//	    _, err := pgfx.QueryToJSON(r.Context(), h.db, w, pgfx.NDJSON, "SELECT id, name FROM users")
+//	    ...
+//	}
+func QueryToJSON(ctx context.Context, db QueryExecutor, w io.Writer, format JSONFormat, sql string, args ...any) (int64, error) {
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf("query to json: %w", err)
+	}
+	defer rows.Close()
+
+	fields := rows.FieldDescriptions()
+	keys := make([][]byte, len(fields))
+	for i, fd := range fields {
+		keys[i], err = json.Marshal(fd.Name)
+		if err != nil {
+			return 0, fmt.Errorf("query to json: marshal column name: %w", err)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	flusher, _ := w.(http.Flusher)
+
+	flush := func() error {
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	if format == JSONArray {
+		if err := bw.WriteByte('['); err != nil {
+			return 0, fmt.Errorf("query to json: %w", err)
+		}
+	}
+
+	var n int64
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return n, fmt.Errorf("query to json: read row: %w", err)
+		}
+
+		if format == JSONArray && n > 0 {
+			if err := bw.WriteByte(','); err != nil {
+				return n, fmt.Errorf("query to json: %w", err)
+			}
+		}
+
+		if err := writeJSONRow(bw, keys, fields, values); err != nil {
+			return n, fmt.Errorf("query to json: %w", err)
+		}
+
+		if format == NDJSON {
+			if err := bw.WriteByte('\n'); err != nil {
+				return n, fmt.Errorf("query to json: %w", err)
+			}
+		}
+
+		n++
+		if n%_jsonFlushEvery == 0 {
+			if err := flush(); err != nil {
+				return n, fmt.Errorf("query to json: %w", err)
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return n, fmt.Errorf("query to json: %w", err)
+	}
+
+	if format == JSONArray {
+		if err := bw.WriteByte(']'); err != nil {
+			return n, fmt.Errorf("query to json: %w", err)
+		}
+	}
+
+	if err := flush(); err != nil {
+		return n, fmt.Errorf("query to json: %w", err)
+	}
+
+	return n, nil
+}
+
+func writeJSONRow(w *bufio.Writer, keys [][]byte, fields []pgconn.FieldDescription, values []any) error {
+	if err := w.WriteByte('{'); err != nil {
+		return err
+	}
+
+	for i, v := range values {
+		if i > 0 {
+			if err := w.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(keys[i]); err != nil {
+			return err
+		}
+		if err := w.WriteByte(':'); err != nil {
+			return err
+		}
+
+		b, err := json.Marshal(jsonValue(fields[i].DataTypeOID, v))
+		if err != nil {
+			return fmt.Errorf("marshal column %q: %w", fields[i].Name, err)
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	return w.WriteByte('}')
+}
+
+// jsonValue приводит значения, которые pgx возвращает в неудобном для JSON виде,
+// к их привычному текстовому представлению.
+func jsonValue(oid uint32, v any) any {
+	if oid == pgtype.UUIDOID {
+		if b, ok := v.([16]byte); ok {
+			return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+		}
+	}
+	return v
+}
@@ -0,0 +1,84 @@
+package pgfx
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// keepalive периодически проверяет простаивающие соединения пула (см. Keepalive).
+type keepalive struct {
+	interval time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func (p *Postgres) startKeepalive() {
+	k := p.keepalive
+	go func() {
+		defer close(k.done)
+
+		t := time.NewTicker(k.interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-k.stop:
+				return
+			case <-t.C:
+				p.pingIdle()
+			}
+		}
+	}()
+}
+
+func (p *Postgres) stopKeepalive() {
+	if p.keepalive == nil {
+		return
+	}
+	p.keepalive.stopOnce.Do(func() { close(p.keepalive.stop) })
+	<-p.keepalive.done
+}
+
+// pingIdle проверяет одно случайное простаивающее соединение; остальные сразу возвращаются в пул,
+// поэтому зависший сервер не задерживает другие запросы. Если проверенное соединение мертво,
+// то, скорее всего, мертвы и остальные (рестарт сервера, обрыв сети), поэтому пул пересоздаёт
+// соединения: простаивающие закрываются сразу, занятые — при возврате, а до MinConns
+// пул восстанавливается сам.
+func (p *Postgres) pingIdle() {
+	ctx, cancel := context.WithTimeout(context.Background(), p.keepalive.interval)
+	defer cancel()
+
+	// Пул не умеет выдать одно простаивающее соединение, не устанавливая новое,
+	// поэтому берём все и сразу отпускаем лишние.
+	idle := p.Pool.AcquireAllIdle(ctx)
+	if len(idle) == 0 {
+		return
+	}
+	i := rand.IntN(len(idle))
+	for j, conn := range idle {
+		if j != i {
+			conn.Release()
+		}
+	}
+
+	sample := idle[i]
+	if sample.Ping(ctx) == nil {
+		sample.Release()
+		return
+	}
+
+	closeConn(sample)
+	p.Pool.Reset()
+}
+
+// closeConn закрывает соединение, чтобы пул не вернул его следующему запросу.
+func closeConn(conn *pgxpool.Conn) {
+	_ = conn.Conn().Close(context.Background())
+	conn.Release()
+}
@@ -0,0 +1,84 @@
+package pgfx
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+)
+
+// WithLimitGuard добавляет LIMIT max к SELECT верхнего уровня, у которых нет ни LIMIT, ни FETCH,
+// и один раз логирует каждый такой запрос, чтобы его можно было найти и исправить. Это страховка
+// для админских и отчётных эндпоинтов от выгрузки всей таблицы, а не замена пагинации.
+//
+// Запросы без FROM (SELECT now()), SELECT ... INTO и запросы, где основная часть не SELECT
+// (WITH ... DELETE), не переписываются. LIMIT ставится перед FOR UPDATE/SHARE и ограничивает
+// весь UNION целиком. Чтобы выполнить запрос без ограничения, используйте контекст WithoutLimitGuard.
+func WithLimitGuard(max int) Option {
+	g := &limitGuard{limit: " LIMIT " + strconv.Itoa(max)}
+	return WithQueryRewriter(g.rewrite)
+}
+
+type withoutLimitGuardKey struct{}
+
+// WithoutLimitGuard возвращает контекст, в котором WithLimitGuard не добавляет LIMIT.
+func WithoutLimitGuard(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutLimitGuardKey{}, true)
+}
+
+type limitGuard struct {
+	limit string
+	// logged — запросы, о которых уже предупредили.
+	logged sync.Map
+}
+
+func (g *limitGuard) rewrite(ctx context.Context, sql string) string {
+	if off, _ := ctx.Value(withoutLimitGuardKey{}).(bool); off {
+		return sql
+	}
+
+	pos, ok := unboundedSelect(topLevel(scanSQL(sql)))
+	if !ok {
+		return sql
+	}
+
+	if _, seen := g.logged.LoadOrStore(sql, struct{}{}); !seen {
+		log.Printf("pgfx: limit guard: SELECT without LIMIT, appending%s: %s", g.limit, sql)
+	}
+	return applyEdits(sql, []sqlEdit{insertAt(pos, g.limit)})
+}
+
+// unboundedSelect сообщает, является ли запрос SELECT без LIMIT/FETCH, и возвращает позицию,
+// в которую нужно вставить LIMIT.
+func unboundedSelect(top []sqlToken) (int, bool) {
+	if len(top) == 0 {
+		return 0, false
+	}
+
+	// Пропускаем CTE: их тела во вложенных скобках, на верхнем уровне остаются имена и AS.
+	main := 0
+	if top[0].is("with") {
+		main = indexOf(top, 1, "select", "insert", "update", "delete", "values", "table")
+		if main < 0 {
+			return 0, false
+		}
+	}
+	if !top[main].is("select") {
+		return 0, false
+	}
+
+	body := top[main:]
+	if indexOf(body, 0, "limit", "fetch", "into") >= 0 || indexOf(body, 0, "from") < 0 {
+		return 0, false
+	}
+
+	if i := indexOf(body, 0, "for"); i >= 0 {
+		return body[i-1].end, true
+	}
+
+	end := len(body) - 1
+	for end >= 0 && body[end].kind == tokPunct && body[end].text == ";" {
+		end--
+	}
+	return body[end].end, true
+}
@@ -0,0 +1,92 @@
+package pgfx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestLimitGuardRewrite(t *testing.T) {
+	g := &limitGuard{limit: " LIMIT 100"}
+
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "select without limit",
+			sql:  `SELECT id FROM users WHERE active ORDER BY id`,
+			want: `SELECT id FROM users WHERE active ORDER BY id LIMIT 100`,
+		},
+		{
+			name: "existing limit",
+			sql:  `SELECT id FROM users LIMIT $1`,
+			want: `SELECT id FROM users LIMIT $1`,
+		},
+		{
+			name: "fetch first",
+			sql:  `SELECT id FROM users FETCH FIRST 10 ROWS ONLY`,
+			want: `SELECT id FROM users FETCH FIRST 10 ROWS ONLY`,
+		},
+		{
+			name: "limit only in subquery",
+			sql:  `SELECT * FROM (SELECT id FROM users LIMIT 5) u JOIN orders o ON o.user_id = u.id;`,
+			want: `SELECT * FROM (SELECT id FROM users LIMIT 5) u JOIN orders o ON o.user_id = u.id LIMIT 100;`,
+		},
+		{
+			name: "for update",
+			sql:  `SELECT id FROM jobs WHERE state = 'new' FOR UPDATE SKIP LOCKED`,
+			want: `SELECT id FROM jobs WHERE state = 'new' LIMIT 100 FOR UPDATE SKIP LOCKED`,
+		},
+		{
+			name: "cte select",
+			sql:  `WITH a AS (SELECT id FROM users) SELECT * FROM a`,
+			want: `WITH a AS (SELECT id FROM users) SELECT * FROM a LIMIT 100`,
+		},
+		{
+			name: "cte delete",
+			sql:  `WITH a AS (SELECT id FROM users) DELETE FROM orders WHERE user_id IN (SELECT id FROM a)`,
+			want: `WITH a AS (SELECT id FROM users) DELETE FROM orders WHERE user_id IN (SELECT id FROM a)`,
+		},
+		{
+			name: "no from",
+			sql:  `SELECT now()`,
+			want: `SELECT now()`,
+		},
+		{
+			name: "keyword in literal",
+			sql:  `SELECT id FROM users WHERE note = 'limit'`,
+			want: `SELECT id FROM users WHERE note = 'limit' LIMIT 100`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := g.rewrite(context.Background(), tt.sql); got != tt.want {
+				t.Errorf("rewrite() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got := g.rewrite(WithoutLimitGuard(context.Background()), `SELECT id FROM users`); got != `SELECT id FROM users` {
+		t.Errorf("rewrite() with WithoutLimitGuard = %s", got)
+	}
+}
+
+func TestRewriteBatchKeepsCallerBatch(t *testing.T) {
+	g := &limitGuard{limit: " LIMIT 100"}
+	p := pgTransactor{rewriters: []QueryRewriter{g.rewrite}}
+
+	b := &pgx.Batch{}
+	b.Queue(`SELECT id FROM users`)
+
+	rb := p.rewriteBatch(context.Background(), b)
+	if got, want := rb.QueuedQueries[0].SQL, `SELECT id FROM users LIMIT 100`; got != want {
+		t.Errorf("rewritten = %s, want %s", got, want)
+	}
+	if got, want := b.QueuedQueries[0].SQL, `SELECT id FROM users`; got != want {
+		t.Errorf("caller batch changed to %s, want %s", got, want)
+	}
+}
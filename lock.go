@@ -0,0 +1,151 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const _pgLockNotAvailable = "55P03"
+
+// ErrLockNotAvailable возвращается LockRow и LockRows с LockNoWait, если строка уже заблокирована.
+var ErrLockNotAvailable = errors.New("lock not available")
+
+// LockStrength — сила блокировки строк.
+type LockStrength int
+
+const (
+	// ForUpdate — FOR UPDATE: исключительная блокировка, если строку будут удалять или менять ключ.
+	ForUpdate LockStrength = iota
+	// ForNoKeyUpdate — FOR NO KEY UPDATE: не конфликтует со вставкой ссылающихся строк (FK).
+	ForNoKeyUpdate
+	// ForShare — FOR SHARE.
+	ForShare
+	// ForKeyShare — FOR KEY SHARE.
+	ForKeyShare
+)
+
+func (s LockStrength) String() string {
+	switch s {
+	case ForNoKeyUpdate:
+		return "FOR NO KEY UPDATE"
+	case ForShare:
+		return "FOR SHARE"
+	case ForKeyShare:
+		return "FOR KEY SHARE"
+	default:
+		return "FOR UPDATE"
+	}
+}
+
+// LockWait — поведение при уже заблокированной строке.
+type LockWait int
+
+const (
+	// LockWaitDefault — ждать освобождения блокировки.
+	LockWaitDefault LockWait = iota
+	// LockNoWait — NOWAIT: сразу вернуть ErrLockNotAvailable.
+	LockNoWait
+	// LockSkipLocked — SKIP LOCKED: пропустить заблокированные строки (очереди задач).
+	LockSkipLocked
+)
+
+// LockOptions описывает блокировку, которую LockRow добавляет к запросу.
+type LockOptions struct {
+	Strength LockStrength
+	Wait     LockWait
+	// Of ограничивает блокировку указанными таблицами (FOR UPDATE OF t1, t2) в запросах с JOIN.
+	Of []string
+}
+
+func (o LockOptions) clause() string {
+	var b strings.Builder
+	b.WriteString(o.Strength.String())
+
+	if len(o.Of) > 0 {
+		of := make([]string, len(o.Of))
+		for i, t := range o.Of {
+			of[i] = quoteTable(t)
+		}
+		b.WriteString(" OF ")
+		b.WriteString(strings.Join(of, ", "))
+	}
+
+	switch o.Wait {
+	case LockNoWait:
+		b.WriteString(" NOWAIT")
+	case LockSkipLocked:
+		b.WriteString(" SKIP LOCKED")
+	}
+
+	return b.String()
+}
+
+// LockRow выполняет SELECT с добавленной блокировкой (FOR UPDATE и т.п., см. LockOptions)
+// и сканирует единственную строку в T. Если строк нет (или все пропущены SKIP LOCKED),
+// возвращается pgx.ErrNoRows.
+//
+// Блокировка строк имеет смысл только до конца транзакции, поэтому вне транзакции
+// возвращается ErrNoTransaction. Запрос не должен сам содержать FOR UPDATE.
+//
+// Пример:
+//
+//	err := txManager.ReadCommitted(ctx, func(ctx context.Context) error {
+//	    job, err := pgfx.LockRow[Job](ctx, db, pgfx.LockOptions{Wait: pgfx.LockSkipLocked},
+//	        "SELECT * FROM jobs WHERE status = 'new' ORDER BY id LIMIT 1")
+//	    ...
+//	})
+func LockRow[T any](ctx context.Context, db QueryExecutor, opts LockOptions, sql string, args ...any) (T, error) {
+	var zero T
+
+	rows, err := lockQuery(ctx, db, opts, sql, args...)
+	if err != nil {
+		return zero, err
+	}
+
+	v, err := pgx.CollectOneRow(rows, rowScanFunc[T]())
+	if err != nil {
+		return zero, lockError(err)
+	}
+	return v, nil
+}
+
+// LockRows аналогичен LockRow, но возвращает все строки результата.
+func LockRows[T any](ctx context.Context, db QueryExecutor, opts LockOptions, sql string, args ...any) ([]T, error) {
+	rows, err := lockQuery(ctx, db, opts, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := pgx.CollectRows(rows, rowScanFunc[T]())
+	if err != nil {
+		return nil, lockError(err)
+	}
+	return v, nil
+}
+
+func lockQuery(ctx context.Context, db QueryExecutor, opts LockOptions, sql string, args ...any) (pgx.Rows, error) {
+	if _, ok := txFrom(ctx, executorTxKey(db)); !ok {
+		return nil, ErrNoTransaction
+	}
+
+	sql = strings.TrimRight(strings.TrimSpace(sql), ";") + " " + opts.clause()
+
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, lockError(err)
+	}
+	return rows, nil
+}
+
+func lockError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == _pgLockNotAvailable {
+		return fmt.Errorf("%w: %w", ErrLockNotAvailable, err)
+	}
+	return err
+}
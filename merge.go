@@ -0,0 +1,180 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// _maxQueryParams — предел числа параметров одного запроса в протоколе PostgreSQL.
+const _maxQueryParams = 65535
+
+// columnTypes — кэш типов колонок таблицы репозитория (format_type).
+type columnTypes struct {
+	mu    sync.Mutex
+	types map[string]string
+}
+
+// columnTypes возвращает типы колонок таблицы, загружая их при первом успешном вызове.
+// Они нужны для явных приведений в VALUES, где параметры иначе получают тип text.
+func (r *Repository[T]) columnTypes(ctx context.Context) (map[string]string, error) {
+	r.types.mu.Lock()
+	defer r.types.mu.Unlock()
+
+	if r.types.types != nil {
+		return r.types.types, nil
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT attname, format_type(atttypid, atttypmod)
+		FROM pg_attribute
+		WHERE attrelid = $1::regclass AND attnum > 0 AND NOT attisdropped`, r.table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := make(map[string]string)
+	for rows.Next() {
+		var name, typ string
+		if err := rows.Scan(&name, &typ); err != nil {
+			return nil, err
+		}
+		types[name] = typ
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	r.types.types = types
+	return types, nil
+}
+
+// Merge вставляет или обновляет items (upsert) и возвращает количество затронутых строк.
+//
+// Строки сопоставляются по полям с флагом pgfx:"key", а если таких нет — по первичному ключу.
+// Для совпавших строк обновляются все записываемые колонки (не auto), кроме ключевых
+// и помеченных pgfx:"noupdate" (например, created_by); несовпавшие вставляются.
+//
+// На PostgreSQL 15+ выполняется MERGE, на более старых версиях — INSERT ... ON CONFLICT,
+// для которого по ключевым колонкам нужен уникальный индекс. Большие срезы разбиваются
+// на несколько запросов, чтобы не превысить предел числа параметров; такие запросы выполняются
+// в одной транзакции, поэтому Merge применяется целиком или не применяется вовсе.
+func (r *Repository[T]) Merge(ctx context.Context, items ...T) (int64, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	keys := r.meta.filter(func(f structField) bool { return f.has("key") })
+	if len(keys) == 0 {
+		keys = r.pk
+	}
+	isKey := func(f structField) bool {
+		for _, k := range keys {
+			if k.column == f.column {
+				return true
+			}
+		}
+		return false
+	}
+	insert := r.meta.filter(func(f structField) bool { return isKey(f) || !f.has("auto") })
+	update := r.meta.filter(func(f structField) bool { return !isKey(f) && !f.has("auto") && !f.has("noupdate") })
+
+	types, err := r.columnTypes(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("repository - Merge - column types: %w", err)
+	}
+	for _, f := range insert {
+		if _, ok := types[f.column]; !ok {
+			return 0, fmt.Errorf("repository - Merge: column %s not found in %s", f.column, r.table)
+		}
+	}
+
+	version, err := serverVersionNum(ctx, r.db)
+	if err != nil {
+		return 0, fmt.Errorf("repository - Merge - server version: %w", err)
+	}
+
+	build := r.buildMerge
+	if !FeatureMerge.SupportedBy(version) {
+		build = r.buildUpsert
+	}
+
+	chunks, err := paramChunks(len(items), len(insert), 0)
+	if err != nil {
+		return 0, fmt.Errorf("repository - Merge: %w", err)
+	}
+
+	total, err := execSplit(ctx, r.db, len(chunks), func(ctx context.Context, db execer, part int) (int64, error) {
+		var args []any
+		tuples := make([]string, 0, chunks[part][1]-chunks[part][0])
+		for i := chunks[part][0]; i < chunks[part][1]; i++ {
+			values, err := r.values(reflect.ValueOf(&items[i]).Elem(), insert)
+			if err != nil {
+				return 0, err
+			}
+
+			ph := make([]string, len(insert))
+			for j, f := range insert {
+				args = append(args, values[j])
+				ph[j] = fmt.Sprintf("$%d::%s", len(args), types[f.column])
+			}
+			tuples = append(tuples, "("+strings.Join(ph, ", ")+")")
+		}
+
+		tag, err := db.Exec(ctx, build(keys, insert, update, strings.Join(tuples, ", ")), args...)
+		return tag.RowsAffected(), err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("repository - Merge: %w", err)
+	}
+
+	return total, nil
+}
+
+func (r *Repository[T]) buildMerge(keys, insert, update []structField, values string) string {
+	on := make([]string, len(keys))
+	for i, f := range keys {
+		c := quoteIdent(f.column)
+		on[i] = fmt.Sprintf("t.%s = s.%s", c, c)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `MERGE INTO %s AS t USING (VALUES %s) AS s (%s) ON %s`,
+		r.table, values, columnList(insert), strings.Join(on, " AND "))
+
+	if len(update) > 0 {
+		sets := make([]string, len(update))
+		for i, f := range update {
+			c := quoteIdent(f.column)
+			sets[i] = fmt.Sprintf("%s = s.%s", c, c)
+		}
+		fmt.Fprintf(&b, ` WHEN MATCHED THEN UPDATE SET %s`, strings.Join(sets, ", "))
+	}
+
+	src := make([]string, len(insert))
+	for i, f := range insert {
+		src[i] = "s." + quoteIdent(f.column)
+	}
+	fmt.Fprintf(&b, ` WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)`, columnList(insert), strings.Join(src, ", "))
+
+	return b.String()
+}
+
+func (r *Repository[T]) buildUpsert(keys, insert, update []structField, values string) string {
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES %s ON CONFLICT (%s)`,
+		r.table, columnList(insert), values, columnList(keys))
+
+	if len(update) == 0 {
+		return query + " DO NOTHING"
+	}
+
+	sets := make([]string, len(update))
+	for i, f := range update {
+		c := quoteIdent(f.column)
+		sets[i] = fmt.Sprintf("%s = EXCLUDED.%s", c, c)
+	}
+	return query + " DO UPDATE SET " + strings.Join(sets, ", ")
+}
@@ -0,0 +1,76 @@
+package pgfx
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// NestingStats — статистика вложенности транзакций, запущенных через TxManager.
+//
+// Большое значение Joined относительно Started или неожиданно глубокая MaxDepth
+// обычно означают, что транзакция одного слоя непреднамеренно "протекает"
+// в вызовы другого (например, сервис вызывает сервис, и оба открывают транзакции).
+type NestingStats struct {
+	// Started — сколько раз обработчик запускал новую транзакцию.
+	Started int64
+	// Joined — сколько раз обработчик присоединялся к уже активной транзакции из контекста
+	// (в том числе на savepoint, см. NestSavepoint).
+	Joined int64
+	// MaxDepth — максимальная наблюдавшаяся глубина вложенности (1 — без вложенности).
+	MaxDepth int64
+}
+
+type nestingStats struct {
+	started  atomic.Int64
+	joined   atomic.Int64
+	maxDepth atomic.Int64
+}
+
+func (s *nestingStats) recordStart() {
+	s.started.Add(1)
+	s.observeDepth(1)
+}
+
+func (s *nestingStats) recordJoin(depth int64) {
+	s.joined.Add(1)
+	s.observeDepth(depth)
+}
+
+func (s *nestingStats) observeDepth(depth int64) {
+	for {
+		cur := s.maxDepth.Load()
+		if depth <= cur || s.maxDepth.CompareAndSwap(cur, depth) {
+			return
+		}
+	}
+}
+
+func (s *nestingStats) snapshot() NestingStats {
+	return NestingStats{
+		Started:  s.started.Load(),
+		Joined:   s.joined.Load(),
+		MaxDepth: s.maxDepth.Load(),
+	}
+}
+
+type txDepthKey struct{}
+
+func txDepth(ctx context.Context) int64 {
+	depth, _ := ctx.Value(txDepthKey{}).(int64)
+	return depth
+}
+
+func withTxDepth(ctx context.Context, depth int64) context.Context {
+	return context.WithValue(ctx, txDepthKey{}, depth)
+}
+
+// NestingStats возвращает статистику вложенности транзакций всех менеджеров,
+// созданных через NewTransactionManager этого экземпляра.
+func (p *Postgres) NestingStats() NestingStats {
+	return p.txStats.snapshot()
+}
+
+// NestingStats возвращает статистику вложенности транзакций, запущенных через этот менеджер.
+func (m *Manager) NestingStats() NestingStats {
+	return m.stats.snapshot()
+}
@@ -0,0 +1,114 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// _defaultNPlusOneThreshold — сколько запросов с разными значениями единственного
+// параметра считается признаком N+1 по умолчанию.
+const _defaultNPlusOneThreshold = 5
+
+// NPlusOneWarning описывает подозрение на N+1: один и тот же запрос с одним параметром
+// выполнен Count раз с разными значениями в рамках одного запроса приложения или транзакции.
+type NPlusOneWarning struct {
+	SQL   string
+	Count int
+	// Stacks — стеки вызовов первого выполнения и выполнения, на котором сработал порог.
+	Stacks []string
+}
+
+// NPlusOneHandler получает предупреждения детектора N+1 (см. DetectNPlusOne).
+type NPlusOneHandler func(ctx context.Context, w NPlusOneWarning)
+
+func logNPlusOne(_ context.Context, w NPlusOneWarning) {
+	log.Printf("pgfx: possible N+1: query executed %d times with different args: %s\n%s",
+		w.Count, w.SQL, strings.Join(w.Stacks, "\n---\n"))
+}
+
+// nPlusOneState хранится в RequestStats и отслеживает запросы с одним параметром.
+type nPlusOneState struct {
+	values map[string]struct{}
+	first  []uintptr
+	warned bool
+}
+
+// observeSingleArg учитывает выполнение sql со значением параметра value и возвращает
+// предупреждение, когда число разных значений впервые достигло threshold.
+func (s *RequestStats) observeSingleArg(sql, value string, threshold int) (NPlusOneWarning, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.nplus1 == nil {
+		s.nplus1 = make(map[string]*nPlusOneState)
+	}
+	st, ok := s.nplus1[sql]
+	if !ok {
+		st = &nPlusOneState{values: make(map[string]struct{}), first: callers()}
+		s.nplus1[sql] = st
+	}
+	if st.warned {
+		return NPlusOneWarning{}, false
+	}
+
+	st.values[value] = struct{}{}
+	if len(st.values) < threshold {
+		return NPlusOneWarning{}, false
+	}
+
+	st.warned = true
+	return NPlusOneWarning{
+		SQL:    sql,
+		Count:  len(st.values),
+		Stacks: []string{formatCallers(st.first), formatCallers(callers())},
+	}, true
+}
+
+func callers() []uintptr {
+	pcs := make([]uintptr, 32)
+	return pcs[:runtime.Callers(3, pcs)]
+}
+
+// formatCallers форматирует стек, пропуская кадры pgx и pgfx.
+func formatCallers(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		f, more := frames.Next()
+		if !strings.Contains(f.Function, "github.com/jackc/pgx") && !strings.HasPrefix(f.Function, "github.com/fr11nik/pgfx.") {
+			fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// nPlusOneTracer ищет N+1 в запросах с RequestStats в контексте.
+type nPlusOneTracer struct {
+	threshold int
+	handler   NPlusOneHandler
+}
+
+func (t nPlusOneTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if len(data.Args) != 1 {
+		return ctx
+	}
+	stats := RequestStatsFromContext(ctx)
+	if stats == nil {
+		return ctx
+	}
+
+	if w, ok := stats.observeSingleArg(data.SQL, fmt.Sprint(data.Args[0]), t.threshold); ok {
+		t.handler(ctx, w)
+	}
+	return ctx
+}
+
+func (nPlusOneTracer) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
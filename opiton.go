@@ -1,9 +1,8 @@
 package pgfx
 
 import (
+	"context"
 	"time"
-
-	"github.com/exaring/otelpgx"
 )
 
 // Option -.
@@ -30,8 +29,377 @@ func ConnTimeout(timeout time.Duration) Option {
 	}
 }
 
+// MaxQueryRows ограничивает количество строк, которое может вернуть один Query
+// через TransactionalPool. При превышении чтение прекращается, а rows.Err()
+// возвращает *RowLimitError. 0 — без ограничения.
+// Лимит можно переопределить для отдельного вызова через WithMaxRows.
+func MaxQueryRows(limit int) Option {
+	return func(p *Postgres) {
+		p.maxRows = limit
+	}
+}
+
+// WithTracer включает трейсинг запросов через OpenTelemetry (otelpgx).
+// Трейсинг можно включать и выключать на лету через ApplyConfig.
 func WithTracer() Option {
 	return func(p *Postgres) {
-		p.qt = otelpgx.NewTracer()
+		p.qt.SetEnabled(true)
+	}
+}
+
+// DetectTxBypass включает отладочный режим, в котором запросы, выполненные с контекстом
+// активной транзакции pgfx, но не через неё (классическая ошибка: pg.Pool вместо
+// TransactionalPool), передаются в handler. Если handler == nil, такие запросы логируются.
+//
+// Проверка стоит одного сравнения на запрос, но предназначена прежде всего для
+// разработки и тестов.
+func DetectTxBypass(handler TxBypassHandler) Option {
+	return func(p *Postgres) {
+		if handler == nil {
+			handler = logTxBypass
+		}
+		p.tracers = append(p.tracers, bypassTracer{handler: handler, key: p.txKey})
+	}
+}
+
+// VerifyQueriesOnStart заставляет New проверить все запросы, зарегистрированные через
+// RegisterQuery, против живой схемы (см. VerifyQueries) и вернуть ошибку, если хотя бы
+// один из них не подготавливается. Так опечатки и несуществующие колонки обнаруживаются
+// при старте, а не на первом запросе пользователя.
+func VerifyQueriesOnStart() Option {
+	return func(p *Postgres) {
+		p.verifyQueries = true
+	}
+}
+
+// WithRedaction задаёт правила маскирования параметров запросов в логах (WithQueryLog)
+// и span'ах (WithTracerQueryArgs). Без этой опции маскируются параметры, связанные
+// с колонками из DefaultSensitiveColumns.
+func WithRedaction(r *Redactor) Option {
+	return func(p *Postgres) {
+		p.redactor = r
+	}
+}
+
+// WithQueryLog включает логирование запросов, выполняемых через пул, вместе с (замаскированными)
+// параметрами. При threshold > 0 логируются только запросы дольше threshold.
+func WithQueryLog(threshold time.Duration) Option {
+	return func(p *Postgres) {
+		p.tracers = append(p.tracers, queryLogTracer{p: p, threshold: threshold})
+	}
+}
+
+// DevQueryLog включает журнал запросов для локальной разработки, если задана переменная окружения
+// PGFX_DEV_QUERIES=1 (см. DevQueryLogEnv): каждый запрос печатается с подставленными параметрами
+// (замаскированными по правилам WithRedaction) и оценкой стоимости из EXPLAIN. Оценка стоит
+// дополнительного round-trip на каждый запрос, поэтому опция не для продакшена; без переменной
+// окружения она ничего не делает, и её можно оставлять в коде.
+func DevQueryLog() Option {
+	return func(p *Postgres) {
+		if devQueryLogEnabled() {
+			p.tracers = append(p.tracers, devQueryLogTracer{p: p})
+		}
+	}
+}
+
+// WithTracerQueryArgs добавляет к span'ам запросов (см. WithTracer) атрибут
+// db.query.parameters с параметрами, замаскированными по правилам WithRedaction.
+func WithTracerQueryArgs() Option {
+	return func(p *Postgres) {
+		p.qt.redact = func(ctx context.Context, sql string, args []any) []any {
+			return p.redactor.Redact(ctx, sql, args)
+		}
+	}
+}
+
+// WithBackpressure включает сигнал перегрузки пула: когда сглаженное время ожидания
+// соединения превышает threshold, Overloaded начинает возвращать true и вызывается
+// onChange(true, wait); когда ожидание падает ниже threshold/2 — onChange(false, wait).
+// onChange может быть nil; он вызывается синхронно в горутине, выполнявшей acquire.
+func WithBackpressure(threshold time.Duration, onChange BackpressureFunc) Option {
+	return func(p *Postgres) {
+		p.acquire.threshold = threshold
+		p.acquire.onChange = onChange
+	}
+}
+
+// AcquireTimeout ограничивает ожидание свободного соединения пула: если за timeout соединение
+// не освободилось (и не было создано новое), запрос через TransactionalPool завершается
+// ошибкой ErrPoolTimeout, а не ждёт до отмены контекста запроса. В отличие от ConnTimeout,
+// который ограничивает установку одного соединения, предел действует на всё ожидание.
+// Запросы напрямую через Pool тоже ограничены, но получают context.DeadlineExceeded.
+func AcquireTimeout(timeout time.Duration) Option {
+	return func(p *Postgres) {
+		p.acquire.timeout = timeout
+	}
+}
+
+// DisableNestedBegin запрещает TransactionalPool.BeginTx внутри активной транзакции:
+// вместо savepoint возвращается ErrNestedTransaction.
+func DisableNestedBegin() Option {
+	return func(p *Postgres) {
+		p.noNestedBegin = true
+	}
+}
+
+// WarnSlowTransactions логирует транзакции менеджеров из NewTransactionManager,
+// длившиеся дольше threshold, вместе с их именем (см. WithTxName).
+func WarnSlowTransactions(threshold time.Duration) Option {
+	return func(p *Postgres) {
+		p.slowTx = threshold
+	}
+}
+
+// WatchIdleTransactions включает сторожа транзакций менеджеров из NewTransactionManager:
+// транзакция, которая дольше threshold не выполняет ни одного запроса (idle in transaction —
+// обработчик ждёт внешний сервис, забыл вернуть управление и т.п.), держит соединение и блокировки.
+// Без rollback такая транзакция только логируется. С rollback она откатывается, контекст
+// обработчика отменяется с причиной *IdleTransactionError (context.Cause), а её последующие
+// запросы и коммит возвращают эту ошибку.
+func WatchIdleTransactions(threshold time.Duration, rollback bool) Option {
+	return func(p *Postgres) {
+		p.idleTx = threshold
+		p.idleRollback = rollback
+	}
+}
+
+// RecordTransactionReplay включает запись запросов транзакций менеджеров из NewTransactionManager:
+// если транзакция завершается ошибкой, ошибка оборачивается в *TxReplayError с упорядоченным
+// списком выполненных запросов (тег, ответ сервера, длительность), чтобы причину отката можно
+// было восстановить по одному сообщению в логе. С withSQL в список попадает и текст запросов
+// (без параметров).
+func RecordTransactionReplay(withSQL bool) Option {
+	return func(p *Postgres) {
+		p.replay = &withSQL
+		p.tracers = append(p.tracers, replayTracer{})
+	}
+}
+
+// WithReplica задаёт строку подключения к реплике, на которой Snapshot выполняет отчёты.
+// Пул реплики использует те же настройки размера, таймаутов и трейсинга, что и основной.
+func WithReplica(connStr string) Option {
+	return func(p *Postgres) {
+		p.replicaConnStr = connStr
+	}
+}
+
+// NestedTransactions задаёт поведение по умолчанию вызовов TxManager внутри уже активной
+// транзакции: NestJoin (по умолчанию) или NestSavepoint. Отдельный вызов может выбрать
+// другое поведение через WithNesting.
+func NestedTransactions(mode NestingMode) Option {
+	return func(p *Postgres) {
+		p.nesting = mode
+	}
+}
+
+// RetryInvalidatedStatements скрывает от приложения ошибки устаревших подготовленных операторов,
+// которые pgx кэширует на соединениях, во время выкатки миграций: "cached plan must not change
+// result type" после изменения колонок и "prepared statement ... does not exist".
+// Кэш операторов соединения, на котором случилась ошибка, сбрасывается, а запрос через
+// TransactionalPool вне транзакции повторяется один раз. Внутри транзакции такая ошибка
+// прерывает её, поэтому повторить можно только транзакцию целиком.
+func RetryInvalidatedStatements() Option {
+	return func(p *Postgres) {
+		p.retryInvalidated = true
+		p.tracers = append(p.tracers, stmtInvalidationTracer{})
+	}
+}
+
+// HealthCheck включает фоновую проверку базы раз в interval (Ping с таймаутом interval).
+// Ответ дольше slow (0 — не учитывать) или перегрузка пула (см. Overloaded) считаются
+// состоянием HealthDegraded. Текущее состояние возвращает Health, о смене состояния
+// сообщает OnHealthChange.
+func HealthCheck(interval, slow time.Duration) Option {
+	return func(p *Postgres) {
+		p.health = &healthChecker{
+			interval: interval,
+			slow:     slow,
+			stop:     make(chan struct{}),
+			done:     make(chan struct{}),
+		}
+	}
+}
+
+// PgBouncerCompatible включает режим совместимости с пулерами в режиме transaction pooling
+// (PgBouncer, odyssey), где соседние транзакции клиента попадают на разные серверные соединения:
+//
+//   - запросы выполняются без именованных подготовленных операторов сессии
+//     (QueryExecModeExec вместо кэша операторов pgx);
+//   - соединение, на котором выполнялся запрос с состоянием сессии (SET без LOCAL, PREPARE,
+//     LISTEN, временные таблицы, курсоры WITH HOLD), сбрасывается через DISCARD ALL
+//     при возврате в пул, чтобы состояние не досталось чужим транзакциям;
+//   - PrewarmQueries запрещён (New вернёт ошибку).
+//
+// Подписка InvalidationBus.Subscribe требует сессии (LISTEN) и через такой пулер не работает:
+// для неё нужен отдельный Postgres с прямым подключением к серверу.
+// Наличие пулера New определяет при старте (см. BehindPooler) и предупреждает в лог,
+// если пулер найден, а режим не включён; CancelAll за пулером возвращает ErrBehindPooler.
+func PgBouncerCompatible() Option {
+	return func(p *Postgres) {
+		p.sessions = &sessionTracer{}
+		p.tracers = append(p.tracers, p.sessions)
+	}
+}
+
+// Keepalive включает фоновую проверку простаивающих соединений пула: раз в interval одно
+// случайное из них проверяется Ping, а если оно не отвечает — соединения пула пересоздаются
+// (как в Reset), и пул восстанавливает их до MinConns. Так первый запрос после затишья
+// не обнаруживает мёртвое соединение сам. Проверка останавливается в Close.
+func Keepalive(interval time.Duration) Option {
+	return func(p *Postgres) {
+		p.keepalive = &keepalive{
+			interval: interval,
+			stop:     make(chan struct{}),
+			done:     make(chan struct{}),
+		}
+	}
+}
+
+// TrackConnectionHolders запоминает для каждого взятого из пула соединения, какой код
+// его взял, когда и под транзакцию ли. Close логирует соединения, не возвращённые к закрытию,
+// а Shutdown возвращает их в CloseReport — это помогает находить утечки соединений.
+// Каждое получение соединения при этом разбирает стек вызова.
+func TrackConnectionHolders() Option {
+	return func(p *Postgres) {
+		p.holders = newHolderTracer()
+		p.tracers = append(p.tracers, p.holders)
+	}
+}
+
+// WithTxMetrics передаёт события транзакций менеджеров этого Postgres (начало, фиксация,
+// откат, повтор и длительность) в metrics, например в счётчики Prometheus или OTEL.
+func WithTxMetrics(metrics TxMetrics) Option {
+	return func(p *Postgres) {
+		p.txMetrics = metrics
+	}
+}
+
+// DetectTransactionLeaks логирует транзакции TxManager, которые не завершились за after,
+// вместе со стеком вызова, открывшего транзакцию. Помогает найти обработчики, зависшие
+// внутри транзакции, и транзакции Begin, для которых забыли вызвать Commit или Rollback.
+// Стек запоминается при каждом BEGIN, поэтому опция рассчитана на отладку и стейджинг.
+func DetectTransactionLeaks(after time.Duration) Option {
+	return func(p *Postgres) {
+		p.txLeakAfter = after
+	}
+}
+
+// GuardConcurrentTxUse включает проверку, что транзакцию TxManager не используют из нескольких
+// горутин одновременно (например, errgroup внутри обработчика с контекстом транзакции):
+// запрос, начатый, пока на соединении транзакции выполняется другой или не закрыты Rows,
+// завершается ошибкой ErrConcurrentTxUse вместо порчи состояния соединения.
+// Проверка ловит только пересекающиеся по времени запросы.
+func GuardConcurrentTxUse() Option {
+	return func(p *Postgres) {
+		p.guardTx = true
+	}
+}
+
+// PrewarmQueries объявляет горячие запросы, которые готовятся (PREPARE) на каждом новом
+// соединении пула, чтобы после пересоздания соединений первые запросы не платили за разбор.
+// Текст должен совпадать с тем, что передаётся в Query/Exec.
+func PrewarmQueries(queries ...string) Option {
+	return func(p *Postgres) {
+		p.prewarmQueries = append(p.prewarmQueries, queries...)
+	}
+}
+
+// PrewarmExplain дополнительно строит для каждого запроса из PrewarmQueries общий план
+// (EXPLAIN (GENERIC_PLAN)) и логирует запросы, для которых это не удалось.
+// На серверах старше PostgreSQL 16 проверка пропускается.
+func PrewarmExplain() Option {
+	return func(p *Postgres) {
+		p.prewarmExplain = true
+	}
+}
+
+// VerifyEnumsOnStart заставляет New сверить перечисления, зарегистрированные через RegisterEnum,
+// с pg_enum (см. VerifyEnums) и вернуть ошибку при расхождении наборов значений.
+func VerifyEnumsOnStart() Option {
+	return func(p *Postgres) {
+		p.verifyEnums = true
+	}
+}
+
+// WithDecimal регистрирует на каждом соединении кодек numeric для github.com/shopspring/decimal:
+// decimal.Decimal и decimal.NullDecimal сканируются из numeric и передаются в запросы без потери
+// точности и без собственных адаптеров pgtype.
+func WithDecimal() Option {
+	return func(p *Postgres) {
+		p.decimal = true
+	}
+}
+
+// WithUUID регистрирует на каждом соединении кодек uuid для github.com/google/uuid:
+// uuid.UUID и uuid.NullUUID передаются и сканируются в бинарном формате, включая массивы uuid[],
+// а rows.Values() возвращает uuid.UUID вместо [16]byte.
+func WithUUID() Option {
+	return func(p *Postgres) {
+		p.uuid = true
+	}
+}
+
+// VerifyTablesOnStart заставляет New сверить структуры, зарегистрированные через RegisterTable,
+// с колонками таблиц (см. VerifyTables). В режиме TableDriftFail расхождения приводят к ошибке New,
+// в режиме TableDriftLog — только логируются.
+func VerifyTablesOnStart(mode TableDriftMode) Option {
+	return func(p *Postgres) {
+		p.verifyTables = &mode
+	}
+}
+
+// DetectNPlusOne включает детектор N+1: если в рамках одного запроса приложения (WithRequestStats,
+// RequestStatsMiddleware) или, при его отсутствии, одной транзакции TxManager один и тот же запрос
+// с единственным параметром выполняется с threshold разными значениями, handler получает
+// предупреждение со стеками вызовов. threshold <= 0 означает 5, handler == nil — логирование.
+func DetectNPlusOne(threshold int, handler NPlusOneHandler) Option {
+	return func(p *Postgres) {
+		if threshold <= 0 {
+			threshold = _defaultNPlusOneThreshold
+		}
+		if handler == nil {
+			handler = logNPlusOne
+		}
+		p.tracers = append(p.tracers, nPlusOneTracer{threshold: threshold, handler: handler})
+		p.txRequestStats = true
+	}
+}
+
+// QueryTimeouts задаёт таймауты запросов по тегу (см. WithQueryTag), чтобы политика таймаутов
+// жила в конфигурации, а не в каждом месте вызова:
+//
+//	pgfx.QueryTimeouts(map[string]time.Duration{
+//	    "search.*": 2 * time.Second,
+//	    "report.*": time.Minute,
+//	})
+//
+// Шаблоны сопоставляются через path.Match; при нескольких совпадениях побеждает самый длинный.
+// Таймаут применяется к Exec, Query и QueryRow в TransactionalPool и не продлевает более ранний
+// дедлайн контекста. Запрос, прерванный в транзакции, переводит её в состояние ошибки.
+func QueryTimeouts(rules map[string]time.Duration) Option {
+	return func(p *Postgres) {
+		p.timeouts = newTimeoutPolicy(rules)
+	}
+}
+
+// PriorityAcquire включает очередь получения соединений по приоритету: TransactionalPool
+// пропускает к пулу не больше MaxPoolSize операций одновременно, а при нехватке соединений
+// выдаёт их по приоритету из контекста (WithPriority), а не в порядке очереди, так что
+// health check'и и интерактивные запросы обгоняют пакетные задачи.
+//
+// Операция занимает слот до конца: запрос — до закрытия rows, транзакция — до Commit/Rollback.
+// Запросы через pg.Pool напрямую очередь не учитывает.
+func PriorityAcquire() Option {
+	return func(p *Postgres) {
+		p.priorityAcquire = true
+	}
+}
+
+// TrackActiveQueries включает реестр выполняющихся запросов (см. ActiveQueries).
+// Стоит одной блокировки мьютекса на начало и конец каждого запроса.
+func TrackActiveQueries() Option {
+	return func(p *Postgres) {
+		p.active = newActiveQueries()
+		p.tracers = append(p.tracers, p.active)
 	}
 }
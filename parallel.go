@@ -0,0 +1,141 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// _defaultParallelism — сколько запросов Parallel выполняет одновременно по умолчанию.
+const _defaultParallelism = 4
+
+// ParallelQuery — один независимый читающий запрос для Parallel.
+type ParallelQuery struct {
+	SQL  string
+	Args []any
+	// Scan читает результат запроса; rows закрываются после возврата.
+	Scan func(rows pgx.Rows) error
+}
+
+// QueryInto — запрос для Parallel, строки которого собираются в *dst через rowTo:
+//
+//	pgfx.QueryInto(&orders, pgx.RowToStructByName[Order], `SELECT * FROM orders WHERE user_id = $1`, id)
+func QueryInto[T any](dst *[]T, rowTo pgx.RowToFunc[T], sql string, args ...any) ParallelQuery {
+	return ParallelQuery{SQL: sql, Args: args, Scan: func(rows pgx.Rows) error {
+		out, err := pgx.CollectRows(rows, rowTo)
+		if err != nil {
+			return err
+		}
+		*dst = out
+		return nil
+	}}
+}
+
+// QueryRowInto — запрос для Parallel, возвращающий одну строку, которая сканируется в dst.
+func QueryRowInto(sql string, args []any, dst ...any) ParallelQuery {
+	return ParallelQuery{SQL: sql, Args: args, Scan: func(rows pgx.Rows) error {
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return pgx.ErrNoRows
+		}
+		if err := rows.Scan(dst...); err != nil {
+			return err
+		}
+		rows.Close()
+		return rows.Err()
+	}}
+}
+
+type parallelismKey struct{}
+
+// WithParallelism ограничивает число запросов, которые Parallel выполняет одновременно
+// (по умолчанию 4), — то есть число соединений пула, которые он занимает.
+func WithParallelism(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, parallelismKey{}, n)
+}
+
+// Parallel выполняет независимые читающие запросы одновременно на разных соединениях пула,
+// например для страницы-дашборда:
+//
+//	var (
+//	    orders []Order
+//	    total  int
+//	)
+//	err := pgfx.Parallel(ctx, pg.TransactionalPool,
+//	    pgfx.QueryInto(&orders, pgx.RowToStructByName[Order], `SELECT * FROM orders ORDER BY id DESC LIMIT 10`),
+//	    pgfx.QueryRowInto(`SELECT count(*) FROM orders`, nil, &total),
+//	)
+//
+// Результаты попадают в назначения своих запросов, поэтому порядок соответствует порядку queries.
+// Первая ошибка отменяет остальные запросы и возвращается с номером запроса.
+//
+// Запросы не видят друг друга и не образуют общего снимка данных. Внутри транзакции db
+// все запросы выполняются в ней по очереди: одно соединение нельзя использовать параллельно.
+func Parallel(ctx context.Context, db QueryExecutor, queries ...ParallelQuery) error {
+	limit, _ := ctx.Value(parallelismKey{}).(int)
+	if limit <= 0 {
+		limit = _defaultParallelism
+	}
+	if _, ok := txFrom(ctx, executorTxKey(db)); ok {
+		limit = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+		sem   = make(chan struct{}, limit)
+	)
+	for i, q := range queries {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := runParallelQuery(ctx, db, q); err != nil {
+				once.Do(func() {
+					first = fmt.Errorf("postgres - Parallel - query %d: %w", i, err)
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if first != nil {
+		return first
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("postgres - Parallel: %w", err)
+	}
+	return nil
+}
+
+func runParallelQuery(ctx context.Context, db QueryExecutor, q ParallelQuery) error {
+	rows, err := db.Query(ctx, q.SQL, q.Args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if err := q.Scan(rows); err != nil {
+		return err
+	}
+	rows.Close()
+	return rows.Err()
+}
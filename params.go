@@ -0,0 +1,154 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// execer — то, на чём выполняются части разбитого запроса: QueryExecutor или транзакция.
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// paramChunks делит n элементов по perItem параметров (плюс fixed общих параметров на запрос)
+// на диапазоны [start, end), каждый из которых укладывается в предел параметров одного запроса.
+func paramChunks(n, perItem, fixed int) ([][2]int, error) {
+	if perItem <= 0 {
+		return nil, errors.New("no parameters per item")
+	}
+	size := (_maxQueryParams - fixed) / perItem
+	if size <= 0 {
+		return nil, fmt.Errorf("%d parameters per item exceed the limit of %d", perItem+fixed, _maxQueryParams)
+	}
+
+	var out [][2]int
+	for start := 0; start < n; start += size {
+		out = append(out, [2]int{start, min(start+size, n)})
+	}
+	return out, nil
+}
+
+// execSplit выполняет parts частей запроса через run. Если частей больше одной, они выполняются
+// в одной транзакции (или на savepoint транзакции из контекста), чтобы разбиение было незаметно:
+// либо применяются все части, либо ни одна. Для TransactionalPool части по-прежнему идут через
+// db, с транзакцией в контексте, поэтому QueryRewriter, таймауты и режим планирования действуют
+// так же, как для запроса из одной части.
+func execSplit(ctx context.Context, db QueryExecutor, parts int, run func(ctx context.Context, db execer, part int) (int64, error)) (int64, error) {
+	if parts == 1 {
+		return run(ctx, db, 0)
+	}
+
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var exec execer = tx
+	if t, ok := db.(pgTransactor); ok {
+		ctx, exec = withTx(ctx, t.key, tx), db
+	}
+
+	var total int64
+	for part := 0; part < parts; part++ {
+		n, err := run(ctx, exec, part)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	return total, nil
+}
+
+// ExecIn выполняет query, в котором %s заменяется списком параметров для values, и возвращает
+// общее число затронутых строк:
+//
+//	n, err := pgfx.ExecIn(ctx, pg.TransactionalPool, `UPDATE jobs SET state = $1 WHERE id IN (%s)`, ids, "done")
+//
+// args — общие параметры $1..$k, значения values нумеруются после них. Если параметров больше,
+// чем PostgreSQL принимает в одном запросе (65535), запрос выполняется несколькими частями
+// в одной транзакции. Для запросов с одним массивом удобнее = ANY($1), которому предел не грозит.
+func ExecIn[V any](ctx context.Context, db QueryExecutor, query string, values []V, args ...any) (int64, error) {
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	chunks, err := paramChunks(len(values), 1, len(args))
+	if err != nil {
+		return 0, fmt.Errorf("exec in: %w", err)
+	}
+
+	n, err := execSplit(ctx, db, len(chunks), func(ctx context.Context, db execer, part int) (int64, error) {
+		c := chunks[part]
+		all := append(make([]any, 0, len(args)+c[1]-c[0]), args...)
+		ph := make([]string, 0, c[1]-c[0])
+		for _, v := range values[c[0]:c[1]] {
+			all = append(all, v)
+			ph = append(ph, "$"+strconv.Itoa(len(all)))
+		}
+
+		tag, err := db.Exec(ctx, fmt.Sprintf(query, strings.Join(ph, ", ")), all...)
+		return tag.RowsAffected(), err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("exec in: %w", err)
+	}
+	return n, nil
+}
+
+// ExecValues выполняет query, в котором %s заменяется списком кортежей VALUES для rows, и возвращает
+// общее число затронутых строк:
+//
+//	n, err := pgfx.ExecValues(ctx, pg.TransactionalPool,
+//	    `INSERT INTO tags (name, color) VALUES %s ON CONFLICT DO NOTHING`, rows)
+//
+// Все строки должны содержать одинаковое число значений. Если параметров больше, чем PostgreSQL
+// принимает в одном запросе (65535), запрос выполняется несколькими частями в одной транзакции.
+func ExecValues(ctx context.Context, db QueryExecutor, query string, rows [][]any) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	width := len(rows[0])
+	for i, row := range rows {
+		if len(row) != width {
+			return 0, fmt.Errorf("exec values: row %d has %d values, want %d", i, len(row), width)
+		}
+	}
+
+	chunks, err := paramChunks(len(rows), width, 0)
+	if err != nil {
+		return 0, fmt.Errorf("exec values: %w", err)
+	}
+
+	n, err := execSplit(ctx, db, len(chunks), func(ctx context.Context, db execer, part int) (int64, error) {
+		c := chunks[part]
+		args := make([]any, 0, (c[1]-c[0])*width)
+		tuples := make([]string, 0, c[1]-c[0])
+		for _, row := range rows[c[0]:c[1]] {
+			ph := make([]string, width)
+			for j, v := range row {
+				args = append(args, v)
+				ph[j] = "$" + strconv.Itoa(len(args))
+			}
+			tuples = append(tuples, "("+strings.Join(ph, ", ")+")")
+		}
+
+		tag, err := db.Exec(ctx, fmt.Sprintf(query, strings.Join(tuples, ", ")), args...)
+		return tag.RowsAffected(), err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("exec values: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,72 @@
+package pgfx
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestParamChunks(t *testing.T) {
+	got, err := paramChunks(70000, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][2]int{{0, 65533}, {65533, 70000}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("paramChunks(70000, 1, 2) = %v, want %v", got, want)
+	}
+
+	got, err = paramChunks(10, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][2]int{{0, 10}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("paramChunks(10, 3, 0) = %v, want %v", got, want)
+	}
+
+	if _, err := paramChunks(1, _maxQueryParams+1, 0); err == nil {
+		t.Error("paramChunks with too wide items must fail")
+	}
+}
+
+// execRecorderTx записывает запросы; Begin возвращает её же, как savepoint.
+type execRecorderTx struct {
+	pgx.Tx
+	sql []string
+}
+
+func (t *execRecorderTx) Begin(context.Context) (pgx.Tx, error) { return t, nil }
+
+func (t *execRecorderTx) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	t.sql = append(t.sql, sql)
+	return pgconn.NewCommandTag("UPDATE 1"), nil
+}
+
+func (t *execRecorderTx) Commit(context.Context) error   { return nil }
+func (t *execRecorderTx) Rollback(context.Context) error { return nil }
+
+func TestExecInSplitSoftDelete(t *testing.T) {
+	sd := &softDelete{column: quoteIdent("deleted_at"), tables: map[string]struct{}{"users": {}}}
+	key := &instanceTxKey{}
+	db := pgTransactor{rewriters: []QueryRewriter{sd.rewrite}, key: key}
+
+	tx := &execRecorderTx{}
+	ctx := withTx(context.Background(), key, tx)
+
+	ids := make([]int, 70000)
+	if _, err := ExecIn(ctx, db, `DELETE FROM users WHERE id IN (%s)`, ids); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tx.sql) != 2 {
+		t.Fatalf("got %d statements, want 2", len(tx.sql))
+	}
+	for i, sql := range tx.sql {
+		if !strings.HasPrefix(sql, "UPDATE users SET") {
+			t.Errorf("part %d is not soft-deleted: %.60s", i, sql)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const _discardTimeout = 5 * time.Second
+
+// ErrBehindPooler возвращается операциями, которым нужны настоящие PID серверных процессов
+// (CancelAll), если соединения идут через пулер: PgBouncer подменяет ключи отмены своими.
+var ErrBehindPooler = errors.New("operation is not supported behind a connection pooler")
+
+// sessionTracer отмечает соединения, запросы которых оставили состояние сессии
+// (SET, PREPARE, LISTEN, временные таблицы, курсоры WITH HOLD), чтобы сбросить его
+// через DISCARD ALL при возврате соединения в пул (см. PgBouncerCompatible).
+type sessionTracer struct {
+	dirty sync.Map // *pgx.Conn → struct{}
+}
+
+func (t *sessionTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if leavesSessionState(data.SQL) {
+		t.dirty.Store(conn, struct{}{})
+	}
+	return ctx
+}
+
+func (t *sessionTracer) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
+
+// afterRelease сбрасывает состояние сессии отмеченного соединения; если это не удалось,
+// соединение закрывается, а не возвращается в пул.
+func (t *sessionTracer) afterRelease(conn *pgx.Conn) bool {
+	if _, ok := t.dirty.LoadAndDelete(conn); !ok {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), _discardTimeout)
+	defer cancel()
+
+	_, err := conn.Exec(ctx, "DISCARD ALL")
+	return err == nil
+}
+
+// leavesSessionState сообщает, меняет ли sql состояние сессии, которое переживает транзакцию.
+func leavesSessionState(sql string) bool {
+	top := topLevel(scanSQL(sql))
+
+	start := true
+	for i, t := range top {
+		if t.kind == tokPunct && t.text == ";" {
+			start = true
+			continue
+		}
+		if !start {
+			continue
+		}
+		start = false
+
+		rest := top[i+1:]
+		next := func(keywords ...string) bool { return len(rest) > 0 && rest[0].isAny(keywords...) }
+		switch {
+		case t.is("SET"):
+			// SET LOCAL и SET TRANSACTION действуют до конца транзакции.
+			if !next("LOCAL", "TRANSACTION") {
+				return true
+			}
+		case t.is("PREPARE"):
+			if !next("TRANSACTION") {
+				return true
+			}
+		case t.isAny("LISTEN", "LOAD"):
+			return true
+		case t.is("CREATE"):
+			for _, w := range rest[:min(len(rest), 2)] {
+				if w.isAny("TEMP", "TEMPORARY") {
+					return true
+				}
+			}
+		case t.is("DECLARE"):
+			for j := 1; j < len(rest) && !(rest[j].kind == tokPunct && rest[j].text == ";"); j++ {
+				if rest[j].is("HOLD") && rest[j-1].is("WITH") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// detectPooler проверяет, идут ли соединения через пулер: PgBouncer и odyssey сообщают клиенту
+// собственный PID в ключе отмены, и он не совпадает с pg_backend_pid() серверного процесса.
+func detectPooler(ctx context.Context, pool *pgxpool.Pool) (bool, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	var pid uint32
+	if err := conn.QueryRow(ctx, `SELECT pg_backend_pid()`).Scan(&pid); err != nil {
+		return false, err
+	}
+	return pid != conn.Conn().PgConn().PID(), nil
+}
+
+// checkPooler определяет пулер при старте и предупреждает, если он найден,
+// а режим совместимости не включён.
+func (p *Postgres) checkPooler(ctx context.Context) {
+	pooled, err := detectPooler(ctx, p.Pool)
+	if err != nil {
+		log.Printf("pgfx: can't detect connection pooler: %v", err)
+		return
+	}
+	p.behindPooler.Store(pooled)
+	if pooled && p.sessions == nil {
+		log.Printf("pgfx: connections go through a pooler (PgBouncer/odyssey); enable PgBouncerCompatible to avoid session state")
+	}
+}
+
+// BehindPooler сообщает, обнаружен ли при старте пулер соединений между приложением и сервером.
+func (p *Postgres) BehindPooler() bool {
+	return p.behindPooler.Load()
+}
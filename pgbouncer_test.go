@@ -0,0 +1,32 @@
+package pgfx
+
+import "testing"
+
+func TestLeavesSessionState(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{`SELECT 1`, false},
+		{`SET search_path = app`, true},
+		{`set local statement_timeout = 1000`, false},
+		{`SET TRANSACTION ISOLATION LEVEL SERIALIZABLE`, false},
+		{`SELECT set_config('app.user', $1, true)`, false},
+		{`PREPARE q AS SELECT 1`, true},
+		{`PREPARE TRANSACTION 'gid-1'`, false},
+		{`LISTEN events`, true},
+		{`CREATE TEMP TABLE t (id int)`, true},
+		{`CREATE LOCAL TEMPORARY TABLE t (id int)`, true},
+		{`CREATE TABLE t (id int)`, false},
+		{`DECLARE c CURSOR WITH HOLD FOR SELECT 1`, true},
+		{`DECLARE c NO SCROLL CURSOR FOR SELECT 1`, false},
+		{`SELECT 1; SET timezone = 'UTC'`, true},
+		{`/* SET x = 1 */ SELECT 'SET x = 1'`, false},
+	}
+
+	for _, tt := range tests {
+		if got := leavesSessionState(tt.sql); got != tt.want {
+			t.Errorf("leavesSessionState(%q) = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+}
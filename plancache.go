@@ -0,0 +1,60 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PlanCacheMode — способ планирования запроса с параметрами (см. WithPlanCacheMode).
+type PlanCacheMode int
+
+const (
+	// PlanForceCustom планирует запрос заново с фактическими значениями параметров при каждом
+	// выполнении, а не переходит на общий (generic) план подготовленного оператора.
+	PlanForceCustom PlanCacheMode = iota + 1
+	// PlanForceGeneric сразу использует общий план, не зависящий от значений параметров.
+	PlanForceGeneric
+)
+
+type planCacheKey struct{}
+
+// WithPlanCacheMode задаёт планирование для запросов TransactionalPool с контекстом ctx —
+// например, для запроса по перекошенному распределению, для которого общий план катастрофически
+// плох:
+//
+//	rows, err := db.Query(pgfx.WithPlanCacheMode(ctx, pgfx.PlanForceCustom), `SELECT ... WHERE tenant_id = $1`, id)
+//
+// PlanForceCustom выполняет запрос через безымянный оператор (pgx.QueryExecModeDescribeExec),
+// который сервер планирует с фактическими параметрами; работает и вне транзакции и не стоит
+// лишнего запроса, но не использует кэш подготовленных операторов pgx.
+//
+// PlanForceGeneric устанавливает plan_cache_mode = force_generic_plan до конца транзакции
+// (как SET LOCAL) и поэтому работает только внутри неё: вне транзакции запрос завершается
+// ErrNoTransaction. Чтобы задать режим всей транзакции, удобнее WithSetLocal("plan_cache_mode", ...).
+func WithPlanCacheMode(ctx context.Context, mode PlanCacheMode) context.Context {
+	return context.WithValue(ctx, planCacheKey{}, mode)
+}
+
+// applyPlanCacheMode готовит запрос к режиму планирования из контекста и возвращает его аргументы.
+func applyPlanCacheMode(ctx context.Context, tx pgx.Tx, args []any) ([]any, error) {
+	mode, _ := ctx.Value(planCacheKey{}).(PlanCacheMode)
+	switch mode {
+	case PlanForceCustom:
+		if len(args) > 0 {
+			if _, ok := args[0].(pgx.QueryExecMode); ok {
+				return args, nil
+			}
+		}
+		return append([]any{pgx.QueryExecModeDescribeExec}, args...), nil
+	case PlanForceGeneric:
+		if tx == nil {
+			return nil, fmt.Errorf("plan cache mode: %w", ErrNoTransaction)
+		}
+		if _, err := tx.Exec(ctx, `SELECT set_config('plan_cache_mode', 'force_generic_plan', true)`); err != nil {
+			return nil, fmt.Errorf("plan cache mode: %w", err)
+		}
+	}
+	return args, nil
+}
@@ -0,0 +1,52 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestApplyPlanCacheMode(t *testing.T) {
+	ctx := context.Background()
+
+	args, err := applyPlanCacheMode(ctx, nil, []any{1})
+	if err != nil || len(args) != 1 {
+		t.Fatalf("no mode: got %v, %v", args, err)
+	}
+
+	custom := WithPlanCacheMode(ctx, PlanForceCustom)
+	args, err = applyPlanCacheMode(custom, nil, []any{1})
+	if err != nil || len(args) != 2 || args[0] != pgx.QueryExecModeDescribeExec {
+		t.Fatalf("custom: got %v, %v", args, err)
+	}
+	args, _ = applyPlanCacheMode(custom, nil, []any{pgx.QueryExecModeSimpleProtocol, 1})
+	if len(args) != 2 || args[0] != pgx.QueryExecModeSimpleProtocol {
+		t.Fatalf("custom with explicit mode: got %v", args)
+	}
+
+	generic := WithPlanCacheMode(ctx, PlanForceGeneric)
+	if _, err := applyPlanCacheMode(generic, nil, nil); !errors.Is(err, ErrNoTransaction) {
+		t.Fatalf("generic outside transaction: got %v, want ErrNoTransaction", err)
+	}
+}
+
+func TestPlanForceCustomRedaction(t *testing.T) {
+	ctx := WithPlanCacheMode(context.Background(), PlanForceCustom)
+	sql := `SELECT id FROM users WHERE password = $1 AND email = $2`
+
+	args, err := applyPlanCacheMode(ctx, nil, []any{"secret", "a@b.c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []any{pgx.QueryExecModeDescribeExec, "***", "a@b.c"}
+	if got := (&Redactor{}).Redact(ctx, sql, args); !reflect.DeepEqual(got, want) {
+		t.Errorf("Redact() = %v, want %v", got, want)
+	}
+	if got, want := interpolateSQL(sql, args), `SELECT id FROM users WHERE password = 'secret' AND email = 'a@b.c'`; got != want {
+		t.Errorf("interpolateSQL() = %s, want %s", got, want)
+	}
+}
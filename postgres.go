@@ -3,14 +3,21 @@ package pgfx
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/exaring/otelpgx"
+	pgxdecimal "github.com/jackc/pgx-shopspring-decimal"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	pgxuuid "github.com/vgarvardt/pgx-google-uuid/v5"
 )
 
 const (
@@ -61,7 +68,58 @@ type Postgres struct {
 	maxPoolSize       int32
 	connAttempts      int32
 	connTimeout       time.Duration
-	qt                pgx.QueryTracer
+	maxRows           int
+	qt                *switchTracer
+	tracers           []pgx.QueryTracer
+	rewriters         []QueryRewriter
+	timeouts          timeoutPolicy
+	verifyQueries     bool
+	verifyEnums       bool
+	verifyTables      *TableDriftMode
+	decimal           bool
+	uuid              bool
+	noNestedBegin     bool
+	slowTx            time.Duration
+	txRequestStats    bool
+	priorityAcquire   bool
+	prewarmQueries    []string
+	prewarmExplain    bool
+	redactor          *Redactor
+	txStats           *nestingStats
+	acquire           *acquireTracer
+	active            *activeQueries
+	idleTx            time.Duration
+	idleRollback      bool
+	replay            *bool
+	nesting           NestingMode
+	retryInvalidated  bool
+	health            *healthChecker
+	keepalive         *keepalive
+	holders           *holderTracer
+	txMetrics         TxMetrics
+	txLeakAfter       time.Duration
+	guardTx           bool
+	extensions        extensionSet
+	// txKey — собственный ключ транзакций этого экземпляра в контексте (см. TxKey).
+	txKey *instanceTxKey
+	// sessions — сброс состояния сессии в режиме совместимости с пулерами, nil — выключен
+	// (см. PgBouncerCompatible); behindPooler — пулер обнаружен при старте.
+	sessions     *sessionTracer
+	behindPooler atomic.Bool
+	// liveConnTimeout — текущее значение ConnTimeout для новых соединений, меняется через ApplyConfig.
+	liveConnTimeout atomic.Int64
+	// statsOnce — метрики пула otelpgx регистрируются один раз: при старте с трейсингом
+	// или при первом его включении через ApplyConfig.
+	statsOnce sync.Once
+	// serverVersion — версия сервера (server_version_num), см. ServerVersion.
+	serverVersion atomic.Int64
+	sqlDBOnce     sync.Once
+	sqlDB         *sql.DB
+	// backends — PID серверных процессов соединений пула (см. CancelAll).
+	backends backendSet
+	// replicaConnStr и replica — реплика для отчётов (см. WithReplica, Snapshot).
+	replicaConnStr string
+	replica        *pgxpool.Pool
 }
 
 // New create postgres instance
@@ -70,6 +128,11 @@ func New(connStr string, opts ...Option) (*Postgres, error) {
 		maxPoolSize:  _defaultMaxPoolSize,
 		connAttempts: _defaultConnAttempts,
 		connTimeout:  _defaultConnTimeout,
+		qt:           &switchTracer{},
+		txStats:      &nestingStats{},
+		redactor:     &Redactor{},
+		acquire:      newAcquireTracer(),
+		txKey:        &instanceTxKey{},
 	}
 
 	for _, opt := range opts {
@@ -81,9 +144,29 @@ func New(connStr string, opts ...Option) (*Postgres, error) {
 		return nil, fmt.Errorf("postgres - NewPostgres - pgxpool.ParseConfig: %w", err)
 	}
 
+	if pg.sessions != nil {
+		if len(pg.prewarmQueries) > 0 {
+			return nil, errors.New("postgres - NewPostgres: PrewarmQueries is not supported with PgBouncerCompatible")
+		}
+		poolConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+		poolConfig.AfterRelease = pg.sessions.afterRelease
+	}
+
 	poolConfig.MaxConns = pg.maxPoolSize
 	poolConfig.ConnConfig.ConnectTimeout = pg.connTimeout
-	poolConfig.ConnConfig.Tracer = pg.qt
+	poolConfig.ConnConfig.Tracer = multitracer.New(append([]pgx.QueryTracer{pg.qt, pg.acquire, requestStatsTracer{}}, pg.tracers...)...)
+	pg.liveConnTimeout.Store(int64(pg.connTimeout))
+	poolConfig.BeforeConnect = func(_ context.Context, cfg *pgx.ConnConfig) error {
+		cfg.ConnectTimeout = time.Duration(pg.liveConnTimeout.Load())
+		return nil
+	}
+	poolConfig.AfterConnect = pg.afterConnect
+	poolConfig.BeforeClose = func(conn *pgx.Conn) {
+		pg.backends.remove(conn.PgConn().PID())
+		if pg.sessions != nil {
+			pg.sessions.dirty.Delete(conn)
+		}
+	}
 	for pg.connAttempts > 0 {
 		pg.Pool, err = pgxpool.NewWithConfig(context.Background(), poolConfig)
 
@@ -102,17 +185,83 @@ func New(connStr string, opts ...Option) (*Postgres, error) {
 		return nil, fmt.Errorf("postgres - NewPostgres - connAttempts == 0: %w", err)
 	}
 
-	if pg.qt != nil {
-		if err := otelpgx.RecordStats(pg.Pool); err != nil {
+	if pg.qt.Enabled() {
+		if err := pg.recordStats(); err != nil {
 			return nil, fmt.Errorf("unable to record database stats: %w", err)
 		}
 	}
-	transactor := pgTransactor{dbc: pg.Pool}
+	pg.checkPooler(context.Background())
+
+	if pg.verifyQueries {
+		if err := pg.VerifyQueries(context.Background()); err != nil {
+			pg.Pool.Close()
+			return nil, fmt.Errorf("postgres - NewPostgres - VerifyQueries: %w", err)
+		}
+	}
+	if pg.verifyEnums {
+		if err := pg.VerifyEnums(context.Background()); err != nil {
+			pg.Pool.Close()
+			return nil, fmt.Errorf("postgres - NewPostgres - VerifyEnums: %w", err)
+		}
+	}
+
+	if pg.verifyTables != nil {
+		if err := pg.VerifyTables(context.Background()); err != nil {
+			if *pg.verifyTables == TableDriftFail {
+				pg.Pool.Close()
+				return nil, fmt.Errorf("postgres - NewPostgres - VerifyTables: %w", err)
+			}
+			log.Printf("postgres - NewPostgres - VerifyTables: %v", err)
+		}
+	}
+
+	if pg.replicaConnStr != "" {
+		if err := pg.connectReplica(poolConfig); err != nil {
+			pg.Pool.Close()
+			return nil, fmt.Errorf("postgres - NewPostgres - replica: %w", err)
+		}
+	}
+
+	transactor := pgTransactor{
+		dbc:           pg.Pool,
+		maxRows:       pg.maxRows,
+		rewriters:     pg.rewriters,
+		noNestedBegin: pg.noNestedBegin,
+		timeouts:      pg.timeouts,
+		key:           pg.txKey,
+
+		retryInvalidated: pg.retryInvalidated,
+	}
+	if pg.priorityAcquire {
+		transactor.gate = newPriorityGate(int(pg.maxPoolSize))
+	}
 	pg.TransactionalPool = transactor
 
+	if pg.health != nil {
+		pg.startHealthCheck()
+	}
+	if pg.keepalive != nil {
+		pg.startKeepalive()
+	}
+
 	return pg, nil
 }
 
+func (p *Postgres) afterConnect(ctx context.Context, conn *pgx.Conn) error {
+	p.backends.add(conn.PgConn().PID())
+	version := p.recordServerVersion(conn)
+	if p.decimal {
+		pgxdecimal.Register(conn.TypeMap())
+	}
+	if p.uuid {
+		pgxuuid.Register(conn.TypeMap())
+	}
+	if len(p.prewarmQueries) > 0 {
+		return p.prewarm(ctx, conn, version)
+	}
+	return nil
+}
+
 // NewTransactionManager создаёт новый менеджер транзакций (Manager),
 // который использует TransactionalPool из текущего экземпляра Postgres.
 //
@@ -133,7 +282,25 @@ func New(connStr string, opts ...Option) (*Postgres, error) {
 // Важно: для выполнения запросов внутри транзакций следует использовать pg.TransactionalPool,
 // а не pg.Pool напрямую.
 func (p *Postgres) NewTransactionManager() *Manager {
-	return newTransactionManager(p.TransactionalPool)
+	m := newTransactionManager(p.TransactionalPool, p.txStats)
+	m.qt = p.qt
+	m.slowTx = p.slowTx
+	m.idleTx = p.idleTx
+	m.idleRollback = p.idleRollback
+	m.replay = p.replay
+	m.nesting = p.nesting
+	m.txStatsScope = p.txRequestStats
+	m.key = p.txKey
+	m.metrics = p.txMetrics
+	m.leakAfter = p.txLeakAfter
+	m.guardTx = p.guardTx
+	return m
+}
+
+// TxFromContext возвращает транзакцию этого экземпляра из контекста и false, если её нет.
+// В отличие от pgfx.TxFromContext не видит транзакций других баз, открытых в том же контексте.
+func (p *Postgres) TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	return txFrom(ctx, p.txKey)
 }
 
 // GetDBForTransactionManager возвращает обертку базы данных через которую можно вызывать запросы.
@@ -158,10 +325,54 @@ func (p *Postgres) GetDBForTransactionManager() QueryExecutor {
 	return p.TransactionalPool
 }
 
+// recordStats регистрирует метрики пула otelpgx; повторные вызовы ничего не делают.
+func (p *Postgres) recordStats() error {
+	var err error
+	p.statsOnce.Do(func() { err = otelpgx.RecordStats(p.Pool) })
+	return err
+}
+
+// Reset пересоздаёт соединения пула (и пула реплики, см. WithReplica): простаивающие
+// соединения закрываются сразу, а занятые — при возврате в пул, поэтому выполняющиеся
+// запросы и транзакции не прерываются. Подходит для ротации учётных данных (вместе
+// с BeforeConnect, подставляющим новый пароль) и очистки после переключения на реплику.
+//
+// После сброса Reset проверяет, что новое соединение устанавливается (Ping), и возвращает
+// ошибку, если это не удалось.
+func (p *Postgres) Reset(ctx context.Context) error {
+	p.Pool.Reset()
+	if p.replica != nil {
+		p.replica.Reset()
+	}
+
+	if err := p.Pool.Ping(ctx); err != nil {
+		return fmt.Errorf("postgres - Reset - ping: %w", err)
+	}
+	if p.replica != nil {
+		if err := p.replica.Ping(ctx); err != nil {
+			return fmt.Errorf("postgres - Reset - ping replica: %w", err)
+		}
+	}
+	return nil
+}
+
 // Close is close postgres pool
 func (p *Postgres) Close() error {
+	p.logHolders()
+	p.close()
+	return nil
+}
+
+func (p *Postgres) close() {
+	p.stopHealthCheck()
+	p.stopKeepalive()
+	if p.sqlDB != nil {
+		_ = p.sqlDB.Close()
+	}
 	if p.Pool != nil {
 		p.Pool.Close()
 	}
-	return nil
+	if p.replica != nil {
+		p.replica.Close()
+	}
 }
@@ -0,0 +1,39 @@
+package pgfx
+
+import (
+	"context"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// prewarm подготавливает горячие запросы на новом соединении (см. PrewarmQueries).
+//
+// Оператор готовится под именем, равным тексту запроса: pgx в режиме кэша операторов
+// по умолчанию сначала ищет подготовленный оператор с таким именем, поэтому первый
+// запрос на новом соединении не тратит лишний round-trip на Parse/Describe.
+// Ошибки только логируются, чтобы опечатка в одном запросе не лишала пул соединений.
+func (p *Postgres) prewarm(ctx context.Context, conn *pgx.Conn, version int) error {
+	rw := pgTransactor{rewriters: p.rewriters}
+
+	for _, q := range p.prewarmQueries {
+		// Запросы через TransactionalPool проходят через QueryRewriter, поэтому
+		// готовится уже переписанный текст — иначе кэш не совпадёт.
+		sql := rw.rewrite(ctx, q)
+
+		if _, err := conn.Prepare(ctx, sql, sql); err != nil {
+			log.Printf("pgfx: prewarm: prepare %q: %v", q, err)
+			continue
+		}
+
+		if p.prewarmExplain && FeatureGenericPlan.SupportedBy(version) {
+			// GENERIC_PLAN строит план без значений параметров.
+			// Простой протокол pgconn отправляет текст как есть, не подставляя $n.
+			if _, err := conn.PgConn().Exec(ctx, "EXPLAIN (GENERIC_PLAN) "+sql).ReadAll(); err != nil {
+				log.Printf("pgfx: prewarm: explain %q: %v", q, err)
+			}
+		}
+	}
+
+	return nil
+}
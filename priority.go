@@ -0,0 +1,181 @@
+package pgfx
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Priority — класс приоритета получения соединения (см. PriorityAcquire).
+type Priority int
+
+const (
+	// PriorityLow — фоновые и пакетные задачи.
+	PriorityLow Priority = -10
+	// PriorityNormal — приоритет по умолчанию.
+	PriorityNormal Priority = 0
+	// PriorityHigh — интерактивные запросы пользователей.
+	PriorityHigh Priority = 10
+	// PriorityCritical — health check'и и служебные запросы.
+	PriorityCritical Priority = 20
+)
+
+type priorityKey struct{}
+
+// WithPriority задаёт приоритет получения соединения для операций с этим контекстом.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+func priorityFromContext(ctx context.Context) Priority {
+	p, ok := ctx.Value(priorityKey{}).(Priority)
+	if !ok {
+		return PriorityNormal
+	}
+	return p
+}
+
+// priorityGate ограничивает число операций, одновременно занимающих соединения пула,
+// и при нехватке выдаёт слоты по приоритету, а внутри приоритета — в порядке очереди.
+type priorityGate struct {
+	mu      sync.Mutex
+	free    int
+	seq     uint64
+	waiters waiterHeap
+}
+
+type gateWaiter struct {
+	prio  Priority
+	seq   uint64
+	ready chan struct{}
+	index int
+}
+
+func newPriorityGate(size int) *priorityGate {
+	return &priorityGate{free: size}
+}
+
+// enter занимает слот и возвращает функцию его освобождения (идемпотентную).
+// На nil-gate ничего не делает.
+func (g *priorityGate) enter(ctx context.Context) (func(), error) {
+	if g == nil {
+		return func() {}, nil
+	}
+
+	g.mu.Lock()
+	if g.free > 0 && len(g.waiters) == 0 {
+		g.free--
+		g.mu.Unlock()
+		return g.releaseOnce(), nil
+	}
+
+	w := &gateWaiter{prio: priorityFromContext(ctx), seq: g.seq, ready: make(chan struct{})}
+	g.seq++
+	heap.Push(&g.waiters, w)
+	g.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return g.releaseOnce(), nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		if w.index >= 0 {
+			heap.Remove(&g.waiters, w.index)
+			g.mu.Unlock()
+		} else {
+			// Слот уже передан нам — отдаём его следующему.
+			g.mu.Unlock()
+			g.release()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (g *priorityGate) releaseOnce() func() {
+	var once sync.Once
+	return func() { once.Do(g.release) }
+}
+
+func (g *priorityGate) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.waiters) > 0 {
+		w := heap.Pop(&g.waiters).(*gateWaiter)
+		close(w.ready)
+		return
+	}
+	g.free++
+}
+
+type waiterHeap []*gateWaiter
+
+func (h waiterHeap) Len() int { return len(h) }
+func (h waiterHeap) Less(i, j int) bool {
+	if h[i].prio != h[j].prio {
+		return h[i].prio > h[j].prio
+	}
+	return h[i].seq < h[j].seq
+}
+func (h waiterHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+func (h *waiterHeap) Push(x any) {
+	w := x.(*gateWaiter)
+	w.index = len(*h)
+	*h = append(*h, w)
+}
+func (h *waiterHeap) Pop() any {
+	old := *h
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	w.index = -1
+	*h = old[:len(old)-1]
+	return w
+}
+
+// gatedTx освобождает слот priorityGate по завершении транзакции.
+type gatedTx struct {
+	pgx.Tx
+	release func()
+}
+
+func (t gatedTx) Commit(ctx context.Context) error {
+	defer t.release()
+	return t.Tx.Commit(ctx)
+}
+
+func (t gatedTx) Rollback(ctx context.Context) error {
+	err := t.Tx.Rollback(ctx)
+	// После Commit Rollback возвращает ErrTxClosed; слот к этому моменту уже освобождён.
+	t.release()
+	return err
+}
+
+// gatedBatchResults освобождает слот priorityGate при закрытии результатов пакета.
+type gatedBatchResults struct {
+	pgx.BatchResults
+	release func()
+}
+
+func (r gatedBatchResults) Close() error {
+	defer r.release()
+	return r.BatchResults.Close()
+}
+
+// errRow — pgx.Row, Scan которого возвращает err.
+type errRow struct{ err error }
+
+func (r errRow) Scan(...any) error { return r.err }
+
+// errBatchResults — pgx.BatchResults, все методы которого возвращают err.
+type errBatchResults struct{ err error }
+
+func (r errBatchResults) Exec() (pgconn.CommandTag, error) { return pgconn.CommandTag{}, r.err }
+func (r errBatchResults) Query() (pgx.Rows, error)         { return nil, r.err }
+func (r errBatchResults) QueryRow() pgx.Row                { return errRow{r.err} }
+func (r errBatchResults) Close() error                     { return r.err }
@@ -0,0 +1,71 @@
+package pgfx
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPriorityGate(t *testing.T) {
+	g := newPriorityGate(1)
+	ctx := context.Background()
+
+	release, err := g.enter(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan Priority, 3)
+	start := func(p Priority) {
+		go func() {
+			rel, err := g.enter(WithPriority(ctx, p))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			order <- p
+			rel()
+		}()
+	}
+
+	start(PriorityLow)
+	waitWaiters(t, g, 1)
+	start(PriorityNormal)
+	waitWaiters(t, g, 2)
+	start(PriorityCritical)
+	waitWaiters(t, g, 3)
+
+	release()
+	release() // повторный вызов не должен освобождать второй слот
+
+	for _, want := range []Priority{PriorityCritical, PriorityNormal, PriorityLow} {
+		if got := <-order; got != want {
+			t.Fatalf("got priority %d, want %d", got, want)
+		}
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	hold, _ := g.enter(ctx)
+	if _, err := g.enter(cctx); err == nil {
+		t.Fatal("expected context error while the gate is full")
+	}
+	hold()
+	if g.free != 1 || len(g.waiters) != 0 {
+		t.Fatalf("gate leaked slots: free=%d waiters=%d", g.free, len(g.waiters))
+	}
+}
+
+func waitWaiters(t *testing.T, g *priorityGate, n int) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		g.mu.Lock()
+		l := len(g.waiters)
+		g.mu.Unlock()
+		if l == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("waiters did not reach %d", n)
+}
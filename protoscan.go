@@ -0,0 +1,312 @@
+package pgfx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// ErrProtoField возвращается при сканировании в protobuf-сообщение, если колонке нет
+// соответствующего поля или значение не приводится к типу поля.
+var ErrProtoField = errors.New("cannot map column to protobuf field")
+
+// ProtoMessage — ограничение для указателя на сгенерированное protobuf-сообщение.
+type ProtoMessage[T any] interface {
+	*T
+	proto.Message
+}
+
+// RowToProto — pgx.RowToFunc, сканирующий строку в новое protobuf-сообщение:
+//
+//	users, err := pgx.CollectRows(rows, pgfx.RowToProto[userpb.User])
+//
+// Колонка сопоставляется с полем по имени поля в .proto (snake_case), затем по JSON-имени.
+// NULL оставляет поле неустановленным. Поддерживаются скалярные поля, enum (по имени или номеру),
+// repeated из массивов и well-known types: Timestamp (timestamp, timestamptz, date),
+// Duration (interval), обёртки (*Value) и Struct (json, jsonb).
+func RowToProto[T any, M ProtoMessage[T]](row pgx.CollectableRow) (M, error) {
+	return scanProto[T, M](row, nil)
+}
+
+// RowToProtoMapped — как RowToProto, но колонки из fields сопоставляются с полями по номеру.
+// Удобно, когда имена колонок и полей расходятся:
+//
+//	pgx.CollectRows(rows, pgfx.RowToProtoMapped[userpb.User](map[string]protoreflect.FieldNumber{"usr_email": 3}))
+func RowToProtoMapped[T any, M ProtoMessage[T]](fields map[string]protoreflect.FieldNumber) pgx.RowToFunc[M] {
+	return func(row pgx.CollectableRow) (M, error) {
+		return scanProto[T, M](row, fields)
+	}
+}
+
+// QueryProto выполняет запрос и сканирует все строки в protobuf-сообщения (см. RowToProto).
+func QueryProto[T any, M ProtoMessage[T]](ctx context.Context, db QueryExecutor, sql string, args ...any) ([]M, error) {
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query proto: %w", err)
+	}
+
+	msgs, err := pgx.CollectRows(rows, RowToProto[T, M])
+	if err != nil {
+		return nil, fmt.Errorf("query proto: %w", err)
+	}
+	return msgs, nil
+}
+
+func scanProto[T any, M ProtoMessage[T]](row pgx.CollectableRow, mapping map[string]protoreflect.FieldNumber) (M, error) {
+	msg := M(new(T))
+	m := msg.ProtoReflect()
+	fields := m.Descriptor().Fields()
+
+	values, err := row.Values()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, fd := range row.FieldDescriptions() {
+		var field protoreflect.FieldDescriptor
+		if num, ok := mapping[fd.Name]; ok {
+			field = fields.ByNumber(num)
+		} else if field = fields.ByName(protoreflect.Name(fd.Name)); field == nil {
+			field = fields.ByJSONName(fd.Name)
+		}
+		if field == nil {
+			return nil, fmt.Errorf("%w: column %q has no field in %s", ErrProtoField, fd.Name, m.Descriptor().FullName())
+		}
+
+		if values[i] == nil {
+			continue
+		}
+		if err := setProtoField(m, field, values[i]); err != nil {
+			return nil, fmt.Errorf("%w: column %q -> %s: %w", ErrProtoField, fd.Name, field.FullName(), err)
+		}
+	}
+
+	return msg, nil
+}
+
+func setProtoField(m protoreflect.Message, fd protoreflect.FieldDescriptor, v any) error {
+	switch {
+	case fd.IsMap():
+		return fmt.Errorf("map fields are not supported")
+	case fd.IsList():
+		items, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("expected array, got %T", v)
+		}
+		list := m.Mutable(fd).List()
+		for _, item := range items {
+			if item == nil {
+				return fmt.Errorf("NULL array element")
+			}
+			pv, err := protoValue(fd, item)
+			if err != nil {
+				return err
+			}
+			list.Append(pv)
+		}
+		return nil
+	default:
+		pv, err := protoValue(fd, v)
+		if err != nil {
+			return err
+		}
+		m.Set(fd, pv)
+		return nil
+	}
+}
+
+// protoValue приводит значение, декодированное pgx, к значению поля fd.
+func protoValue(fd protoreflect.FieldDescriptor, v any) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		if b, ok := v.(bool); ok {
+			return protoreflect.ValueOfBool(b), nil
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if n, ok := toInt64(v); ok && n >= math.MinInt32 && n <= math.MaxInt32 {
+			return protoreflect.ValueOfInt32(int32(n)), nil
+		}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if n, ok := toInt64(v); ok {
+			return protoreflect.ValueOfInt64(n), nil
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if n, ok := toInt64(v); ok && n >= 0 && n <= math.MaxUint32 {
+			return protoreflect.ValueOfUint32(uint32(n)), nil
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if n, ok := toInt64(v); ok && n >= 0 {
+			return protoreflect.ValueOfUint64(uint64(n)), nil
+		}
+	case protoreflect.FloatKind:
+		if f, ok := toFloat64(v); ok {
+			return protoreflect.ValueOfFloat32(float32(f)), nil
+		}
+	case protoreflect.DoubleKind:
+		if f, ok := toFloat64(v); ok {
+			return protoreflect.ValueOfFloat64(f), nil
+		}
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(toProtoString(v)), nil
+	case protoreflect.BytesKind:
+		switch b := v.(type) {
+		case []byte:
+			return protoreflect.ValueOfBytes(b), nil
+		case [16]byte:
+			return protoreflect.ValueOfBytes(b[:]), nil
+		}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		switch e := v.(type) {
+		case string:
+			if ev := values.ByName(protoreflect.Name(e)); ev != nil {
+				return protoreflect.ValueOfEnum(ev.Number()), nil
+			}
+			return protoreflect.Value{}, fmt.Errorf("unknown enum value %q", e)
+		default:
+			if n, ok := toInt64(v); ok && n >= math.MinInt32 && n <= math.MaxInt32 {
+				return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), nil
+			}
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		msg, err := wellKnownMessage(fd.Message().FullName(), v)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		if msg != nil {
+			return protoreflect.ValueOfMessage(msg.ProtoReflect()), nil
+		}
+		return protoreflect.Value{}, fmt.Errorf("message type %s is not supported", fd.Message().FullName())
+	}
+
+	return protoreflect.Value{}, fmt.Errorf("cannot convert %T to %s", v, fd.Kind())
+}
+
+// wellKnownMessage строит well-known type по имени; (nil, nil) — тип не поддерживается.
+func wellKnownMessage(name protoreflect.FullName, v any) (proto.Message, error) {
+	switch name {
+	case "google.protobuf.Timestamp":
+		if t, ok := v.(time.Time); ok {
+			return timestamppb.New(t), nil
+		}
+	case "google.protobuf.Duration":
+		switch d := v.(type) {
+		case time.Duration:
+			return durationpb.New(d), nil
+		case pgtype.Interval:
+			if d.Months != 0 {
+				return nil, fmt.Errorf("interval with months cannot be represented as Duration")
+			}
+			return durationpb.New(time.Duration(d.Days)*24*time.Hour + time.Duration(d.Microseconds)*time.Microsecond), nil
+		}
+	case "google.protobuf.StringValue":
+		return wrapperspb.String(toProtoString(v)), nil
+	case "google.protobuf.BoolValue":
+		if b, ok := v.(bool); ok {
+			return wrapperspb.Bool(b), nil
+		}
+	case "google.protobuf.Int32Value":
+		if n, ok := toInt64(v); ok && n >= math.MinInt32 && n <= math.MaxInt32 {
+			return wrapperspb.Int32(int32(n)), nil
+		}
+	case "google.protobuf.Int64Value":
+		if n, ok := toInt64(v); ok {
+			return wrapperspb.Int64(n), nil
+		}
+	case "google.protobuf.UInt32Value":
+		if n, ok := toInt64(v); ok && n >= 0 && n <= math.MaxUint32 {
+			return wrapperspb.UInt32(uint32(n)), nil
+		}
+	case "google.protobuf.UInt64Value":
+		if n, ok := toInt64(v); ok && n >= 0 {
+			return wrapperspb.UInt64(uint64(n)), nil
+		}
+	case "google.protobuf.FloatValue":
+		if f, ok := toFloat64(v); ok {
+			return wrapperspb.Float(float32(f)), nil
+		}
+	case "google.protobuf.DoubleValue":
+		if f, ok := toFloat64(v); ok {
+			return wrapperspb.Double(f), nil
+		}
+	case "google.protobuf.BytesValue":
+		if b, ok := v.([]byte); ok {
+			return wrapperspb.Bytes(b), nil
+		}
+	case "google.protobuf.Struct":
+		if obj, ok := v.(map[string]any); ok {
+			return structpb.NewStruct(obj)
+		}
+	case "google.protobuf.Value":
+		return structpb.NewValue(v)
+	default:
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("cannot convert %T to %s", v, name)
+}
+
+func toInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case pgtype.Numeric:
+		i, err := n.Int64Value()
+		return i.Int64, err == nil && i.Valid
+	}
+	return 0, false
+}
+
+func toFloat64(v any) (float64, bool) {
+	switch f := v.(type) {
+	case float32:
+		return float64(f), true
+	case float64:
+		return f, true
+	case pgtype.Numeric:
+		fv, err := f.Float64Value()
+		return fv.Float64, err == nil && fv.Valid
+	}
+	if n, ok := toInt64(v); ok {
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func toProtoString(v any) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case [16]byte:
+		return fmt.Sprintf("%x-%x-%x-%x-%x", s[0:4], s[4:6], s[6:8], s[8:10], s[10:16])
+	case time.Time:
+		return s.Format(time.RFC3339Nano)
+	case pgtype.Numeric:
+		if b, err := s.MarshalJSON(); err == nil {
+			return string(b)
+		}
+	case map[string]any, []any:
+		if b, err := json.Marshal(s); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(v)
+}
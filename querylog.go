@@ -0,0 +1,59 @@
+package pgfx
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// queryLogTracer пишет запросы в стандартный лог. При threshold > 0 логируются только
+// запросы, выполнявшиеся дольше threshold (журнал медленных запросов).
+// Параметры запросов проходят через Redactor, заданный WithRedaction.
+type queryLogTracer struct {
+	p         *Postgres
+	threshold time.Duration
+}
+
+type queryLogKey struct{}
+
+type queryLogEntry struct {
+	start time.Time
+	sql   string
+	args  []any
+	tag   string
+}
+
+func (t queryLogTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryLogKey{}, &queryLogEntry{
+		start: time.Now(),
+		sql:   data.SQL,
+		args:  t.p.redactor.Redact(ctx, data.SQL, data.Args),
+		tag:   QueryTag(ctx),
+	})
+}
+
+func (t queryLogTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	e, ok := ctx.Value(queryLogKey{}).(*queryLogEntry)
+	if !ok {
+		return
+	}
+
+	elapsed := time.Since(e.start)
+	if elapsed < t.threshold {
+		return
+	}
+
+	prefix := "pgfx: query"
+	if t.threshold > 0 {
+		prefix = "pgfx: slow query"
+	}
+
+	switch {
+	case data.Err != nil:
+		log.Printf("%s [%s] %s (%s) tag=%q args: %s: error: %v", prefix, elapsed, e.sql, data.CommandTag, e.tag, formatArgs(e.args), data.Err)
+	default:
+		log.Printf("%s [%s] %s (%s) tag=%q args: %s", prefix, elapsed, e.sql, data.CommandTag, e.tag, formatArgs(e.args))
+	}
+}
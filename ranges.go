@@ -0,0 +1,127 @@
+package pgfx
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Interval — значение interval PostgreSQL в удобном для Go виде. Месяцы и дни хранятся
+// отдельно, потому что их длительность зависит от календаря ('1 month' ≠ 30 дней).
+// Реализует pgtype.IntervalScanner и pgtype.IntervalValuer, поэтому сканируется и передаётся
+// в запросы напрямую.
+type Interval struct {
+	Months   int32
+	Days     int32
+	Duration time.Duration
+	// Null — значение NULL.
+	Null bool
+}
+
+// IntervalOf возвращает интервал фиксированной длительности d.
+func IntervalOf(d time.Duration) Interval {
+	return Interval{Duration: d}
+}
+
+// ToDuration возвращает длительность интервала; дни считаются по 24 часа.
+// Для интервалов с месяцами возвращается ошибка: их длина не определена без даты отсчёта.
+func (i Interval) ToDuration() (time.Duration, error) {
+	if i.Months != 0 {
+		return 0, fmt.Errorf("interval %d months cannot be converted to time.Duration", i.Months)
+	}
+	return time.Duration(i.Days)*24*time.Hour + i.Duration, nil
+}
+
+// AddTo прибавляет интервал к t по правилам PostgreSQL: месяцы и дни — календарно, остаток — точно.
+func (i Interval) AddTo(t time.Time) time.Time {
+	return t.AddDate(0, int(i.Months), int(i.Days)).Add(i.Duration)
+}
+
+func (i *Interval) ScanInterval(v pgtype.Interval) error {
+	if !v.Valid {
+		*i = Interval{Null: true}
+		return nil
+	}
+	*i = Interval{Months: v.Months, Days: v.Days, Duration: time.Duration(v.Microseconds) * time.Microsecond}
+	return nil
+}
+
+func (i Interval) IntervalValue() (pgtype.Interval, error) {
+	if i.Null {
+		return pgtype.Interval{}, nil
+	}
+	return pgtype.Interval{Months: i.Months, Days: i.Days, Microseconds: i.Duration.Microseconds(), Valid: true}, nil
+}
+
+// TimeRange — значение tstzrange (и tsrange, daterange).
+type TimeRange = pgtype.Range[time.Time]
+
+// Int64Range — значение int8range (и int4range).
+type Int64Range = pgtype.Range[int64]
+
+// NewTimeRange возвращает полуоткрытый диапазон [from, to). Нулевое время означает
+// отсутствие соответствующей границы.
+func NewTimeRange(from, to time.Time) TimeRange {
+	r := TimeRange{Lower: from, Upper: to, LowerType: pgtype.Inclusive, UpperType: pgtype.Exclusive, Valid: true}
+	if from.IsZero() {
+		r.LowerType = pgtype.Unbounded
+	}
+	if to.IsZero() {
+		r.UpperType = pgtype.Unbounded
+	}
+	return r
+}
+
+// NewInt64Range возвращает полуоткрытый диапазон [from, to) — каноническую форму int8range.
+func NewInt64Range(from, to int64) Int64Range {
+	return Int64Range{Lower: from, Upper: to, LowerType: pgtype.Inclusive, UpperType: pgtype.Exclusive, Valid: true}
+}
+
+// RangeContains сообщает, входит ли v в диапазон r (аналог оператора @> на стороне Go).
+// compare — функция сравнения элементов: time.Time.Compare, cmp.Compare[int64] и т.п.
+func RangeContains[T any](r pgtype.Range[T], v T, compare func(a, b T) int) bool {
+	if !r.Valid || r.LowerType == pgtype.Empty {
+		return false
+	}
+
+	switch r.LowerType {
+	case pgtype.Inclusive:
+		if compare(v, r.Lower) < 0 {
+			return false
+		}
+	case pgtype.Exclusive:
+		if compare(v, r.Lower) <= 0 {
+			return false
+		}
+	}
+
+	switch r.UpperType {
+	case pgtype.Inclusive:
+		return compare(v, r.Upper) <= 0
+	case pgtype.Exclusive:
+		return compare(v, r.Upper) < 0
+	}
+	return true
+}
+
+// RangesOverlap сообщает, пересекаются ли диапазоны a и b (аналог оператора &&).
+func RangesOverlap[T any](a, b pgtype.Range[T], compare func(x, y T) int) bool {
+	if !a.Valid || !b.Valid || a.LowerType == pgtype.Empty || b.LowerType == pgtype.Empty {
+		return false
+	}
+	return lowerBeforeUpper(a, b, compare) && lowerBeforeUpper(b, a, compare)
+}
+
+// lowerBeforeUpper сообщает, начинается ли a раньше, чем заканчивается b.
+func lowerBeforeUpper[T any](a, b pgtype.Range[T], compare func(x, y T) int) bool {
+	if a.LowerType == pgtype.Unbounded || b.UpperType == pgtype.Unbounded {
+		return true
+	}
+
+	c := compare(a.Lower, b.Upper)
+	if c != 0 {
+		return c < 0
+	}
+	return a.LowerType == pgtype.Inclusive && b.UpperType == pgtype.Inclusive
+}
@@ -0,0 +1,39 @@
+package pgfx
+
+import (
+	"cmp"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestRangeHelpers(t *testing.T) {
+	r := NewInt64Range(10, 20)
+	for v, want := range map[int64]bool{9: false, 10: true, 19: true, 20: false} {
+		if got := RangeContains(r, v, cmp.Compare[int64]); got != want {
+			t.Errorf("RangeContains([10,20), %d) = %v, want %v", v, got, want)
+		}
+	}
+
+	if RangesOverlap(r, NewInt64Range(20, 30), cmp.Compare[int64]) {
+		t.Error("[10,20) and [20,30) must not overlap")
+	}
+	if !RangesOverlap(r, NewInt64Range(19, 30), cmp.Compare[int64]) {
+		t.Error("[10,20) and [19,30) must overlap")
+	}
+
+	closed := Int64Range{Lower: 20, Upper: 30, LowerType: pgtype.Inclusive, UpperType: pgtype.Inclusive, Valid: true}
+	if !RangesOverlap(closed, Int64Range{Lower: 0, Upper: 20, LowerType: pgtype.Inclusive, UpperType: pgtype.Inclusive, Valid: true}, cmp.Compare[int64]) {
+		t.Error("[20,30] and [0,20] must overlap")
+	}
+
+	now := time.Now()
+	open := NewTimeRange(now, time.Time{})
+	if !RangeContains(open, now.Add(100*365*24*time.Hour), time.Time.Compare) {
+		t.Error("unbounded upper range must contain future times")
+	}
+	if RangeContains(open, now.Add(-time.Second), time.Time.Compare) {
+		t.Error("range must not contain times before its lower bound")
+	}
+}
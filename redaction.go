@@ -0,0 +1,251 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type queryTagKey struct{}
+
+// WithQueryTag помечает запросы, выполняемые с этим контекстом, тегом tag
+// (например "users.get" или "report.sales"). Тег используется правилами
+// маскирования параметров и попадает в логи запросов.
+func WithQueryTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, queryTagKey{}, tag)
+}
+
+// QueryTag возвращает тег запроса из контекста или пустую строку.
+func QueryTag(ctx context.Context) string {
+	tag, _ := ctx.Value(queryTagKey{}).(string)
+	return tag
+}
+
+// DefaultSensitiveColumns — подстроки имён колонок, параметры которых Redactor маскирует по умолчанию.
+var DefaultSensitiveColumns = []string{"password", "passwd", "secret", "token", "ssn", "card_number", "cvv", "api_key"}
+
+// Redactor описывает, какие параметры запросов маскируются в логах запросов и span'ах.
+// Параметр маскируется, если сработало хотя бы одно из правил.
+type Redactor struct {
+	// Positions — номера параметров ($1 = 1), которые маскируются во всех запросах.
+	Positions []int
+	// Tags — тег запроса (см. WithQueryTag) → номера параметров; 0 маскирует все параметры запроса.
+	Tags map[string][]int
+	// Columns — подстроки имён колонок (без учёта регистра). Параметр маскируется, если
+	// он сравнивается с такой колонкой (password = $1), присваивается ей (SET password = $1)
+	// или вставляется в неё (INSERT INTO t (password) VALUES ($1)).
+	// nil означает DefaultSensitiveColumns, пустой срез отключает эвристику.
+	Columns []string
+	// Mask — строка, которой заменяется значение. По умолчанию "***".
+	Mask string
+}
+
+// Redact возвращает копию args, в которой чувствительные параметры заменены маской.
+// Ведущие опции pgx (pgx.QueryExecMode и т.п.) не считаются параметрами и не маскируются.
+func (r *Redactor) Redact(ctx context.Context, sql string, args []any) []any {
+	out := make([]any, len(args))
+	copy(out, args)
+	params := out[queryOptions(args):]
+	if r == nil || len(params) == 0 {
+		return out
+	}
+
+	mask := r.Mask
+	if mask == "" {
+		mask = "***"
+	}
+
+	masked := make(map[int]bool)
+	for _, pos := range r.Positions {
+		masked[pos] = true
+	}
+	for _, pos := range r.Tags[QueryTag(ctx)] {
+		if pos == 0 {
+			for i := range params {
+				params[i] = mask
+			}
+			return out
+		}
+		masked[pos] = true
+	}
+
+	columns := r.Columns
+	if columns == nil {
+		columns = DefaultSensitiveColumns
+	}
+	if len(columns) > 0 {
+		for pos, column := range paramColumns(sql) {
+			column = strings.ToLower(column)
+			for _, c := range columns {
+				if strings.Contains(column, strings.ToLower(c)) {
+					masked[pos] = true
+					break
+				}
+			}
+		}
+	}
+
+	for pos := range masked {
+		if pos >= 1 && pos <= len(params) {
+			params[pos-1] = mask
+		}
+	}
+
+	return out
+}
+
+// queryOptions возвращает число ведущих опций pgx в args: pgx передаёт их трейсерам вместе
+// с параметрами запроса, а $1 — первый аргумент после них.
+func queryOptions(args []any) int {
+	for i, arg := range args {
+		switch arg.(type) {
+		case pgx.QueryExecMode, pgx.QueryResultFormats, pgx.QueryResultFormatsByOID, pgx.QueryRewriter:
+		default:
+			return i
+		}
+	}
+	return len(args)
+}
+
+// paramColumns сопоставляет номера параметров с колонками, с которыми они используются:
+// "col <op> $n", "$n <op> col" и списки INSERT (cols) VALUES (...).
+func paramColumns(sql string) map[int]string {
+	ts := significant(scanSQL(sql))
+	out := make(map[int]string)
+
+	for i, t := range ts {
+		if t.kind != tokParam {
+			continue
+		}
+		pos, err := strconv.Atoi(t.text[1:])
+		if err != nil {
+			continue
+		}
+
+		if column, ok := columnBefore(ts, i); ok {
+			out[pos] = column
+		} else if column, ok := columnAfter(ts, i); ok {
+			out[pos] = column
+		}
+	}
+
+	for pos, column := range insertColumns(ts) {
+		out[pos] = column
+	}
+
+	return out
+}
+
+func isComparison(ts []sqlToken, i int) bool {
+	return i >= 0 && i < len(ts) && ts[i].kind == tokPunct && strings.Contains("=<>!~", ts[i].text) ||
+		i >= 0 && i < len(ts) && ts[i].isAny("like", "ilike")
+}
+
+// columnBefore распознаёт "col = $n", "t.col <> $n" и т.п. (операторы из нескольких символов
+// лексер отдаёт по одному символу).
+func columnBefore(ts []sqlToken, i int) (string, bool) {
+	j := i - 1
+	for isComparison(ts, j) {
+		j--
+	}
+	if j == i-1 || j < 0 {
+		return "", false
+	}
+	return identName(ts[j])
+}
+
+func columnAfter(ts []sqlToken, i int) (string, bool) {
+	j := i + 1
+	for isComparison(ts, j) {
+		j++
+	}
+	if j == i+1 || j >= len(ts) {
+		return "", false
+	}
+	return identName(ts[j])
+}
+
+func identName(t sqlToken) (string, bool) {
+	switch t.kind {
+	case tokWord:
+		return t.text, true
+	case tokQuotedIdent:
+		return strings.ReplaceAll(t.text[1:len(t.text)-1], `""`, `"`), true
+	}
+	return "", false
+}
+
+// insertColumns разбирает INSERT INTO t (a, b) VALUES ($1, $2), ($3, $4).
+func insertColumns(ts []sqlToken) map[int]string {
+	out := make(map[int]string)
+
+	into := indexOf(ts, 0, "into")
+	if into < 0 || indexOf(ts, 0, "insert") < 0 {
+		return out
+	}
+
+	open := -1
+	for i := into + 1; i < len(ts); i++ {
+		if ts[i].text == "(" {
+			open = i
+			break
+		}
+		if ts[i].is("values") || ts[i].is("select") {
+			return out
+		}
+	}
+	if open < 0 {
+		return out
+	}
+
+	var columns []string
+	i := open + 1
+	for ; i < len(ts) && ts[i].text != ")"; i++ {
+		if name, ok := identName(ts[i]); ok {
+			columns = append(columns, name)
+		}
+	}
+
+	values := indexOf(ts, i, "values")
+	if values < 0 || len(columns) == 0 {
+		return out
+	}
+
+	col := 0
+	for i := values + 1; i < len(ts); i++ {
+		t := ts[i]
+		switch {
+		case t.text == "(" && t.depth == ts[values].depth:
+			col = 0
+		case t.text == "," && t.depth == ts[values].depth+1:
+			col++
+		case t.kind == tokParam && t.depth == ts[values].depth+1 && col < len(columns):
+			if pos, err := strconv.Atoi(t.text[1:]); err == nil {
+				out[pos] = columns[col]
+			}
+		case t.depth == ts[values].depth && t.kind == tokWord:
+			// ON CONFLICT / RETURNING — список значений закончился.
+			return out
+		}
+	}
+
+	return out
+}
+
+// formatArgs выводит параметры для логов, обрезая длинные значения.
+func formatArgs(args []any) string {
+	const maxLen = 64
+
+	parts := make([]string, len(args))
+	for i, a := range args {
+		s := fmt.Sprintf("%v", a)
+		if len(s) > maxLen {
+			s = s[:maxLen] + "…"
+		}
+		parts[i] = fmt.Sprintf("$%d=%s", i+1, s)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,67 @@
+package pgfx
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		r    *Redactor
+		ctx  context.Context
+		sql  string
+		args []any
+		want []any
+	}{
+		{
+			name: "comparison with sensitive column",
+			r:    &Redactor{},
+			sql:  `SELECT id FROM users WHERE email = $1 AND password_hash = $2`,
+			args: []any{"a@b.c", "hash"},
+			want: []any{"a@b.c", "***"},
+		},
+		{
+			name: "insert columns",
+			r:    &Redactor{},
+			sql:  `INSERT INTO users (email, api_key) VALUES ($1, $2), ($3, $4) RETURNING id`,
+			args: []any{"a", "k1", "b", "k2"},
+			want: []any{"a", "***", "b", "***"},
+		},
+		{
+			name: "update set",
+			r:    &Redactor{Mask: "[redacted]"},
+			sql:  `UPDATE users SET "Token" = $1 WHERE id = $2`,
+			args: []any{"t", 7},
+			want: []any{"[redacted]", 7},
+		},
+		{
+			name: "positions",
+			r:    &Redactor{Positions: []int{2}, Columns: []string{}},
+			sql:  `SELECT $1, $2`,
+			args: []any{1, 2},
+			want: []any{1, "***"},
+		},
+		{
+			name: "tag masks everything",
+			r:    &Redactor{Tags: map[string][]int{"auth.login": {0}}},
+			ctx:  WithQueryTag(context.Background(), "auth.login"),
+			sql:  `SELECT check_login($1, $2)`,
+			args: []any{"user", "pass"},
+			want: []any{"***", "***"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if got := tt.r.Redact(ctx, tt.sql, tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Redact() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// queryRegistry — реестр именованных SQL-запросов приложения.
+type queryRegistry struct {
+	mu      sync.RWMutex
+	queries map[string]string
+}
+
+var defaultRegistry = &queryRegistry{queries: make(map[string]string)}
+
+// RegisterQuery регистрирует запрос под именем name и возвращает sql без изменений,
+// что позволяет объявлять запросы как переменные пакета:
+//
+//	var getUserSQL = pgfx.RegisterQuery("users.get", `SELECT id, name FROM users WHERE id = $1`)
+//
+// Зарегистрированные запросы можно проверить против живой схемы через VerifyQueries
+// или опцию VerifyQueriesOnStart. Повторная регистрация имени с другим SQL вызывает панику:
+// это почти всегда ошибка копирования.
+func RegisterQuery(name, sql string) string {
+	defaultRegistry.mu.Lock()
+	defer defaultRegistry.mu.Unlock()
+
+	if prev, ok := defaultRegistry.queries[name]; ok && prev != sql {
+		panic(fmt.Sprintf("pgfx: query %q registered twice with different SQL", name))
+	}
+	defaultRegistry.queries[name] = sql
+
+	return sql
+}
+
+// RegisteredQueries возвращает копию реестра: имя → SQL.
+func RegisteredQueries() map[string]string {
+	defaultRegistry.mu.RLock()
+	defer defaultRegistry.mu.RUnlock()
+
+	out := make(map[string]string, len(defaultRegistry.queries))
+	for name, sql := range defaultRegistry.queries {
+		out[name] = sql
+	}
+	return out
+}
+
+// QueryVerificationError перечисляет зарегистрированные запросы, которые не удалось подготовить.
+type QueryVerificationError struct {
+	// Failed — имя запроса → ошибка сервера (опечатка, несуществующая колонка и т.п.).
+	Failed map[string]error
+}
+
+func (e *QueryVerificationError) Error() string {
+	names := make([]string, 0, len(e.Failed))
+	for name := range e.Failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errs := make([]error, len(names))
+	for i, name := range names {
+		errs[i] = fmt.Errorf("%s: %w", name, e.Failed[name])
+	}
+
+	return fmt.Sprintf("%d registered queries failed verification:\n%v", len(names), errors.Join(errs...))
+}
+
+// VerifyQueries подготавливает (PREPARE) каждый зарегистрированный запрос на одном соединении
+// и сразу отбрасывает подготовленный оператор. Сервер при этом разбирает запрос и проверяет
+// его против текущей схемы, но не выполняет его.
+//
+// Возвращает *QueryVerificationError со всеми найденными проблемами.
+func (p *Postgres) VerifyQueries(ctx context.Context) error {
+	conn, err := p.Pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("postgres - VerifyQueries - acquire: %w", err)
+	}
+	defer conn.Release()
+
+	failed := make(map[string]error)
+	for name, sql := range RegisteredQueries() {
+		// Безымянный оператор заменяется следующим Prepare и не требует DEALLOCATE.
+		if _, err := conn.Conn().PgConn().Prepare(ctx, "", sql, nil); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("postgres - VerifyQueries: %w", ctx.Err())
+			}
+			failed[name] = err
+		}
+	}
+
+	if len(failed) > 0 {
+		return &QueryVerificationError{Failed: failed}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ReplayStatement — запрос, выполненный внутри транзакции (см. RecordTransactionReplay).
+type ReplayStatement struct {
+	// Tag — тег запроса (WithQueryTag), CommandTag — ответ сервера ("UPDATE 3").
+	Tag        string
+	CommandTag string
+	// SQL заполняется только при RecordTransactionReplay(true).
+	SQL      string
+	Duration time.Duration
+	Err      error
+}
+
+// TxReplayError — ошибка транзакции вместе с упорядоченным списком выполненных в ней запросов.
+// Оборачивает исходную ошибку, поэтому errors.Is/As продолжают работать.
+type TxReplayError struct {
+	TxName     string
+	Statements []ReplayStatement
+	Err        error
+}
+
+func (e *TxReplayError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v\ntransaction %q replay (%d statements):", e.Err, txDisplayName(e.TxName), len(e.Statements))
+	for i, s := range e.Statements {
+		fmt.Fprintf(&b, "\n  %d. [%s]", i+1, s.Duration.Round(time.Microsecond))
+		if s.Tag != "" {
+			fmt.Fprintf(&b, " %s", s.Tag)
+		}
+		if s.CommandTag != "" {
+			fmt.Fprintf(&b, " (%s)", s.CommandTag)
+		}
+		if s.SQL != "" {
+			fmt.Fprintf(&b, " %s", strings.Join(strings.Fields(s.SQL), " "))
+		}
+		if s.Err != nil {
+			fmt.Fprintf(&b, " error: %v", s.Err)
+		}
+	}
+	return b.String()
+}
+
+func (e *TxReplayError) Unwrap() error {
+	return e.Err
+}
+
+// txReplay накапливает запросы одной транзакции.
+type txReplay struct {
+	withSQL    bool
+	mu         sync.Mutex
+	statements []ReplayStatement
+}
+
+type txReplayKey struct{}
+
+func (r *txReplay) snapshot() []ReplayStatement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]ReplayStatement, len(r.statements))
+	copy(out, r.statements)
+	return out
+}
+
+// replayTracer записывает запросы в txReplay из контекста; вне транзакций с записью ничего не делает.
+type replayTracer struct{}
+
+type replayStartKey struct{}
+
+type replayStart struct {
+	start time.Time
+	sql   string
+}
+
+func (replayTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if _, ok := ctx.Value(txReplayKey{}).(*txReplay); !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, replayStartKey{}, replayStart{start: time.Now(), sql: data.SQL})
+}
+
+func (replayTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	r, ok := ctx.Value(txReplayKey{}).(*txReplay)
+	if !ok {
+		return
+	}
+	start, ok := ctx.Value(replayStartKey{}).(replayStart)
+	if !ok {
+		return
+	}
+
+	s := ReplayStatement{
+		Tag:        QueryTag(ctx),
+		CommandTag: data.CommandTag.String(),
+		Duration:   time.Since(start.start),
+		Err:        data.Err,
+	}
+	if r.withSQL {
+		s.SQL = start.sql
+	}
+
+	r.mu.Lock()
+	r.statements = append(r.statements, s)
+	r.mu.Unlock()
+}
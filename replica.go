@@ -0,0 +1,45 @@
+package pgfx
+
+import (
+	"context"
+
+	pgxdecimal "github.com/jackc/pgx-shopspring-decimal"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pgxuuid "github.com/vgarvardt/pgx-google-uuid/v5"
+)
+
+// connectReplica создаёт пул реплики с настройками основного пула primary.
+// Соединения реплики не попадают в backends: CancelAll работает только с основным сервером.
+func (p *Postgres) connectReplica(primary *pgxpool.Config) error {
+	cfg, err := pgxpool.ParseConfig(p.replicaConnStr)
+	if err != nil {
+		return err
+	}
+
+	cfg.MaxConns = primary.MaxConns
+	cfg.ConnConfig.ConnectTimeout = primary.ConnConfig.ConnectTimeout
+	cfg.ConnConfig.Tracer = primary.ConnConfig.Tracer
+	cfg.BeforeConnect = primary.BeforeConnect
+	cfg.AfterConnect = func(_ context.Context, conn *pgx.Conn) error {
+		if p.decimal {
+			pgxdecimal.Register(conn.TypeMap())
+		}
+		if p.uuid {
+			pgxuuid.Register(conn.TypeMap())
+		}
+		return nil
+	}
+
+	p.replica, err = pgxpool.NewWithConfig(context.Background(), cfg)
+	return err
+}
+
+// replicaTransactor возвращает транзактор с настройками TransactionalPool, работающий через реплику.
+// Очередь приоритетов не используется: она рассчитана на размер основного пула.
+func (p *Postgres) replicaTransactor() pgTransactor {
+	t, _ := p.TransactionalPool.(pgTransactor)
+	t.dbc = p.replica
+	t.gate = nil
+	return t
+}
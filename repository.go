@@ -0,0 +1,442 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrNoPrimaryKey возвращается NewRepository, если у типа нет полей с тегом pgfx:"pk".
+	ErrNoPrimaryKey = errors.New("no primary key fields")
+	// ErrNoKeyring возвращается NewRepository, если у типа есть поля pgfx:"encrypted",
+	// а Keyring не передан через WithEncryption.
+	ErrNoKeyring = errors.New("encrypted fields require a keyring")
+)
+
+// RepositoryOption настраивает Repository.
+type RepositoryOption func(*repositoryOptions)
+
+type repositoryOptions struct {
+	keyring *Keyring
+}
+
+// WithEncryption включает клиентское шифрование полей с тегом pgfx:"encrypted".
+//
+// Такие поля (string или []byte, колонка bytea) шифруются AES-GCM через keyring перед
+// передачей в базу и расшифровываются при чтении; база видит только шифротекст.
+// По зашифрованным колонкам нельзя фильтровать и сортировать.
+//
+// Значение привязано к таблице (в том виде, в каком она передана в NewRepository) и колонке:
+// шифротекст, скопированный в другую колонку или таблицу, не расшифруется. К строке значение
+// не привязано — его можно переставить в другую строку той же колонки.
+func WithEncryption(keyring *Keyring) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.keyring = keyring
+	}
+}
+
+// Repository — обобщённый CRUD-репозиторий для простых таблиц.
+//
+// Колонки описываются тегами структуры: db — имя колонки (как в pgx.RowToStructByName,
+// по умолчанию имя поля в snake_case), pgfx:"pk" — поле первичного ключа,
+// pgfx:"auto" — значение формирует база, поле не передаётся в INSERT и UPDATE,
+// pgfx:"encrypted" — поле шифруется на клиенте (см. WithEncryption).
+// Все запросы выполняются через db, поэтому при использовании TransactionalPool
+// репозиторий автоматически участвует в транзакциях TxManager.
+//
+// Пример:
+//
+//	type User struct {
+//	    ID    int64  `db:"id" pgfx:"pk,auto"`
+//	    Email string `db:"email"`
+//	}
+//
+//	users, err := pgfx.NewRepository[User](pg.TransactionalPool, "users")
+//	u := &User{Email: "a@b.c"}
+//	err = users.Insert(ctx, u) // u.ID заполнен из RETURNING
+type Repository[T any] struct {
+	db      QueryExecutor
+	table   string
+	meta    *structMeta
+	pk      []structField
+	keyring *Keyring
+	types   *columnTypes
+}
+
+// NewRepository создаёт репозиторий для таблицы table.
+func NewRepository[T any](db QueryExecutor, table string, opts ...RepositoryOption) (*Repository[T], error) {
+	var o repositoryOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	meta, err := structMetaOf(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, fmt.Errorf("repository - %s: %w", table, err)
+	}
+
+	pk := meta.primaryKey()
+	if len(pk) == 0 {
+		return nil, fmt.Errorf("repository - %s: %w", table, ErrNoPrimaryKey)
+	}
+
+	for _, f := range meta.fields {
+		if !f.has("encrypted") {
+			continue
+		}
+		if o.keyring == nil {
+			return nil, fmt.Errorf("repository - %s: field %s: %w", table, f.column, ErrNoKeyring)
+		}
+		if f.typ.Kind() != reflect.String && f.typ != reflect.TypeFor[[]byte]() {
+			return nil, fmt.Errorf("repository - %s: encrypted field %s must be string or []byte, got %s", table, f.column, f.typ)
+		}
+	}
+
+	return &Repository[T]{
+		db:      db,
+		table:   quoteTable(table),
+		meta:    meta,
+		pk:      pk,
+		keyring: o.keyring,
+		types:   &columnTypes{},
+	}, nil
+}
+
+// GetByID возвращает строку по первичному ключу (значения в порядке полей pk)
+// или pgx.ErrNoRows, если её нет.
+func (r *Repository[T]) GetByID(ctx context.Context, id ...any) (T, error) {
+	var zero T
+
+	where, err := r.pkCondition(id, 1)
+	if err != nil {
+		return zero, err
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE %s`, columnList(r.meta.fields), r.table, where)
+
+	rows, err := r.db.Query(ctx, query, id...)
+	if err != nil {
+		return zero, fmt.Errorf("repository - GetByID: %w", err)
+	}
+
+	v, err := pgx.CollectOneRow(rows, r.scanRow)
+	if err != nil {
+		return zero, fmt.Errorf("repository - GetByID: %w", err)
+	}
+	return v, nil
+}
+
+// Insert вставляет v и заполняет его значениями, возвращёнными базой (RETURNING),
+// включая колонки с флагом auto.
+func (r *Repository[T]) Insert(ctx context.Context, v *T) error {
+	fields := r.meta.writable()
+	placeholders := make([]string, len(fields))
+	for i := range fields {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s) RETURNING %s`,
+		r.table, columnList(fields), strings.Join(placeholders, ", "), columnList(r.meta.fields))
+
+	values, err := r.values(reflect.ValueOf(v).Elem(), fields)
+	if err != nil {
+		return fmt.Errorf("repository - Insert: %w", err)
+	}
+
+	if err := r.queryInto(ctx, v, query, values...); err != nil {
+		return fmt.Errorf("repository - Insert: %w", err)
+	}
+	return nil
+}
+
+// Update обновляет все колонки, кроме первичного ключа и auto, у строки с ключом из v,
+// и перечитывает v из RETURNING. Если строки нет, возвращается pgx.ErrNoRows.
+func (r *Repository[T]) Update(ctx context.Context, v *T) error {
+	fields := r.meta.filter(func(f structField) bool { return !f.has("pk") && !f.has("auto") })
+	if len(fields) == 0 {
+		return nil
+	}
+
+	rv := reflect.ValueOf(v).Elem()
+	sets := make([]string, len(fields))
+	for i, f := range fields {
+		sets[i] = fmt.Sprintf("%s = $%d", quoteIdent(f.column), i+1)
+	}
+
+	pkValues := fieldValues(rv, r.pk)
+	where, err := r.pkCondition(pkValues, len(fields)+1)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET %s WHERE %s RETURNING %s`,
+		r.table, strings.Join(sets, ", "), where, columnList(r.meta.fields))
+
+	values, err := r.values(rv, fields)
+	if err != nil {
+		return fmt.Errorf("repository - Update: %w", err)
+	}
+
+	if err := r.queryInto(ctx, v, query, append(values, pkValues...)...); err != nil {
+		return fmt.Errorf("repository - Update: %w", err)
+	}
+	return nil
+}
+
+// Delete удаляет строку по первичному ключу. Если строки нет, возвращается pgx.ErrNoRows.
+func (r *Repository[T]) Delete(ctx context.Context, id ...any) error {
+	where, err := r.pkCondition(id, 1)
+	if err != nil {
+		return err
+	}
+
+	tag, err := r.db.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE %s`, r.table, where), id...)
+	if err != nil {
+		return fmt.Errorf("repository - Delete: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("repository - Delete: %w", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// ListOptions — сортировка и пагинация для Repository.List.
+type ListOptions struct {
+	// OrderBy — колонки сортировки, например "created_at DESC". Подставляются в SQL как есть,
+	// поэтому не должны приходить из пользовательского ввода.
+	OrderBy []string
+	Limit   int
+	Offset  int
+}
+
+// List возвращает строки, удовлетворяющие filter.
+//
+// filter — nil или структура, поля которой описывают условия (объединяются через AND):
+// указатели и срезы учитываются, только если не nil; срез превращается в "= ANY(...)",
+// а с op=ne — в "<> ALL(...)" (значение не входит в список). Поля других типов — ошибка.
+// Колонка берётся из тега db, оператор — из тега pgfx:"op=..." (eq, ne, lt, lte, gt, gte, like, ilike,
+// а для диапазонов и массивов — contains, contained, overlaps):
+//
+//	type UserFilter struct {
+//	    Email   *string    `db:"email"`
+//	    IDs     []int64    `db:"id"`
+//	    Created *time.Time `db:"created_at" pgfx:"op=gte"`
+//	}
+func (r *Repository[T]) List(ctx context.Context, filter any, opts ListOptions) ([]T, error) {
+	where, args, err := buildFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("repository - List: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM %s`, columnList(r.meta.fields), r.table)
+	if where != "" {
+		query += " WHERE " + where
+	}
+	if len(opts.OrderBy) > 0 {
+		query += " ORDER BY " + strings.Join(opts.OrderBy, ", ")
+	}
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if opts.Offset > 0 {
+		args = append(args, opts.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository - List: %w", err)
+	}
+
+	items, err := pgx.CollectRows(rows, r.scanRow)
+	if err != nil {
+		return nil, fmt.Errorf("repository - List: %w", err)
+	}
+	return items, nil
+}
+
+func (r *Repository[T]) pkCondition(id []any, first int) (string, error) {
+	if len(id) != len(r.pk) {
+		return "", fmt.Errorf("repository: expected %d primary key values, got %d", len(r.pk), len(id))
+	}
+
+	conds := make([]string, len(r.pk))
+	for i, f := range r.pk {
+		conds[i] = fmt.Sprintf("%s = $%d", quoteIdent(f.column), first+i)
+	}
+	return strings.Join(conds, " AND "), nil
+}
+
+func (r *Repository[T]) queryInto(ctx context.Context, v *T, query string, args ...any) error {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	res, err := pgx.CollectOneRow(rows, r.scanRow)
+	if err != nil {
+		return err
+	}
+
+	*v = res
+	return nil
+}
+
+// values возвращает значения полей для передачи в запрос, шифруя поля pgfx:"encrypted".
+func (r *Repository[T]) values(rv reflect.Value, fields []structField) ([]any, error) {
+	values := fieldValues(rv, fields)
+	for i, f := range fields {
+		if !f.has("encrypted") {
+			continue
+		}
+
+		var plaintext []byte
+		switch v := rv.FieldByIndex(f.index); v.Kind() {
+		case reflect.String:
+			plaintext = []byte(v.String())
+		default:
+			if v.IsNil() {
+				values[i] = nil
+				continue
+			}
+			plaintext = v.Bytes()
+		}
+
+		ciphertext, err := r.keyring.EncryptWithAAD(plaintext, r.encryptionAAD(f))
+		if err != nil {
+			return nil, fmt.Errorf("encrypt %s: %w", f.column, err)
+		}
+		values[i] = ciphertext
+	}
+	return values, nil
+}
+
+// encryptionAAD — дополнительные данные шифрования поля f: таблица и колонка.
+func (r *Repository[T]) encryptionAAD(f structField) []byte {
+	return []byte(r.table + "." + quoteIdent(f.column))
+}
+
+// scanRow сканирует строку, выбранную списком колонок columnList(r.meta.fields),
+// и расшифровывает поля pgfx:"encrypted".
+func (r *Repository[T]) scanRow(row pgx.CollectableRow) (T, error) {
+	var v T
+	rv := reflect.ValueOf(&v).Elem()
+
+	targets := make([]any, len(r.meta.fields))
+	encrypted := make(map[int]*[]byte)
+	for i, f := range r.meta.fields {
+		if f.has("encrypted") {
+			buf := new([]byte)
+			encrypted[i] = buf
+			targets[i] = buf
+			continue
+		}
+		targets[i] = rv.FieldByIndex(f.index).Addr().Interface()
+	}
+
+	if err := row.Scan(targets...); err != nil {
+		return v, err
+	}
+
+	for i, buf := range encrypted {
+		if *buf == nil {
+			continue
+		}
+
+		f := r.meta.fields[i]
+		plaintext, err := r.keyring.DecryptWithAAD(*buf, r.encryptionAAD(f))
+		if err != nil {
+			return v, fmt.Errorf("decrypt %s: %w", f.column, err)
+		}
+
+		fv := rv.FieldByIndex(f.index)
+		if fv.Kind() == reflect.String {
+			fv.SetString(string(plaintext))
+		} else {
+			fv.SetBytes(plaintext)
+		}
+	}
+
+	return v, nil
+}
+
+var _filterOps = map[string]string{
+	"":      "=",
+	"eq":    "=",
+	"ne":    "<>",
+	"lt":    "<",
+	"lte":   "<=",
+	"gt":    ">",
+	"gte":   ">=",
+	"like":  "LIKE",
+	"ilike": "ILIKE",
+	// Операторы диапазонов и массивов.
+	"contains":  "@>",
+	"contained": "<@",
+	"overlaps":  "&&",
+}
+
+// _containerOps — операторы, правый операнд которых — диапазон или массив целиком.
+var _containerOps = map[string]bool{"@>": true, "<@": true, "&&": true}
+
+func buildFilter(filter any) (string, []any, error) {
+	rv := reflect.ValueOf(filter)
+	if filter == nil || rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return "", nil, nil
+	}
+	rv = reflect.Indirect(rv)
+	meta, err := structMetaOf(rv.Type())
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		conds []string
+		args  []any
+	)
+
+	for _, f := range meta.fields {
+		fv := rv.FieldByIndex(f.index)
+
+		op, ok := _filterOps[f.flags["op"]]
+		if !ok {
+			return "", nil, fmt.Errorf("filter field %s: unknown op %q", f.column, f.flags["op"])
+		}
+
+		switch fv.Kind() {
+		case reflect.Pointer:
+			if fv.IsNil() {
+				continue
+			}
+			args = append(args, fv.Elem().Interface())
+			conds = append(conds, fmt.Sprintf("%s %s $%d", quoteIdent(f.column), op, len(args)))
+		case reflect.Slice:
+			if fv.IsNil() {
+				continue
+			}
+			args = append(args, fv.Interface())
+			if _containerOps[op] {
+				// Срез — сам операнд оператора массива: tags @> $1.
+				conds = append(conds, fmt.Sprintf("%s %s $%d", quoteIdent(f.column), op, len(args)))
+				continue
+			}
+			quantifier := "ANY"
+			if op == "<>" {
+				// col <> ANY(...) истинно, если отличается хотя бы один элемент.
+				quantifier = "ALL"
+			}
+			conds = append(conds, fmt.Sprintf("%s %s %s($%d)", quoteIdent(f.column), op, quantifier, len(args)))
+		default:
+			// Обычное поле нельзя "не задать", а молча пропущенное условие вернуло бы все строки.
+			return "", nil, fmt.Errorf("filter field %s: must be a pointer or a slice, got %s", f.column, fv.Type())
+		}
+	}
+
+	return strings.Join(conds, " AND "), args, nil
+}
@@ -0,0 +1,46 @@
+package pgfx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildFilter(t *testing.T) {
+	status := "new"
+	type filter struct {
+		IDs      []int64  `db:"id"`
+		Excluded []int64  `db:"owner_id" pgfx:"op=ne"`
+		Status   *string  `db:"status" pgfx:"op=ne"`
+		Tags     []string `db:"tags" pgfx:"op=contains"`
+	}
+
+	where, args, err := buildFilter(filter{IDs: []int64{1, 2}, Excluded: []int64{3}, Status: &status, Tags: []string{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantWhere := `"id" = ANY($1) AND "owner_id" <> ALL($2) AND "status" <> $3 AND "tags" @> $4`
+	if where != wantWhere {
+		t.Errorf("where = %s, want %s", where, wantWhere)
+	}
+	wantArgs := []any{[]int64{1, 2}, []int64{3}, "new", []string{"a"}}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("args = %v, want %v", args, wantArgs)
+	}
+}
+
+func TestBuildFilterInvalid(t *testing.T) {
+	type filter struct {
+		Status *string `db:"status"`
+	}
+	if where, args, err := buildFilter((*filter)(nil)); err != nil || where != "" || args != nil {
+		t.Errorf("nil pointer filter: got %q, %v, %v", where, args, err)
+	}
+
+	type plain struct {
+		ID int `db:"id"`
+	}
+	if _, _, err := buildFilter(plain{ID: 1}); err == nil {
+		t.Error("plain field: want error")
+	}
+}
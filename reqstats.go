@@ -0,0 +1,119 @@
+package pgfx
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RequestStats собирает статистику запросов к базе в рамках одной операции (обычно HTTP-запроса).
+// Создаётся WithRequestStats; запросы учитываются, если выполнены с производным контекстом
+// через любой пул или транзакцию pgfx.
+type RequestStats struct {
+	mu      sync.Mutex
+	queries int64
+	errors  int64
+	rows    int64
+	dbTime  time.Duration
+	bySQL   map[string]int64
+	nplus1  map[string]*nPlusOneState
+}
+
+// RequestStatsSummary — снимок RequestStats.
+type RequestStatsSummary struct {
+	// Queries — число запросов, Errors — сколько из них завершились ошибкой.
+	Queries int64
+	Errors  int64
+	// Rows — строки, возвращённые или затронутые запросами (по CommandTag).
+	Rows int64
+	// DBTime — суммарное время выполнения запросов.
+	DBTime time.Duration
+	// BySQL — сколько раз выполнялся каждый текст запроса.
+	BySQL map[string]int64
+}
+
+type requestStatsKey struct{}
+
+// WithRequestStats возвращает контекст, запросы с которым учитываются в возвращённом RequestStats.
+func WithRequestStats(ctx context.Context) (context.Context, *RequestStats) {
+	s := &RequestStats{bySQL: make(map[string]int64)}
+	return context.WithValue(ctx, requestStatsKey{}, s), s
+}
+
+// RequestStatsFromContext возвращает RequestStats из контекста или nil.
+func RequestStatsFromContext(ctx context.Context) *RequestStats {
+	s, _ := ctx.Value(requestStatsKey{}).(*RequestStats)
+	return s
+}
+
+// Summary возвращает текущую статистику.
+func (s *RequestStats) Summary() RequestStatsSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bySQL := make(map[string]int64, len(s.bySQL))
+	for sql, n := range s.bySQL {
+		bySQL[sql] = n
+	}
+	return RequestStatsSummary{Queries: s.queries, Errors: s.errors, Rows: s.rows, DBTime: s.dbTime, BySQL: bySQL}
+}
+
+func (s *RequestStats) record(sql string, elapsed time.Duration, rows int64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queries++
+	s.rows += rows
+	s.dbTime += elapsed
+	s.bySQL[sql]++
+	if err != nil {
+		s.errors++
+	}
+}
+
+// RequestStatsMiddleware включает RequestStats для каждого HTTP-запроса и после его обработки
+// вызывает report, например чтобы залогировать "this endpoint made 47 queries":
+//
+//	mux := pgfx.RequestStatsMiddleware(func(r *http.Request, s pgfx.RequestStatsSummary) {
+//	    if s.Queries > 20 {
+//	        log.Printf("%s %s: %d queries, %s in db", r.Method, r.URL.Path, s.Queries, s.DBTime)
+//	    }
+//	})(mux)
+func RequestStatsMiddleware(report func(r *http.Request, s RequestStatsSummary)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, stats := WithRequestStats(r.Context())
+			r = r.WithContext(ctx)
+			next.ServeHTTP(w, r)
+			report(r, stats.Summary())
+		})
+	}
+}
+
+// requestStatsTracer учитывает запросы в RequestStats из контекста.
+type requestStatsTracer struct{}
+
+type requestStatsStartKey struct{}
+
+func (requestStatsTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if RequestStatsFromContext(ctx) == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, requestStatsStartKey{}, queryStart{at: time.Now(), sql: data.SQL})
+}
+
+func (requestStatsTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(requestStatsStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	RequestStatsFromContext(ctx).record(start.sql, time.Since(start.at), data.CommandTag.RowsAffected(), data.Err)
+}
+
+type queryStart struct {
+	at  time.Time
+	sql string
+}
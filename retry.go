@@ -0,0 +1,87 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	_retryBaseDelay = 50 * time.Millisecond
+	_retryMaxDelay  = 5 * time.Second
+)
+
+// retryBackoff возвращает _retryBaseDelay·2^n, но не больше _retryMaxDelay.
+func retryBackoff(n int) time.Duration {
+	if n < 0 {
+		n = 0
+	}
+	if d := _retryMaxDelay >> n; d < _retryBaseDelay {
+		return _retryMaxDelay
+	}
+	return _retryBaseDelay << n
+}
+
+// ReadCommittedRetry выполняет f в транзакции READ COMMITTED, как ReadCommitted, и при
+// ошибке соединения (обрыв, рестарт сервера, отказ в подключении) запускает весь обработчик
+// заново в новой транзакции — всего не более attempts раз, с экспоненциальной паузой между попытками.
+//
+// Используйте только для идемпотентных обработчиков: если соединение оборвалось во время COMMIT,
+// транзакция могла успеть зафиксироваться, и f будет выполнен повторно.
+//
+// Внутри уже активной транзакции повтор невозможен, поэтому f выполняется один раз.
+func (m *Manager) ReadCommittedRetry(ctx context.Context, f func(ctx context.Context) error, attempts int, opts ...TxOption) error {
+	if _, ok := txFrom(ctx, m.key); ok || attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if m.metrics != nil {
+				m.metrics.TxRetried(newTxConfig(opts).name, "connection")
+			}
+			delay := retryBackoff(attempt - 1)
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("retry aborted after %d attempts: %w (last error: %w)", attempt, ctx.Err(), err)
+			case <-time.After(delay):
+			}
+		}
+
+		err = m.ReadCommitted(ctx, f, opts...)
+		if err == nil || !isConnectionError(err) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+}
+
+// isConnectionError сообщает, вызвана ли ошибка потерей или отсутствием соединения с сервером.
+func isConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// 08xxx — connection exception; 57P01..57P03 — завершение/перезапуск сервера.
+		return strings.HasPrefix(pgErr.Code, "08") ||
+			pgErr.Code == "57P01" || pgErr.Code == "57P02" || pgErr.Code == "57P03"
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
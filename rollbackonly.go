@@ -0,0 +1,39 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrRollbackOnly возвращается TxManager, если транзакция была помечена SetRollbackOnly:
+// она откатывается, хотя обработчик завершился без ошибки.
+var ErrRollbackOnly = errors.New("transaction was marked rollback-only")
+
+type rollbackOnlyKey struct{}
+
+// withRollbackOnly добавляет в контекст новой транзакции пометку "только откат".
+func withRollbackOnly(ctx context.Context) (context.Context, *atomic.Bool) {
+	flag := &atomic.Bool{}
+	return context.WithValue(ctx, rollbackOnlyKey{}, flag), flag
+}
+
+// SetRollbackOnly помечает внешнюю транзакцию TxManager для отката: когда обработчик завершится,
+// транзакция будет откачена, даже если он вернул nil, а TxManager вернёт ErrRollbackOnly.
+// Вызов из savepoint (NestSavepoint) помечает всю внешнюю транзакцию. Помогает глубоко вложенному
+// коду отменить всю операцию, не протаскивая ошибку через каждый уровень, например в dry-run.
+// Вне транзакции TxManager возвращает ErrNoTransaction.
+func SetRollbackOnly(ctx context.Context) error {
+	flag, ok := ctx.Value(rollbackOnlyKey{}).(*atomic.Bool)
+	if !ok {
+		return ErrNoTransaction
+	}
+	flag.Store(true)
+	return nil
+}
+
+// IsRollbackOnly сообщает, помечена ли транзакция из контекста для отката (см. SetRollbackOnly).
+func IsRollbackOnly(ctx context.Context) bool {
+	flag, ok := ctx.Value(rollbackOnlyKey{}).(*atomic.Bool)
+	return ok && flag.Load()
+}
@@ -0,0 +1,71 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrTooManyRows — базовая ошибка превышения лимита строк, с ней можно сравнивать через errors.Is.
+var ErrTooManyRows = errors.New("too many rows")
+
+// RowLimitError возвращается из rows.Err(), если запрос вернул больше строк,
+// чем разрешено MaxQueryRows или WithMaxRows.
+type RowLimitError struct {
+	Limit int
+}
+
+func (e *RowLimitError) Error() string {
+	return fmt.Sprintf("query returned more than %d rows", e.Limit)
+}
+
+func (e *RowLimitError) Is(target error) bool {
+	return target == ErrTooManyRows
+}
+
+type maxRowsKey struct{}
+
+// WithMaxRows задаёт лимит строк для запросов, выполняемых с этим контекстом,
+// переопределяя глобальный MaxQueryRows. 0 снимает ограничение.
+func WithMaxRows(ctx context.Context, limit int) context.Context {
+	return context.WithValue(ctx, maxRowsKey{}, limit)
+}
+
+func maxRowsFromContext(ctx context.Context, def int) int {
+	if limit, ok := ctx.Value(maxRowsKey{}).(int); ok {
+		return limit
+	}
+	return def
+}
+
+// limitedRows прекращает чтение, как только количество строк превысило limit.
+type limitedRows struct {
+	pgx.Rows
+	limit int
+	n     int
+	err   error
+}
+
+func (r *limitedRows) Next() bool {
+	if r.err != nil || !r.Rows.Next() {
+		return false
+	}
+
+	r.n++
+	if r.n > r.limit {
+		r.err = &RowLimitError{Limit: r.limit}
+		r.Rows.Close()
+		return false
+	}
+
+	return true
+}
+
+func (r *limitedRows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.Rows.Err()
+}
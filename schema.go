@@ -0,0 +1,103 @@
+package pgfx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// _schemaLockKey — ключ advisory-блокировки EnsureSchema ("pgfx_schema").
+const _schemaLockKey = `SELECT pg_advisory_xact_lock(hashtext('pgfx_schema'))`
+
+const _schemaFilesTable = `CREATE TABLE IF NOT EXISTS pgfx_schema_files (
+	name       TEXT PRIMARY KEY,
+	checksum   TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+// EnsureSchema выполняет идемпотентный DDL начальной настройки схемы (CREATE TABLE IF NOT EXISTS,
+// CREATE EXTENSION IF NOT EXISTS и т.п.) из файлов *.sql в ddl — обычно embed.FS — для сервисов,
+// которым не нужны полноценные миграции:
+//
+//	//go:embed schema/*.sql
+//	var schemaFS embed.FS
+//
+//	err := pg.EnsureSchema(ctx, schemaFS)
+//
+// Файлы выполняются по алфавиту (в порядке fs.WalkDir) в одной транзакции под advisory-блокировкой,
+// поэтому одновременно стартующие реплики сервиса не мешают друг другу. Контрольная сумма каждого
+// файла сохраняется в pgfx_schema_files: неизменённые файлы пропускаются, изменённый выполняется
+// заново — поэтому DDL должен быть идемпотентным. Команды, недопустимые в транзакции
+// (CREATE INDEX CONCURRENTLY), не поддерживаются.
+func (p *Postgres) EnsureSchema(ctx context.Context, ddl fs.FS) error {
+	var files []string
+	err := fs.WalkDir(ddl, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && path.Ext(name) == ".sql" {
+			files = append(files, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("postgres - EnsureSchema - read files: %w", err)
+	}
+
+	// Напрямую через пул: переписчики запросов TransactionalPool не должны трогать DDL.
+	tx, err := p.Pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("postgres - EnsureSchema - begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, _schemaLockKey); err != nil {
+		return fmt.Errorf("postgres - EnsureSchema - lock: %w", err)
+	}
+	if _, err := tx.Exec(ctx, _schemaFilesTable); err != nil {
+		return fmt.Errorf("postgres - EnsureSchema - create table: %w", err)
+	}
+
+	for _, name := range files {
+		body, err := fs.ReadFile(ddl, name)
+		if err != nil {
+			return fmt.Errorf("postgres - EnsureSchema - %s: %w", name, err)
+		}
+		sum := sha256.Sum256(body)
+		checksum := hex.EncodeToString(sum[:])
+
+		var applied string
+		err = tx.QueryRow(ctx, `SELECT checksum FROM pgfx_schema_files WHERE name = $1`, name).Scan(&applied)
+		switch {
+		case err == nil && applied == checksum:
+			continue
+		case err == nil:
+			log.Printf("pgfx: schema file %s changed, applying again", name)
+		case !errors.Is(err, pgx.ErrNoRows):
+			return fmt.Errorf("postgres - EnsureSchema - %s: %w", name, err)
+		}
+
+		// Без параметров Exec использует простой протокол, поэтому файл может содержать несколько команд.
+		if _, err := tx.Exec(ctx, string(body)); err != nil {
+			return fmt.Errorf("postgres - EnsureSchema - %s: %w", name, err)
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO pgfx_schema_files (name, checksum) VALUES ($1, $2)
+			ON CONFLICT (name) DO UPDATE SET checksum = EXCLUDED.checksum, applied_at = now()`,
+			name, checksum); err != nil {
+			return fmt.Errorf("postgres - EnsureSchema - %s: record checksum: %w", name, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("postgres - EnsureSchema - commit: %w", err)
+	}
+	return nil
+}
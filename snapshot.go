@@ -0,0 +1,101 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ExportSnapshot экспортирует снимок данных текущей транзакции db (pg_export_snapshot)
+// и возвращает его идентификатор. Снимок можно импортировать в другие транзакции через
+// Manager.ImportSnapshot, пока экспортировавшая транзакция открыта.
+//
+// Транзакция должна быть REPEATABLE READ или SERIALIZABLE, иначе каждый запрос видит свой снимок;
+// обычно удобнее Manager.ShareSnapshot.
+func ExportSnapshot(ctx context.Context, db QueryExecutor) (string, error) {
+	tx, ok := txFrom(ctx, executorTxKey(db))
+	if !ok {
+		return "", fmt.Errorf("export snapshot: %w", ErrNoTransaction)
+	}
+	return exportSnapshot(ctx, tx)
+}
+
+func exportSnapshot(ctx context.Context, tx pgx.Tx) (string, error) {
+	var id string
+	if err := tx.QueryRow(ctx, `SELECT pg_export_snapshot()`).Scan(&id); err != nil {
+		return "", fmt.Errorf("export snapshot: %w", err)
+	}
+	return id, nil
+}
+
+// ShareSnapshot открывает транзакцию REPEATABLE READ READ ONLY, экспортирует её снимок и вызывает f
+// с его идентификатором. Снимок действителен, пока выполняется f, поэтому f должен дождаться
+// завершения всех воркеров, импортирующих снимок:
+//
+//	err := tm.ShareSnapshot(ctx, func(ctx context.Context, snapshot string) error {
+//	    g, gctx := errgroup.WithContext(context.Background())
+//	    for _, part := range parts {
+//	        g.Go(func() error {
+//	            return tm.ImportSnapshot(gctx, snapshot, func(ctx context.Context) error {
+//	                return dumpPart(ctx, part)
+//	            })
+//	        })
+//	    }
+//	    return g.Wait()
+//	})
+//
+// Воркеры должны получать контекст без транзакции экспорта (как gctx выше), иначе
+// ImportSnapshot присоединится к ней, а не начнёт свою.
+func (m *Manager) ShareSnapshot(ctx context.Context, f func(ctx context.Context, snapshot string) error, opts ...TxOption) error {
+	if _, ok := txFrom(ctx, m.key); ok {
+		return errors.New("share snapshot: already inside a transaction")
+	}
+
+	txOpts := pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+	return m.transaction(ctx, txOpts, newTxConfig(opts), func(ctx context.Context) error {
+		tx, _ := txFrom(ctx, m.key)
+		id, err := exportSnapshot(ctx, tx)
+		if err != nil {
+			return err
+		}
+		return f(ctx, id)
+	})
+}
+
+// ImportSnapshot выполняет f в транзакции REPEATABLE READ READ ONLY, видящей те же данные,
+// что и транзакция, экспортировавшая снимок snapshot (SET TRANSACTION SNAPSHOT).
+func (m *Manager) ImportSnapshot(ctx context.Context, snapshot string, f func(ctx context.Context) error, opts ...TxOption) error {
+	if _, ok := txFrom(ctx, m.key); ok {
+		return errors.New("import snapshot: already inside a transaction")
+	}
+
+	cfg := newTxConfig(opts)
+	cfg.init = func(ctx context.Context, tx pgx.Tx) error {
+		// SET TRANSACTION не принимает параметры, поэтому идентификатор передаётся литералом.
+		_, err := tx.Exec(ctx, "SET TRANSACTION SNAPSHOT "+quoteLiteral(snapshot))
+		return err
+	}
+
+	txOpts := pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+	return m.transaction(ctx, txOpts, cfg, f)
+}
+
+// Snapshot выполняет fn в транзакции REPEATABLE READ READ ONLY на реплике (см. WithReplica),
+// а без неё — на основном сервере. Все запросы fn через TransactionalPool видят один
+// согласованный снимок данных, поэтому многозапросные отчёты не расходятся между собой
+// из-за параллельных изменений.
+func (p *Postgres) Snapshot(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	if _, ok := p.TxFromContext(ctx); ok {
+		return errors.New("snapshot: already inside a transaction")
+	}
+
+	m := p.NewTransactionManager()
+	if p.replica != nil {
+		m.db = p.replicaTransactor()
+	}
+
+	txOpts := pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+	return m.transaction(ctx, txOpts, newTxConfig(opts), fn)
+}
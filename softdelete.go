@@ -0,0 +1,273 @@
+package pgfx
+
+import (
+	"context"
+	"strings"
+)
+
+// QueryRewriter переписывает SQL перед выполнением через TransactionalPool.
+// Должен возвращать исходную строку, если запрос его не касается.
+type QueryRewriter func(ctx context.Context, sql string) string
+
+// WithQueryRewriter добавляет переписчик запросов. Переписчики применяются в порядке
+// добавления к Exec, Query и QueryRow, выполняемым через TransactionalPool;
+// запросы напрямую через Pool не переписываются.
+func WithQueryRewriter(rewriter QueryRewriter) Option {
+	return func(p *Postgres) {
+		p.rewriters = append(p.rewriters, rewriter)
+	}
+}
+
+// WithSoftDelete включает "мягкое удаление" для tables: строки помечаются временем
+// удаления в колонке column вместо физического удаления.
+//
+//   - SELECT, читающий зарегистрированную таблицу, получает условие column IS NULL
+//     (в WHERE для таблиц из FROM и внутренних соединений, в ON — для JOIN ... ON);
+//   - DELETE FROM table превращается в UPDATE table SET column = now(), затрагивающий
+//     только ещё не удалённые строки; RETURNING и USING сохраняются.
+//
+// Переписываются только запросы верхнего уровня (включая ветки UNION); подзапросы,
+// CTE (WITH ...) и внешние соединения через USING остаются как есть. Чтобы прочитать
+// или физически удалить строки, используйте контекст IncludeDeleted.
+//
+// Имена таблиц можно указывать со схемой ("public.users") или без неё.
+func WithSoftDelete(column string, tables ...string) Option {
+	sd := &softDelete{
+		column: quoteIdent(column),
+		tables: make(map[string]struct{}, len(tables)),
+	}
+	for _, t := range tables {
+		sd.tables[strings.ToLower(t)] = struct{}{}
+	}
+
+	return WithQueryRewriter(sd.rewrite)
+}
+
+type includeDeletedKey struct{}
+
+// IncludeDeleted возвращает контекст, в котором запросы не переписываются WithSoftDelete:
+// SELECT видит удалённые строки, а DELETE удаляет физически.
+func IncludeDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, includeDeletedKey{}, true)
+}
+
+type softDelete struct {
+	column string
+	tables map[string]struct{}
+}
+
+// _selectClauses — ключевые слова, которые завершают FROM и WHERE в SELECT.
+var _selectClauses = []string{"where", "group", "having", "window", "order", "limit", "offset", "fetch", "for"}
+
+func (s *softDelete) rewrite(ctx context.Context, sql string) string {
+	if include, _ := ctx.Value(includeDeletedKey{}).(bool); include {
+		return sql
+	}
+
+	top := topLevel(scanSQL(sql))
+	if len(top) == 0 {
+		return sql
+	}
+
+	if top[0].is("delete") {
+		return applyEdits(sql, s.deleteEdits(top))
+	}
+
+	var edits []sqlEdit
+	for _, part := range splitSetOperations(top) {
+		if len(part) > 0 && part[0].is("select") {
+			edits = append(edits, s.selectEdits(part)...)
+		}
+	}
+
+	return applyEdits(sql, edits)
+}
+
+func (s *softDelete) selectEdits(part []sqlToken) []sqlEdit {
+	from := indexOf(part, 0, "from")
+	if from < 0 {
+		return nil
+	}
+
+	fromEnd := indexOf(part, from+1, _selectClauses...)
+	if fromEnd < 0 {
+		fromEnd = len(part)
+	}
+	if fromEnd == from+1 {
+		return nil
+	}
+
+	var (
+		edits      []sqlEdit
+		whereConds []string
+		items      = part[from+1 : fromEnd]
+	)
+
+	for i := 0; i < len(items); {
+		var (
+			isJoin, isOuter bool
+			j               = i
+		)
+		for j < len(items) && isJoinWord(items, j) && !items[j].is("join") {
+			isOuter = isOuter || items[j].isAny("left", "right", "full")
+			j++
+		}
+		if j < len(items) && items[j].is("join") {
+			isJoin = true
+			j++
+		} else {
+			j = i
+		}
+
+		k := j
+		for k < len(items) && !(items[k].kind == tokPunct && items[k].text == ",") && !isJoinWord(items, k) {
+			k++
+		}
+
+		item := items[j:k]
+		if q, ok := s.matchTable(item); ok {
+			cond := q + "." + s.column + " IS NULL"
+			on := indexOf(item, 0, "on")
+			switch {
+			case isJoin && on >= 0 && on+1 < len(item):
+				edits = append(edits,
+					insertAt(item[on+1].start, "("),
+					insertAt(item[len(item)-1].end, ") AND "+cond),
+				)
+			case !isJoin || !isOuter:
+				whereConds = append(whereConds, cond)
+			}
+		}
+
+		i = k
+		if i < len(items) && items[i].text == "," {
+			i++
+		}
+	}
+
+	if len(whereConds) == 0 {
+		return edits
+	}
+
+	cond := strings.Join(whereConds, " AND ")
+	if fromEnd < len(part) && part[fromEnd].is("where") && fromEnd+1 < len(part) {
+		whereEnd := indexOf(part, fromEnd+1, _selectClauses[1:]...)
+		if whereEnd < 0 {
+			whereEnd = len(part)
+		}
+		return append(edits,
+			insertAt(part[fromEnd+1].start, "("),
+			insertAt(part[whereEnd-1].end, ") AND "+cond),
+		)
+	}
+
+	return append(edits, insertAt(part[fromEnd-1].end, " WHERE "+cond))
+}
+
+func (s *softDelete) deleteEdits(top []sqlToken) []sqlEdit {
+	if len(top) < 3 || !top[1].is("from") {
+		return nil
+	}
+
+	end := len(top)
+	if top[end-1].text == ";" {
+		end--
+	}
+
+	rest := top[2:end]
+	q, ok := s.matchTable(rest)
+	if !ok {
+		return nil
+	}
+
+	// Конец "FROM [ONLY] table [[AS] alias]" — первое из USING/WHERE/RETURNING.
+	tableEnd := indexOf(rest, 0, "using", "where", "returning")
+	if tableEnd < 0 {
+		tableEnd = len(rest)
+	}
+
+	edits := []sqlEdit{
+		{start: top[0].start, end: top[1].end, text: "UPDATE"},
+		insertAt(rest[tableEnd-1].end, " SET "+s.column+" = now()"),
+	}
+
+	if using := indexOf(rest, tableEnd, "using"); using >= 0 {
+		edits = append(edits, sqlEdit{start: rest[using].start, end: rest[using].end, text: "FROM"})
+	}
+
+	cond := q + "." + s.column + " IS NULL"
+	returning := indexOf(rest, tableEnd, "returning")
+	whereEnd := len(rest)
+	if returning >= 0 {
+		whereEnd = returning
+	}
+
+	if where := indexOf(rest, tableEnd, "where"); where >= 0 && where+1 < whereEnd {
+		return append(edits,
+			insertAt(rest[where+1].start, "("),
+			insertAt(rest[whereEnd-1].end, ") AND "+cond),
+		)
+	}
+
+	return append(edits, insertAt(rest[whereEnd-1].end, " WHERE "+cond))
+}
+
+// isJoinWord сообщает, начинается ли в позиции i описание соединения.
+// left(...)/right(...) — это функции, а не соединения.
+func isJoinWord(ts []sqlToken, i int) bool {
+	if !ts[i].isAny("join", "left", "right", "full", "outer", "inner", "cross", "natural") {
+		return false
+	}
+	return i+1 >= len(ts) || ts[i+1].text != "("
+}
+
+// matchTable проверяет, ссылается ли элемент FROM ("[ONLY] name [[AS] alias] ...") на
+// зарегистрированную таблицу, и возвращает квалификатор для условия: псевдоним или имя.
+func (s *softDelete) matchTable(item []sqlToken) (string, bool) {
+	if len(item) > 0 && item[0].is("only") {
+		item = item[1:]
+	}
+	if len(item) == 0 || item[0].kind != tokWord && item[0].kind != tokQuotedIdent {
+		return "", false
+	}
+
+	n := 1
+	for n+1 < len(item) && item[n].text == "." && (item[n+1].kind == tokWord || item[n+1].kind == tokQuotedIdent) {
+		n += 2
+	}
+	if n < len(item) && item[n].text == "(" {
+		// Вызов функции в FROM.
+		return "", false
+	}
+
+	var (
+		parts []string
+		text  strings.Builder
+	)
+	for _, t := range item[:n] {
+		text.WriteString(t.text)
+		switch t.kind {
+		case tokWord:
+			parts = append(parts, strings.ToLower(t.text))
+		case tokQuotedIdent:
+			parts = append(parts, strings.ReplaceAll(t.text[1:len(t.text)-1], `""`, `"`))
+		}
+	}
+
+	_, full := s.tables[strings.Join(parts, ".")]
+	_, short := s.tables[parts[len(parts)-1]]
+	if !full && !short {
+		return "", false
+	}
+
+	rest := item[n:]
+	if len(rest) > 0 && rest[0].is("as") {
+		rest = rest[1:]
+	}
+	if len(rest) > 0 && (rest[0].kind == tokQuotedIdent ||
+		rest[0].kind == tokWord && !rest[0].isAny("on", "using", "where", "returning", "tablesample")) {
+		return rest[0].text, true
+	}
+
+	return text.String(), true
+}
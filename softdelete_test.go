@@ -0,0 +1,72 @@
+package pgfx
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSoftDeleteRewrite(t *testing.T) {
+	sd := &softDelete{
+		column: quoteIdent("deleted_at"),
+		tables: map[string]struct{}{"users": {}, "orders": {}},
+	}
+
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "select without where",
+			sql:  `SELECT id FROM users`,
+			want: `SELECT id FROM users WHERE users."deleted_at" IS NULL`,
+		},
+		{
+			name: "select with where and order",
+			sql:  `SELECT id FROM users u WHERE u.id = $1 OR u.name = 'from where' ORDER BY id`,
+			want: `SELECT id FROM users u WHERE (u.id = $1 OR u.name = 'from where') AND u."deleted_at" IS NULL ORDER BY id`,
+		},
+		{
+			name: "join on",
+			sql:  `SELECT * FROM accounts a LEFT JOIN orders o ON o.account_id = a.id`,
+			want: `SELECT * FROM accounts a LEFT JOIN orders o ON (o.account_id = a.id) AND o."deleted_at" IS NULL`,
+		},
+		{
+			name: "unregistered table and subquery",
+			sql:  `SELECT * FROM accounts WHERE id IN (SELECT account_id FROM users)`,
+			want: `SELECT * FROM accounts WHERE id IN (SELECT account_id FROM users)`,
+		},
+		{
+			name: "union branches",
+			sql:  `SELECT id FROM users UNION ALL SELECT id FROM orders LIMIT 10`,
+			want: `SELECT id FROM users WHERE users."deleted_at" IS NULL UNION ALL SELECT id FROM orders WHERE orders."deleted_at" IS NULL LIMIT 10`,
+		},
+		{
+			name: "delete with where and returning",
+			sql:  `DELETE FROM users WHERE id = $1 RETURNING id`,
+			want: `UPDATE users SET "deleted_at" = now() WHERE (id = $1) AND users."deleted_at" IS NULL RETURNING id`,
+		},
+		{
+			name: "delete using",
+			sql:  `DELETE FROM public.users AS u USING accounts a WHERE a.id = u.account_id;`,
+			want: `UPDATE public.users AS u SET "deleted_at" = now() FROM accounts a WHERE (a.id = u.account_id) AND u."deleted_at" IS NULL;`,
+		},
+		{
+			name: "delete from unregistered table",
+			sql:  `DELETE FROM accounts`,
+			want: `DELETE FROM accounts`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sd.rewrite(context.Background(), tt.sql); got != tt.want {
+				t.Errorf("rewrite(%q)\n got: %s\nwant: %s", tt.sql, got, tt.want)
+			}
+		})
+	}
+
+	if got := sd.rewrite(IncludeDeleted(context.Background()), `SELECT id FROM users`); got != `SELECT id FROM users` {
+		t.Errorf("IncludeDeleted: got %s", got)
+	}
+}
@@ -0,0 +1,53 @@
+package pgfx
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DBTX совпадает с интерфейсом DBTX, который генерирует sqlc для драйвера pgx/v5
+// (включая CopyFrom и SendBatch для запросов :copyfrom и :batch*).
+type DBTX interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+	QueryRow(context.Context, string, ...any) pgx.Row
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+var _ DBTX = pgTransactor{}
+
+// DBTX возвращает адаптер для кода, сгенерированного sqlc. Запросы выполняются через
+// TransactionalPool, поэтому Queries из sqlc автоматически участвуют в транзакциях
+// TxManager из контекста, а QueryRewriter, лимиты строк и трейсинг pgfx применяются к ним так же:
+//
+//	queries := db.New(pg.DBTX())
+//	err := tm.ReadCommitted(ctx, func(ctx context.Context) error {
+//	    if err := queries.CreateOrder(ctx, params); err != nil {
+//	        return err
+//	    }
+//	    return queries.ReserveStock(ctx, params.ItemID)
+//	})
+func (p *Postgres) DBTX() DBTX {
+	if db, ok := p.TransactionalPool.(DBTX); ok {
+		return db
+	}
+	return dbtxAdapter{QueryExecutor: p.TransactionalPool, pool: p.Pool, key: p.txKey}
+}
+
+// dbtxAdapter дополняет QueryExecutor без SendBatch (например, подменённый TransactionalPool).
+type dbtxAdapter struct {
+	QueryExecutor
+	pool *pgxpool.Pool
+	key  any
+}
+
+func (a dbtxAdapter) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	if tx, ok := txFrom(ctx, a.key); ok {
+		return tx.SendBatch(ctx, b)
+	}
+	return a.pool.SendBatch(ctx, b)
+}
@@ -0,0 +1,26 @@
+package pgfx
+
+import (
+	"database/sql"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// SQLDB возвращает *sql.DB поверх пула pgfx (stdlib.OpenDBFromPool) для библиотек,
+// работающих через database/sql: GORM, ent, goose и т.п. Соединения берутся из того же
+// пула, поэтому лимит MaxPoolSize, трейсинг и остальные трейсеры pgfx действуют и для них,
+// а число соединений к базе не удваивается:
+//
+//	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: pg.SQLDB()}), &gorm.Config{})
+//	client := ent.NewClient(ent.Driver(entsql.OpenDB(dialect.Postgres, pg.SQLDB())))
+//
+// Транзакции TxManager из контекста database/sql не видит: запросы GORM/ent внутри
+// ReadCommitted выполняются на отдельном соединении, вне транзакции pgfx.
+// QueryRewriter и MaxQueryRows к ним тоже не применяются.
+// Возвращается один и тот же *sql.DB; закрывать его не нужно — это делает Close.
+func (p *Postgres) SQLDB() *sql.DB {
+	p.sqlDBOnce.Do(func() {
+		p.sqlDB = stdlib.OpenDBFromPool(p.Pool)
+	})
+	return p.sqlDB
+}
@@ -0,0 +1,274 @@
+package pgfx
+
+import (
+	"sort"
+	"strings"
+)
+
+// Лёгкий лексер SQL для переписывания запросов (soft delete, защита от SELECT без LIMIT).
+// Это не парсер: он понимает строки, идентификаторы в кавычках, dollar-quoting,
+// комментарии и глубину скобок — ровно столько, чтобы надёжно находить ключевые
+// слова верхнего уровня и не трогать содержимое литералов.
+
+type sqlTokenKind int
+
+const (
+	tokWord sqlTokenKind = iota
+	tokQuotedIdent
+	tokString
+	tokNumber
+	tokParam
+	tokPunct
+	tokSpace
+)
+
+type sqlToken struct {
+	kind  sqlTokenKind
+	text  string
+	start int
+	end   int
+	// depth — глубина скобок, в которой находится токен; открывающая и закрывающая
+	// скобки принадлежат внешнему уровню.
+	depth int
+}
+
+func (t sqlToken) is(keyword string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, keyword)
+}
+
+func (t sqlToken) isAny(keywords ...string) bool {
+	for _, k := range keywords {
+		if t.is(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanSQL разбивает запрос на токены. Незавершённые строки и комментарии
+// поглощают остаток запроса — сервер всё равно отвергнет такой SQL.
+func scanSQL(sql string) []sqlToken {
+	var (
+		tokens []sqlToken
+		depth  int
+	)
+
+	for i := 0; i < len(sql); {
+		start := i
+		c := sql[i]
+		kind := tokPunct
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			kind = tokSpace
+			for i < len(sql) && strings.IndexByte(" \t\n\r\f", sql[i]) >= 0 {
+				i++
+			}
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			kind = tokSpace
+			if j := strings.IndexByte(sql[i:], '\n'); j >= 0 {
+				i += j + 1
+			} else {
+				i = len(sql)
+			}
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			kind = tokSpace
+			i = skipBlockComment(sql, i)
+		case c == '\'':
+			kind = tokString
+			i = skipQuoted(sql, i+1, '\'', false)
+		case (c == 'E' || c == 'e') && i+1 < len(sql) && sql[i+1] == '\'':
+			kind = tokString
+			i = skipQuoted(sql, i+2, '\'', true)
+		case c == '"':
+			kind = tokQuotedIdent
+			i = skipQuoted(sql, i+1, '"', false)
+		case c == '$':
+			i, kind = scanDollar(sql, i)
+		case isIdentStart(c):
+			kind = tokWord
+			for i < len(sql) && isIdentChar(sql[i]) {
+				i++
+			}
+		case c >= '0' && c <= '9':
+			kind = tokNumber
+			for i < len(sql) && (sql[i] >= '0' && sql[i] <= '9' || sql[i] == '.') {
+				i++
+			}
+		default:
+			i++
+		}
+
+		tok := sqlToken{kind: kind, text: sql[start:i], start: start, end: i, depth: depth}
+		if kind == tokPunct {
+			switch c {
+			case '(':
+				depth++
+			case ')':
+				if depth > 0 {
+					depth--
+				}
+				tok.depth = depth
+			}
+		}
+		tokens = append(tokens, tok)
+	}
+
+	return tokens
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9' || c == '$'
+}
+
+func skipQuoted(sql string, i int, quote byte, backslash bool) int {
+	for i < len(sql) {
+		switch {
+		case backslash && sql[i] == '\\':
+			i += 2
+		case sql[i] == quote && i+1 < len(sql) && sql[i+1] == quote:
+			i += 2
+		case sql[i] == quote:
+			return i + 1
+		default:
+			i++
+		}
+	}
+	return len(sql)
+}
+
+func skipBlockComment(sql string, i int) int {
+	nesting := 0
+	for i < len(sql) {
+		switch {
+		case strings.HasPrefix(sql[i:], "/*"):
+			nesting++
+			i += 2
+		case strings.HasPrefix(sql[i:], "*/"):
+			nesting--
+			i += 2
+			if nesting == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return len(sql)
+}
+
+// scanDollar разбирает параметр ($1) или строку в dollar-quoting ($$...$$, $tag$...$tag$).
+func scanDollar(sql string, i int) (int, sqlTokenKind) {
+	j := i + 1
+	if j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+		for j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+			j++
+		}
+		return j, tokParam
+	}
+
+	for j < len(sql) && sql[j] != '$' && isIdentChar(sql[j]) {
+		j++
+	}
+	if j >= len(sql) || sql[j] != '$' {
+		return i + 1, tokPunct
+	}
+
+	tag := sql[i : j+1]
+	if k := strings.Index(sql[j+1:], tag); k >= 0 {
+		return j + 1 + k + len(tag), tokString
+	}
+	return len(sql), tokString
+}
+
+// significant возвращает токены без пробелов и комментариев.
+func significant(tokens []sqlToken) []sqlToken {
+	out := make([]sqlToken, 0, len(tokens))
+	for _, t := range tokens {
+		if t.kind != tokSpace {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// topLevel возвращает значимые токены нулевой глубины.
+func topLevel(tokens []sqlToken) []sqlToken {
+	out := make([]sqlToken, 0, len(tokens))
+	for _, t := range tokens {
+		if t.kind != tokSpace && t.depth == 0 {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// splitSetOperations делит токены верхнего уровня на части, соединённые UNION/INTERSECT/EXCEPT,
+// и отбрасывает завершающую точку с запятой.
+func splitSetOperations(top []sqlToken) [][]sqlToken {
+	var (
+		parts [][]sqlToken
+		cur   []sqlToken
+	)
+
+	for _, t := range top {
+		switch {
+		case t.isAny("union", "intersect", "except"):
+			parts = append(parts, cur)
+			cur = nil
+		case t.kind == tokPunct && t.text == ";":
+		case len(cur) == 0 && t.isAny("all", "distinct") && len(parts) > 0:
+		default:
+			cur = append(cur, t)
+		}
+	}
+
+	return append(parts, cur)
+}
+
+type sqlEdit struct {
+	start int
+	end   int
+	text  string
+}
+
+func insertAt(pos int, text string) sqlEdit {
+	return sqlEdit{start: pos, end: pos, text: text}
+}
+
+// applyEdits применяет непересекающиеся правки. Вставки в одну позицию
+// применяются в порядке их добавления.
+func applyEdits(sql string, edits []sqlEdit) string {
+	if len(edits) == 0 {
+		return sql
+	}
+
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+
+	var b strings.Builder
+	last := 0
+	for _, e := range edits {
+		b.WriteString(sql[last:e.start])
+		b.WriteString(e.text)
+		last = e.end
+	}
+	b.WriteString(sql[last:])
+
+	return b.String()
+}
+
+// indexOf возвращает индекс первого токена из ts начиная с from, совпадающего с одним из ключевых слов.
+func indexOf(ts []sqlToken, from int, keywords ...string) int {
+	for i := from; i < len(ts); i++ {
+		if ts[i].isAny(keywords...) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,22 @@
+package pgfx
+
+import (
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// quoteLiteral экранирует строку как SQL-литерал в одинарных кавычках.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// quoteTable экранирует имя таблицы, допуская указание схемы через точку ("schema.table").
+func quoteTable(name string) string {
+	return pgx.Identifier(strings.Split(name, ".")).Sanitize()
+}
+
+// quoteIdent экранирует одиночный идентификатор (колонку, ограничение и т.п.).
+func quoteIdent(name string) string {
+	return pgx.Identifier{name}.Sanitize()
+}
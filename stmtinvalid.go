@@ -0,0 +1,95 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// isStatementInvalidated сообщает, что кэшированный на соединении подготовленный оператор
+// устарел после DDL: изменился тип результата ("cached plan must not change result type")
+// или оператора больше нет на сервере ("prepared statement ... does not exist").
+func isStatementInvalidated(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "26000" || pgErr.Code == "0A000" && pgErr.Message == "cached plan must not change result type"
+}
+
+// stmtInvalidationTracer сбрасывает кэш операторов соединения, на котором запрос упал
+// из-за устаревшего оператора, чтобы следующий запрос подготовил его заново.
+type stmtInvalidationTracer struct{}
+
+func (stmtInvalidationTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return ctx
+}
+
+func (stmtInvalidationTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	if !isStatementInvalidated(data.Err) {
+		return
+	}
+
+	// В прерванной транзакции DEALLOCATE не выполнится, но клиентские кэши
+	// DeallocateAll сбрасывает до обращения к серверу, а этого достаточно.
+	if err := conn.DeallocateAll(context.WithoutCancel(ctx)); err != nil && conn.PgConn().TxStatus() == 'I' {
+		log.Printf("pgfx: reset statement cache after invalidation: %v", err)
+	}
+}
+
+// retryRows повторяет запрос один раз, если он упал из-за устаревшего оператора,
+// не успев вернуть ни одной строки.
+type retryRows struct {
+	pgx.Rows
+	retry   func() (pgx.Rows, error)
+	started bool
+	retried bool
+	err     error
+}
+
+func (r *retryRows) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	if r.Rows.Next() {
+		r.started = true
+		return true
+	}
+	if r.started || r.retried || !isStatementInvalidated(r.Rows.Err()) {
+		return false
+	}
+
+	r.retried = true
+	r.Rows.Close()
+	rows, err := r.retry()
+	if err != nil {
+		r.err = err
+		return false
+	}
+	r.Rows = rows
+	return r.Next()
+}
+
+func (r *retryRows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.Rows.Err()
+}
+
+// retryRow повторяет QueryRow один раз, если он упал из-за устаревшего оператора.
+type retryRow struct {
+	row   pgx.Row
+	retry func() pgx.Row
+}
+
+func (r retryRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if isStatementInvalidated(err) {
+		return r.retry().Scan(dest...)
+	}
+	return err
+}
@@ -0,0 +1,158 @@
+package pgfx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Границы адаптивного размера пачки Stream по умолчанию.
+const (
+	_defaultStreamMinBatch    = 64
+	_defaultStreamMaxBatch    = 10000
+	_defaultStreamBatchBytes  = 4 << 20
+	_defaultStreamBatchTarget = 100 * time.Millisecond
+)
+
+// StreamOption настраивает Stream.
+type StreamOption func(*streamOptions)
+
+type streamOptions struct {
+	minBatch    int
+	maxBatch    int
+	batchBytes  int
+	batchTarget time.Duration
+}
+
+// WithFetchSize задаёт границы размера пачки FETCH: первая пачка — lo строк, дальше размер
+// растёт не больше чем до hi (по умолчанию 64 и 10000).
+func WithFetchSize(lo, hi int) StreamOption {
+	return func(o *streamOptions) {
+		o.minBatch = lo
+		o.maxBatch = hi
+	}
+}
+
+// WithFetchTarget задаёт целевой объём (в байтах) и длительность одной пачки FETCH
+// (по умолчанию 4 МиБ и 100 мс).
+func WithFetchTarget(bytes int, latency time.Duration) StreamOption {
+	return func(o *streamOptions) {
+		o.batchBytes = bytes
+		o.batchTarget = latency
+	}
+}
+
+// next вычисляет размер следующей пачки по предыдущей: rows строк, bytes байт за elapsed.
+// Размер не более чем удваивается и подстраивается так, чтобы пачка укладывалась
+// и в целевой объём (широкие строки), и в целевую длительность (медленный запрос).
+func (o streamOptions) next(size, rows, bytes int, elapsed time.Duration) int {
+	next := size * 2
+	if rows > 0 && bytes > 0 {
+		next = min(next, o.batchBytes/max(bytes/rows, 1))
+	}
+	if elapsed > 0 {
+		next = min(next, int(float64(size)*float64(o.batchTarget)/float64(elapsed)))
+	}
+	return max(o.minBatch, min(next, o.maxBatch))
+}
+
+// Stream выполняет запрос через серверный курсор (DECLARE ... CURSOR) и возвращает итератор
+// по строкам, которые выбираются пачками FETCH. Размер пачки подбирается сам: начинается
+// с малого и растёт, пока пачка укладывается в целевой объём и длительность
+// (см. WithFetchSize, WithFetchTarget), поэтому и узкие, и широкие строки читаются без ручной настройки.
+//
+// В отличие от Iterate, сервер не отправляет весь результат сразу, а клиент не держит
+// соединение на чтении между пачками: между итерациями можно выполнять запросы в той же транзакции.
+// Курсор живёт в транзакции из контекста (на её savepoint) или в отдельной транзакции.
+// Сканирование в T — как в Iterate.
+func Stream[T any](ctx context.Context, db QueryExecutor, sql string, args []any, opts ...StreamOption) iter.Seq2[T, error] {
+	o := streamOptions{
+		minBatch:    _defaultStreamMinBatch,
+		maxBatch:    _defaultStreamMaxBatch,
+		batchBytes:  _defaultStreamBatchBytes,
+		batchTarget: _defaultStreamBatchTarget,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.minBatch = max(o.minBatch, 1)
+	o.maxBatch = max(o.maxBatch, o.minBatch)
+
+	scan := rowScanFunc[T]()
+
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			yield(zero, fmt.Errorf("stream: begin: %w", err))
+			return
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+
+		suffix := make([]byte, 8)
+		if _, err := rand.Read(suffix); err != nil {
+			yield(zero, fmt.Errorf("stream: %w", err))
+			return
+		}
+		cursor := quoteIdent("pgfx_stream_" + hex.EncodeToString(suffix))
+
+		if _, err := tx.Exec(ctx, "DECLARE "+cursor+" NO SCROLL CURSOR FOR "+sql, args...); err != nil {
+			yield(zero, fmt.Errorf("stream: declare: %w", err))
+			return
+		}
+
+		for size := o.minBatch; ; {
+			start := time.Now()
+			rows, err := tx.Query(ctx, fmt.Sprintf("FETCH %d FROM %s", size, cursor))
+			if err != nil {
+				yield(zero, fmt.Errorf("stream: fetch: %w", err))
+				return
+			}
+
+			var n, bytes int
+			// Выводим строки пачки после её чтения: rows держат соединение,
+			// а обработчик может выполнять запросы в той же транзакции.
+			batch := make([]T, 0, size)
+			for rows.Next() {
+				for _, v := range rows.RawValues() {
+					bytes += len(v)
+				}
+				v, err := scan(rows)
+				if err != nil {
+					rows.Close()
+					yield(zero, fmt.Errorf("stream: %w", err))
+					return
+				}
+				batch = append(batch, v)
+				n++
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				yield(zero, fmt.Errorf("stream: fetch: %w", err))
+				return
+			}
+			elapsed := time.Since(start)
+
+			for _, v := range batch {
+				if !yield(v, nil) {
+					return
+				}
+			}
+
+			if n < size {
+				break
+			}
+			size = o.next(size, n, bytes, elapsed)
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			yield(zero, fmt.Errorf("stream: commit: %w", err))
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package pgfx
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreamBatchSize(t *testing.T) {
+	o := streamOptions{minBatch: 64, maxBatch: 10000, batchBytes: 1 << 20, batchTarget: 100 * time.Millisecond}
+
+	tests := []struct {
+		name    string
+		size    int
+		rows    int
+		bytes   int
+		elapsed time.Duration
+		want    int
+	}{
+		{name: "narrow fast rows double", size: 64, rows: 64, bytes: 64 * 16, elapsed: time.Millisecond, want: 128},
+		{name: "capped by max", size: 8000, rows: 8000, bytes: 8000 * 16, elapsed: time.Millisecond, want: 10000},
+		{name: "wide rows limited by bytes", size: 1000, rows: 1000, bytes: 1000 * 4096, elapsed: time.Millisecond, want: 256},
+		{name: "slow batches shrink", size: 1000, rows: 1000, bytes: 1000 * 16, elapsed: 400 * time.Millisecond, want: 250},
+		{name: "not below min", size: 64, rows: 64, bytes: 64 * (1 << 20), elapsed: time.Millisecond, want: 64},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := o.next(tt.size, tt.rows, tt.bytes, tt.elapsed); got != tt.want {
+				t.Errorf("next() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
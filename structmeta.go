@@ -0,0 +1,161 @@
+package pgfx
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ErrNotStruct возвращается хелперами, работающими со структурами, если тип не является структурой.
+var ErrNotStruct = errors.New("type is not a struct")
+
+// Описание колонок структуры строится по тегам:
+//
+//	type User struct {
+//	    ID        int64     `db:"id" pgfx:"pk,auto"`
+//	    Email     string    `db:"email"`
+//	    CreatedAt time.Time `db:"created_at" pgfx:"auto"`
+//	    Internal  string    `db:"-"`
+//	}
+//
+// Тег db задаёт имя колонки (по умолчанию — имя поля в snake_case, "-" исключает поле) и
+// совместим с pgx.RowToStructByName. Тег pgfx содержит флаги через запятую:
+//
+//   - pk — колонка входит в первичный ключ;
+//   - auto — значение формирует база (serial, DEFAULT now()), колонка не передаётся в INSERT/UPDATE.
+type structField struct {
+	column string
+	index  []int
+	typ    reflect.Type
+	flags  map[string]string
+}
+
+func (f structField) has(flag string) bool {
+	_, ok := f.flags[flag]
+	return ok
+}
+
+type structMeta struct {
+	typ    reflect.Type
+	fields []structField
+}
+
+var structMetaCache sync.Map // reflect.Type -> *structMeta
+
+func structMetaOf(t reflect.Type) (*structMeta, error) {
+	if m, ok := structMetaCache.Load(t); ok {
+		return m.(*structMeta), nil
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %s", ErrNotStruct, t)
+	}
+
+	m := &structMeta{typ: t}
+	collectStructFields(t, nil, &m.fields)
+
+	structMetaCache.Store(t, m)
+	return m, nil
+}
+
+func collectStructFields(t reflect.Type, parent []int, out *[]structField) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		index := append(append([]int(nil), parent...), i)
+
+		// Правила совпадают с pgx.RowToStructByName, чтобы сгенерированный SQL
+		// и сканирование результата видели одни и те же колонки.
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			collectStructFields(sf.Type, index, out)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		tag, _, _ := strings.Cut(sf.Tag.Get("db"), ",")
+		if tag == "-" {
+			continue
+		}
+
+		column := tag
+		if column == "" {
+			column = toSnakeCase(sf.Name)
+		}
+
+		*out = append(*out, structField{
+			column: column,
+			index:  index,
+			typ:    sf.Type,
+			flags:  parseFlags(sf.Tag.Get("pgfx")),
+		})
+	}
+}
+
+// parseFlags разбирает "pk,auto,op=gte" в map[pk: auto: op:gte].
+func parseFlags(tag string) map[string]string {
+	flags := make(map[string]string)
+	for _, f := range strings.Split(tag, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(f, "=")
+		flags[name] = value
+	}
+	return flags
+}
+
+func toSnakeCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// filter возвращает поля, для которых keep возвращает true.
+func (m *structMeta) filter(keep func(structField) bool) []structField {
+	var out []structField
+	for _, f := range m.fields {
+		if keep(f) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func (m *structMeta) primaryKey() []structField {
+	return m.filter(func(f structField) bool { return f.has("pk") })
+}
+
+// writable — колонки, значения которых передаются в INSERT.
+func (m *structMeta) writable() []structField {
+	return m.filter(func(f structField) bool { return !f.has("auto") })
+}
+
+func columnList(fields []structField) string {
+	cols := make([]string, len(fields))
+	for i, f := range fields {
+		cols[i] = quoteIdent(f.column)
+	}
+	return strings.Join(cols, ", ")
+}
+
+func fieldValues(v reflect.Value, fields []structField) []any {
+	values := make([]any, len(fields))
+	for i, f := range fields {
+		values[i] = v.FieldByIndex(f.index).Interface()
+	}
+	return values
+}
@@ -0,0 +1,185 @@
+package pgfx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TableDriftMode определяет реакцию New на расхождение структур и таблиц (см. VerifyTablesOnStart).
+type TableDriftMode int
+
+const (
+	// TableDriftFail — New возвращает ошибку.
+	TableDriftFail TableDriftMode = iota
+	// TableDriftLog — расхождения логируются, New продолжает работу.
+	TableDriftLog
+)
+
+type tableRegistry struct {
+	mu     sync.RWMutex
+	tables map[string]reflect.Type
+}
+
+var defaultTables = &tableRegistry{tables: make(map[string]reflect.Type)}
+
+// RegisterTable связывает структуру T с таблицей table для проверки VerifyTables:
+//
+//	var _ = pgfx.RegisterTable[User]("users")
+//
+// Колонки определяются по тем же правилам тегов db, что и у Repository.
+func RegisterTable[T any](table string) bool {
+	defaultTables.mu.Lock()
+	defer defaultTables.mu.Unlock()
+
+	defaultTables.tables[table] = reflect.TypeFor[T]()
+	return true
+}
+
+// TableVerificationError перечисляет расхождения зарегистрированных структур с таблицами.
+type TableVerificationError struct {
+	// Problems — таблица → описания расхождений.
+	Problems map[string][]string
+}
+
+func (e *TableVerificationError) Error() string {
+	tables := make([]string, 0, len(e.Problems))
+	for t := range e.Problems {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d tables differ from registered structs:", len(tables))
+	for _, t := range tables {
+		for _, p := range e.Problems[t] {
+			fmt.Fprintf(&b, "\n%s: %s", t, p)
+		}
+	}
+	return b.String()
+}
+
+// VerifyTables проверяет структуры, зарегистрированные через RegisterTable, против таблиц:
+// у каждого поля должна быть колонка, а её тип — подходить к типу поля (по категории типа
+// PostgreSQL: строки, числа, даты, bool, bytea, массивы). Поля структурных и map-типов,
+// а также типов, реализующих sql.Scanner, по типу не проверяются.
+//
+// Возвращает *TableVerificationError со всеми найденными расхождениями.
+func (p *Postgres) VerifyTables(ctx context.Context) error {
+	defaultTables.mu.RLock()
+	tables := make(map[string]reflect.Type, len(defaultTables.tables))
+	for name, t := range defaultTables.tables {
+		tables[name] = t
+	}
+	defaultTables.mu.RUnlock()
+
+	problems := make(map[string][]string)
+	for table, typ := range tables {
+		meta, err := structMetaOf(typ)
+		if err != nil {
+			problems[table] = append(problems[table], err.Error())
+			continue
+		}
+
+		columns, err := tableColumns(ctx, p.Pool, table)
+		if err != nil {
+			return fmt.Errorf("postgres - VerifyTables - %s: %w", table, err)
+		}
+		if len(columns) == 0 {
+			problems[table] = append(problems[table], "table not found")
+			continue
+		}
+
+		for _, f := range meta.fields {
+			col, ok := columns[f.column]
+			if !ok {
+				problems[table] = append(problems[table], fmt.Sprintf("column %s (field of %s) not found", f.column, typ))
+				continue
+			}
+			if f.has("encrypted") {
+				if col.name != "bytea" {
+					problems[table] = append(problems[table], fmt.Sprintf("encrypted column %s must be bytea, got %s", f.column, col.name))
+				}
+				continue
+			}
+			if !goTypeMatches(f.typ, col) {
+				problems[table] = append(problems[table], fmt.Sprintf("column %s is %s, incompatible with %s", f.column, col.name, f.typ))
+			}
+		}
+		sort.Strings(problems[table])
+	}
+
+	if len(problems) > 0 {
+		return &TableVerificationError{Problems: problems}
+	}
+	return nil
+}
+
+type pgColumnType struct {
+	name     string // имя типа (int8, text, ...)
+	category string // pg_type.typcategory
+}
+
+func tableColumns(ctx context.Context, db QueryExecutor, table string) (map[string]pgColumnType, error) {
+	rows, err := db.Query(ctx, `
+		SELECT a.attname, t.typname, t.typcategory
+		FROM pg_attribute a
+		JOIN pg_type t ON t.oid = a.atttypid
+		WHERE a.attrelid = to_regclass($1) AND a.attnum > 0 AND NOT a.attisdropped`, quoteTable(table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]pgColumnType)
+	for rows.Next() {
+		var name string
+		var col pgColumnType
+		if err := rows.Scan(&name, &col.name, &col.category); err != nil {
+			return nil, err
+		}
+		columns[name] = col
+	}
+	return columns, rows.Err()
+}
+
+// goTypeMatches грубо сопоставляет тип поля с категорией типа колонки.
+func goTypeMatches(t reflect.Type, col pgColumnType) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeFor[time.Time]() {
+		return col.category == "D"
+	}
+	if t == reflect.TypeFor[[]byte]() {
+		return col.name == "bytea" || col.name == "json" || col.name == "jsonb"
+	}
+	if isScannerType(t) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		// S — строки, E — перечисления, uuid, json и т.п. тоже читаются в строку.
+		return col.category == "S" || col.category == "E" || col.category == "U"
+	case reflect.Bool:
+		return col.category == "B"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return col.category == "N"
+	case reflect.Slice, reflect.Array:
+		return col.category == "A" || col.name == "json" || col.name == "jsonb" || col.name == "uuid"
+	default:
+		return true
+	}
+}
+
+func isScannerType(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(reflect.TypeFor[sql.Scanner]())
+}
@@ -0,0 +1,88 @@
+package pgfx
+
+import (
+	"context"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// timeoutRule — шаблон тега запроса (path.Match: "search.*", "report.daily") и таймаут.
+type timeoutRule struct {
+	pattern string
+	timeout time.Duration
+}
+
+// timeoutPolicy — правила, отсортированные от более конкретных (длинных) шаблонов к общим.
+type timeoutPolicy []timeoutRule
+
+func newTimeoutPolicy(rules map[string]time.Duration) timeoutPolicy {
+	policy := make(timeoutPolicy, 0, len(rules))
+	for pattern, timeout := range rules {
+		policy = append(policy, timeoutRule{pattern: pattern, timeout: timeout})
+	}
+	sort.Slice(policy, func(i, j int) bool {
+		if len(policy[i].pattern) != len(policy[j].pattern) {
+			return len(policy[i].pattern) > len(policy[j].pattern)
+		}
+		return policy[i].pattern < policy[j].pattern
+	})
+	return policy
+}
+
+// timeoutFor возвращает таймаут для тега или 0, если ни одно правило не подошло.
+func (p timeoutPolicy) timeoutFor(tag string) time.Duration {
+	if tag == "" {
+		return 0
+	}
+	for _, r := range p {
+		if ok, _ := path.Match(r.pattern, tag); ok {
+			return r.timeout
+		}
+	}
+	return 0
+}
+
+// withTimeout применяет таймаут политики к ctx по тегу запроса (см. WithQueryTag).
+// Более ранний дедлайн контекста сохраняется.
+func (p timeoutPolicy) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if len(p) == 0 {
+		return ctx, func() {}
+	}
+	if timeout := p.timeoutFor(QueryTag(ctx)); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {}
+}
+
+// cancelRows отменяет контекст запроса при закрытии rows.
+type cancelRows struct {
+	pgx.Rows
+	cancel context.CancelFunc
+}
+
+func (r *cancelRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.cancel()
+	return false
+}
+
+func (r *cancelRows) Close() {
+	r.Rows.Close()
+	r.cancel()
+}
+
+// cancelRow отменяет контекст запроса после Scan.
+type cancelRow struct {
+	pgx.Row
+	cancel context.CancelFunc
+}
+
+func (r cancelRow) Scan(dest ...any) error {
+	defer r.cancel()
+	return r.Row.Scan(dest...)
+}
@@ -0,0 +1,182 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/exaring/otelpgx"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// switchTracer — OpenTelemetry-трейсер, который можно включать и выключать на лету.
+// Сам otelpgx.Tracer создаётся при первом включении.
+//
+// Операция, начатая при включённом трейсинге, всегда завершается в нём же,
+// даже если трейсинг был выключен в процессе, чтобы не оставлять незакрытых span'ов.
+type switchTracer struct {
+	enabled atomic.Bool
+	once    sync.Once
+	tracer  *otelpgx.Tracer
+	// redact, если задан, возвращает параметры запроса для атрибута span'а.
+	redact func(ctx context.Context, sql string, args []any) []any
+}
+
+type tracedKey struct{}
+
+// SetEnabled включает или выключает трейсинг.
+func (t *switchTracer) SetEnabled(enabled bool) {
+	if enabled {
+		t.once.Do(func() {
+			t.tracer = otelpgx.NewTracer()
+		})
+	}
+	t.enabled.Store(enabled)
+}
+
+// Enabled сообщает, включён ли трейсинг.
+func (t *switchTracer) Enabled() bool {
+	return t.enabled.Load()
+}
+
+func (t *switchTracer) start(ctx context.Context) (*otelpgx.Tracer, context.Context) {
+	if !t.enabled.Load() {
+		return nil, ctx
+	}
+	return t.tracer, context.WithValue(ctx, tracedKey{}, true)
+}
+
+func (t *switchTracer) started(ctx context.Context) *otelpgx.Tracer {
+	if traced, _ := ctx.Value(tracedKey{}).(bool); traced {
+		return t.tracer
+	}
+	return nil
+}
+
+func (t *switchTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	tr, ctx := t.start(ctx)
+	if tr == nil {
+		return ctx
+	}
+
+	ctx = tr.TraceQueryStart(ctx, conn, data)
+	if t.redact != nil && len(data.Args) > 0 {
+		args := t.redact(ctx, data.SQL, data.Args)
+		values := make([]string, len(args))
+		for i, a := range args {
+			values[i] = fmt.Sprintf("%v", a)
+		}
+		trace.SpanFromContext(ctx).SetAttributes(attribute.StringSlice("db.query.parameters", values))
+	}
+	return ctx
+}
+
+func (t *switchTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	if tr := t.started(ctx); tr != nil {
+		tr.TraceQueryEnd(ctx, conn, data)
+	}
+}
+
+func (t *switchTracer) TraceCopyFromStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	if tr, ctx := t.start(ctx); tr != nil {
+		return tr.TraceCopyFromStart(ctx, conn, data)
+	}
+	return ctx
+}
+
+func (t *switchTracer) TraceCopyFromEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	if tr := t.started(ctx); tr != nil {
+		tr.TraceCopyFromEnd(ctx, conn, data)
+	}
+}
+
+func (t *switchTracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	if tr, ctx := t.start(ctx); tr != nil {
+		return tr.TraceBatchStart(ctx, conn, data)
+	}
+	return ctx
+}
+
+func (t *switchTracer) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+	if tr := t.started(ctx); tr != nil {
+		tr.TraceBatchQuery(ctx, conn, data)
+	}
+}
+
+func (t *switchTracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
+	if tr := t.started(ctx); tr != nil {
+		tr.TraceBatchEnd(ctx, conn, data)
+	}
+}
+
+func (t *switchTracer) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	if tr, ctx := t.start(ctx); tr != nil {
+		return tr.TraceConnectStart(ctx, data)
+	}
+	return ctx
+}
+
+func (t *switchTracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	if tr := t.started(ctx); tr != nil {
+		tr.TraceConnectEnd(ctx, data)
+	}
+}
+
+func (t *switchTracer) TracePrepareStart(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareStartData) context.Context {
+	if tr, ctx := t.start(ctx); tr != nil {
+		return tr.TracePrepareStart(ctx, conn, data)
+	}
+	return ctx
+}
+
+func (t *switchTracer) TracePrepareEnd(ctx context.Context, conn *pgx.Conn, data pgx.TracePrepareEndData) {
+	if tr := t.started(ctx); tr != nil {
+		tr.TracePrepareEnd(ctx, conn, data)
+	}
+}
+
+func (t *switchTracer) TraceAcquireStart(ctx context.Context, pool *pgxpool.Pool, data pgxpool.TraceAcquireStartData) context.Context {
+	if tr, ctx := t.start(ctx); tr != nil {
+		return tr.TraceAcquireStart(ctx, pool, data)
+	}
+	return ctx
+}
+
+func (t *switchTracer) TraceAcquireEnd(ctx context.Context, pool *pgxpool.Pool, data pgxpool.TraceAcquireEndData) {
+	if tr := t.started(ctx); tr != nil {
+		tr.TraceAcquireEnd(ctx, pool, data)
+	}
+}
+
+const _tracerName = "github.com/fr11nik/pgfx"
+
+// startSpan начинает span транзакции, если трейсинг включён; иначе возвращает nil.
+func (m *Manager) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	if m.qt == nil || !m.qt.Enabled() {
+		return ctx, nil
+	}
+
+	spanName := "pgfx.transaction"
+	if name != "" {
+		spanName += " " + name
+	}
+	ctx, span := otel.Tracer(_tracerName).Start(ctx, spanName, trace.WithSpanKind(trace.SpanKindClient))
+	if name != "" {
+		span.SetAttributes(attribute.String("pgfx.tx.name", name))
+	}
+	return ctx, span
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -2,7 +2,11 @@ package pgfx
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"sort"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
@@ -11,33 +15,156 @@ type Transactor interface {
 	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
 }
 
+// TxManager — интерфейс менеджера транзакций, от которого удобно зависеть в сервисах.
+// Реализуется *Manager и NopTxManager.
+type TxManager interface {
+	ReadCommitted(ctx context.Context, f func(ctx context.Context) error, opts ...TxOption) error
+	RepeatableRead(ctx context.Context, f func(ctx context.Context) error, opts ...TxOption) error
+	ReadOnly(ctx context.Context, f func(ctx context.Context) error, opts ...TxOption) error
+	Serializable(ctx context.Context, f func(ctx context.Context) error, opts ...TxOption) error
+	WithTxOptions(ctx context.Context, txOptions pgx.TxOptions, f Handler, opts ...TxOption) error
+	Begin(ctx context.Context, txOptions pgx.TxOptions, opts ...TxOption) (*TxHandle, error)
+}
+
+// Handler — обработчик, выполняемый в транзакции.
+type Handler func(ctx context.Context) error
+
+var (
+	_ TxManager = (*Manager)(nil)
+	_ TxManager = nopTxManager{}
+)
+
 type Manager struct {
-	db Transactor
+	db    Transactor
+	stats *nestingStats
+	// qt — трейсер Postgres; при включённом трейсинге транзакция оборачивается в span.
+	qt *switchTracer
+	// slowTx — порог предупреждения о медленной транзакции, 0 — выключено.
+	slowTx time.Duration
+	// txStatsScope — собирать RequestStats на время транзакции, если их нет в контексте.
+	txStatsScope bool
+	// idleTx — порог простоя транзакции между запросами, 0 — сторож выключен;
+	// idleRollback — откатывать такие транзакции, а не только логировать (см. WatchIdleTransactions).
+	idleTx       time.Duration
+	idleRollback bool
+	// replay — записывать запросы транзакции для TxReplayError, nil — выключено;
+	// значение — включать ли текст запросов (см. RecordTransactionReplay).
+	replay *bool
+	// nesting — поведение вложенных вызовов по умолчанию (см. NestedTransactions).
+	nesting NestingMode
+	// key — ключ транзакций экземпляра Postgres в контексте, nil — общий TxKey.
+	key any
+	// metrics получает события транзакций, nil — выключено (см. WithTxMetrics).
+	metrics TxMetrics
+	// settings — параметры SET LOCAL каждой транзакции менеджера (см. WithSettings).
+	settings []txSetting
+	// leakAfter — через сколько незавершённая транзакция считается утечкой, 0 — выключено
+	// (см. DetectTransactionLeaks).
+	leakAfter time.Duration
+	// guardTx — отклонять одновременное использование транзакции из разных горутин
+	// (см. GuardConcurrentTxUse).
+	guardTx bool
 }
 
 // NewTransactionManager создает новый менеджер транзакций, который удовлетворяет интерфейсу db.TxManager
-func newTransactionManager(db Transactor) *Manager {
+func newTransactionManager(db Transactor, stats *nestingStats) *Manager {
+	if stats == nil {
+		stats = &nestingStats{}
+	}
+
 	return &Manager{
-		db: db,
+		db:    db,
+		stats: stats,
 	}
 }
 
 // transaction основная функция, которая выполняет указанный пользователем обработчик в транзакции
-func (m *Manager) transaction(ctx context.Context, opts pgx.TxOptions, fn func(ctx context.Context) error) (err error) {
+func (m *Manager) transaction(ctx context.Context, opts pgx.TxOptions, cfg txConfig, fn func(ctx context.Context) error) (err error) {
 	// Если это вложенная транзакция, пропускаем инициацию новой транзакции и выполняем обработчик.
-	tx, ok := ctx.Value(TxKey).(pgx.Tx)
+	tx, ok := txFrom(ctx, m.key)
 	if ok {
-		return fn(ctx)
+		depth := txDepth(ctx) + 1
+		m.stats.recordJoin(depth)
+
+		nesting := cfg.nesting
+		if nesting == 0 {
+			nesting = m.nesting
+		}
+		if nesting == NestSavepoint {
+			return m.savepoint(ctx, tx, depth, fn)
+		}
+		return fn(withTxDepth(ctx, depth))
+	}
+
+	if cfg.deadlockAttempts > 1 {
+		return m.retryDeadlocks(ctx, opts, cfg, fn)
+	}
+
+	if cfg.name != "" {
+		ctx = context.WithValue(ctx, txNameKey{}, cfg.name)
+	}
+	if m.replay != nil {
+		r := &txReplay{withSQL: *m.replay}
+		ctx = context.WithValue(ctx, txReplayKey{}, r)
+		defer func() {
+			if err != nil {
+				err = &TxReplayError{TxName: cfg.name, Statements: r.snapshot(), Err: err}
+			}
+		}()
+	}
+	if m.txStatsScope && RequestStatsFromContext(ctx) == nil {
+		ctx, _ = WithRequestStats(ctx)
+	}
+
+	if cfg.budget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, cfg.budget, ErrTxBudgetExceeded)
+		defer cancel()
+	}
+
+	ctx, span := m.startSpan(ctx, cfg.name)
+	if span != nil {
+		defer func() { endSpan(span, err) }()
+	}
+	if m.slowTx > 0 {
+		start := time.Now()
+		defer func() { m.warnSlow(cfg.name, time.Since(start), err) }()
 	}
 
 	// Стартуем новую транзакцию.
+	began := time.Now()
 	tx, err = m.db.BeginTx(ctx, opts)
 	if err != nil {
 		return fmt.Errorf("can't begin transaction %w", err)
 	}
+	if m.metrics != nil {
+		m.metrics.TxStarted(cfg.name)
+	}
+	if m.leakAfter > 0 {
+		defer m.watchLeak(cfg.name)()
+	}
+
+	if m.idleTx > 0 {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+
+		var stop func()
+		tx, stop = m.watchIdle(ctx, tx, cfg.name, cancel)
+		defer stop()
+	}
+	if cfg.stmtBudget > 0 {
+		tx = budgetTx{Tx: tx, stmt: cfg.stmtBudget}
+	}
+	if m.guardTx {
+		tx = newGuardedTx(tx)
+	}
 
 	// Кладем транзакцию в контекст.
-	ctx = MakeContextTx(ctx, tx)
+	ctx = withTxDepth(withTx(ctx, m.key, tx), 1)
+	ctx, hooks := withTxHooks(ctx)
+	ctx, rollbackOnly := withRollbackOnly(ctx)
+	m.stats.recordStart()
 
 	// Настраиваем функцию отсрочки для отката или коммита транзакции.
 	defer func() {
@@ -48,43 +175,361 @@ func (m *Manager) transaction(ctx context.Context, opts pgx.TxOptions, fn func(c
 
 		// откатываем транзакцию, если произошла ошибка
 		if err != nil {
-			if errRollback := tx.Rollback(ctx); errRollback != nil {
-				err = fmt.Errorf("errRollback: %w", errRollback)
+			err = budgetErr(ctx, cfg, err)
+			if errRollback := rollbackTx(ctx, tx); errRollback != nil {
+				err = errors.Join(err, fmt.Errorf("errRollback: %w", errRollback))
 			}
+			m.finished(cfg.name, TxRolledBack, began)
+			hooks.runRollback()
 
 			return
 		}
 
+		// при двухфазной фиксации транзакция только подготавливается; COMMIT после PREPARE
+		// уже не относится ни к какой транзакции и лишь возвращает соединение
+		if cfg.prepareGID != "" {
+			if _, err = tx.Exec(ctx, "PREPARE TRANSACTION "+quoteLiteral(cfg.prepareGID)); err != nil {
+				err = fmt.Errorf("prepare transaction failed: %w", err)
+				_ = rollbackTx(ctx, tx)
+				m.finished(cfg.name, TxRolledBack, began)
+				hooks.runRollback()
+				return
+			}
+			_ = tx.Commit(ctx)
+			m.finished(cfg.name, TxPrepared, began)
+			return
+		}
+
 		// если ошибок не было, коммитим транзакцию
 		if nil == err {
 			err = tx.Commit(ctx)
 			if err != nil {
 				err = fmt.Errorf("tx commit failed: %w", err)
+				m.finished(cfg.name, TxRolledBack, began)
+				hooks.runRollback()
+				return
 			}
+			m.finished(cfg.name, TxCommitted, began)
+			hooks.runCommit()
 		}
 	}()
 
+	if err = setupTx(ctx, tx, cfg, m.settings); err != nil {
+		return err
+	}
+
 	// Выполните код внутри транзакции.
 	// Если функция терпит неудачу, возвращаем ошибку, и функция отсрочки выполняет откат
 	// или в противном случае транзакция коммитится.
 	if err = fn(ctx); err != nil {
 		err = fmt.Errorf("failed executing code inside transaction: %w", err)
+		return err
+	}
+
+	if rollbackOnly.Load() {
+		return ErrRollbackOnly
+	}
+
+	if err = hooks.runBeforeCommit(ctx); err != nil {
+		err = fmt.Errorf("before commit hook failed: %w", err)
+		return err
+	}
+
+	// Обработчики BeforeCommit тоже могут пометить транзакцию для отката.
+	if rollbackOnly.Load() {
+		return ErrRollbackOnly
+	}
+
+	// Обработчик мог превысить бюджет, не обращаясь к базе: такая транзакция откатывается.
+	if cfg.budget > 0 && ctx.Err() != nil {
+		err = fmt.Errorf("transaction exceeded its time budget: %w", context.Cause(ctx))
+	}
+
+	return err
+}
+
+// finished сообщает TxMetrics о завершении транзакции, начатой в began.
+func (m *Manager) finished(name string, outcome TxOutcome, began time.Time) {
+	if m.metrics != nil {
+		m.metrics.TxFinished(name, outcome, time.Since(began))
+	}
+}
+
+// setupTx выполняет первые запросы новой транзакции: cfg.init, параметры менеджера
+// defaults и вызова, имя и statement_timeout.
+func setupTx(ctx context.Context, tx pgx.Tx, cfg txConfig, defaults []txSetting) error {
+	if cfg.init != nil {
+		if err := cfg.init(ctx, tx); err != nil {
+			return fmt.Errorf("can't init transaction: %w", err)
+		}
+	}
+
+	if settings := append(defaults[:len(defaults):len(defaults)], cfg.settings...); len(settings) > 0 {
+		if err := setLocalSettings(ctx, tx, settings); err != nil {
+			return fmt.Errorf("can't apply transaction settings: %w", err)
+		}
+	}
+
+	if cfg.name != "" {
+		// Имя видно в pg_stat_activity.application_name до конца транзакции.
+		if _, err := tx.Exec(ctx, `SELECT set_config('application_name', left(current_setting('application_name') || ':' || $1, 63), true)`, cfg.name); err != nil {
+			return fmt.Errorf("can't set transaction name: %w", err)
+		}
+	}
+
+	if cfg.stmtBudget > 0 {
+		if err := setStatementTimeout(ctx, tx, cfg.stmtBudget); err != nil {
+			return fmt.Errorf("can't set statement timeout: %w", err)
+		}
+	}
+	return nil
+}
+
+// setLocalSettings устанавливает settings до конца транзакции одним запросом, по порядку.
+func setLocalSettings(ctx context.Context, tx pgx.Tx, settings []txSetting) error {
+	names := make([]string, len(settings))
+	values := make([]string, len(settings))
+	for i, st := range settings {
+		if err := validateSettingName(st.name); err != nil {
+			return err
+		}
+		names[i], values[i] = st.name, st.value
+	}
+
+	_, err := tx.Exec(ctx, `SELECT set_config(s.name, s.value, true) FROM unnest($1::text[], $2::text[]) WITH ORDINALITY AS s(name, value, n) ORDER BY s.n`, names, values)
+	return err
+}
+
+// WithSettings возвращает копию менеджера, каждая новая транзакция которого сразу после BEGIN
+// устанавливает параметры settings (как SET LOCAL), например роль и statement_timeout сервиса:
+//
+//	reports := tm.WithSettings(map[string]string{"role": "reporting", "statement_timeout": "30s"})
+//
+// Параметры вызова (WithSetLocal) применяются после них и переопределяют их.
+func (m *Manager) WithSettings(settings map[string]string) *Manager {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	c := *m
+	c.settings = append([]txSetting(nil), m.settings...)
+	for _, name := range names {
+		c.settings = append(c.settings, txSetting{name: name, value: settings[name]})
+	}
+	return &c
+}
+
+// savepoint выполняет вложенный обработчик на savepoint транзакции tx (см. NestSavepoint).
+func (m *Manager) savepoint(ctx context.Context, tx pgx.Tx, depth int64, fn func(ctx context.Context) error) (err error) {
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("can't create savepoint: %w", err)
+	}
+	// Обработчики savepoint копятся отдельно, только если внешняя транзакция принадлежит менеджеру.
+	parent, _ := ctx.Value(txHooksKey{}).(*txHooks)
+	var hooks *txHooks
+	if parent != nil {
+		ctx, hooks = withTxHooks(ctx)
 	}
 
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic recovered: %v", r)
+		}
+
+		if err != nil {
+			if errRollback := sp.Rollback(ctx); errRollback != nil {
+				err = fmt.Errorf("errRollback to savepoint: %w", errRollback)
+			}
+			if hooks != nil {
+				hooks.runRollback()
+			}
+			return
+		}
+
+		if err = sp.Commit(ctx); err != nil {
+			err = fmt.Errorf("release savepoint failed: %w", err)
+			return
+		}
+		if parent != nil {
+			parent.merge(hooks)
+		}
+	}()
+
+	if err = fn(withTxDepth(withTx(ctx, m.key, sp), depth)); err != nil {
+		err = fmt.Errorf("failed executing code inside savepoint: %w", err)
+	}
 	return err
 }
 
-func (m *Manager) ReadCommitted(ctx context.Context, f func(ctx context.Context) error) error {
+func (m *Manager) ReadCommitted(ctx context.Context, f func(ctx context.Context) error, opts ...TxOption) error {
 	txOpts := pgx.TxOptions{IsoLevel: pgx.ReadCommitted}
-	return m.transaction(ctx, txOpts, f)
+	return m.transaction(ctx, txOpts, newTxConfig(opts), f)
+}
+
+// ReadCommittedNamed выполняет f в транзакции READ COMMITTED с именем name — то же, что
+// ReadCommitted с WithTxName(name): имя попадает в span транзакции, в application_name
+// (pg_stat_activity) и в предупреждения о медленных транзакциях.
+//
+//	err := tm.ReadCommittedNamed(ctx, "create-order", func(ctx context.Context) error { ... })
+func (m *Manager) ReadCommittedNamed(ctx context.Context, name string, f func(ctx context.Context) error, opts ...TxOption) error {
+	return m.ReadCommitted(ctx, f, append([]TxOption{WithTxName(name)}, opts...)...)
+}
+
+// ReadOnly выполняет f в транзакции READ COMMITTED READ ONLY: сервер отвергает любые изменения
+// данных (SQLSTATE 25006), что защищает отчётные и читающие пути от случайной записи.
+// Транзакция выполняется на основном сервере; для чтения с реплики см. Postgres.Snapshot.
+// Вложенный вызов присоединяется к уже активной транзакции, которая может быть и пишущей.
+func (m *Manager) ReadOnly(ctx context.Context, f func(ctx context.Context) error, opts ...TxOption) error {
+	txOpts := pgx.TxOptions{IsoLevel: pgx.ReadCommitted, AccessMode: pgx.ReadOnly}
+	return m.transaction(ctx, txOpts, newTxConfig(opts), f)
+}
+
+// RepeatableRead выполняет f в транзакции с уровнем изоляции REPEATABLE READ: все запросы f видят
+// один снимок данных, сделанный при первом запросе транзакции. Конкурентное изменение тех же строк
+// завершает транзакцию ошибкой сериализации (SQLSTATE 40001).
+// Вложенный вызов присоединяется к уже активной транзакции с её уровнем изоляции.
+func (m *Manager) RepeatableRead(ctx context.Context, f func(ctx context.Context) error, opts ...TxOption) error {
+	txOpts := pgx.TxOptions{IsoLevel: pgx.RepeatableRead}
+	return m.transaction(ctx, txOpts, newTxConfig(opts), f)
+}
+
+// Serializable выполняет f в транзакции с уровнем изоляции SERIALIZABLE — например, для переводов
+// между счетами, где read committed допускает аномалии. Сервер может отменить такую транзакцию
+// с ошибкой сериализации (SQLSTATE 40001), её нужно повторять целиком.
+// Вложенный вызов присоединяется к уже активной транзакции с её уровнем изоляции.
+func (m *Manager) Serializable(ctx context.Context, f func(ctx context.Context) error, opts ...TxOption) error {
+	txOpts := pgx.TxOptions{IsoLevel: pgx.Serializable}
+	return m.transaction(ctx, txOpts, newTxConfig(opts), f)
+}
+
+// WithTxOptions выполняет f в транзакции с произвольными txOptions: уровнем изоляции, режимом
+// доступа и DEFERRABLE, например
+//
+//	tm.WithTxOptions(ctx, pgx.TxOptions{IsoLevel: pgx.Serializable, AccessMode: pgx.ReadOnly, DeferrableMode: pgx.Deferrable}, report)
+//
+// Вложенный вызов присоединяется к уже активной транзакции, и txOptions игнорируются.
+func (m *Manager) WithTxOptions(ctx context.Context, txOptions pgx.TxOptions, f Handler, opts ...TxOption) error {
+	return m.transaction(ctx, txOptions, newTxConfig(opts), f)
 }
 
 type key string
 
 const (
+	// TxKey — ключ самой внутренней транзакции контекста, какому бы Postgres она ни принадлежала.
+	// Каждый Postgres дополнительно хранит свою транзакцию под собственным ключом, поэтому
+	// TransactionalPool и TxManager разных баз не подхватывают чужие транзакции.
 	TxKey key = "tx"
 )
 
+// instanceTxKey — собственный ключ транзакции экземпляра Postgres.
+// Поле не даёт указателям на разные ключи совпасть.
+type instanceTxKey struct{ _ byte }
+
+// txOwnerKey хранит ключ экземпляра, которому принадлежит самая внутренняя транзакция.
+type txOwnerKey struct{}
+
+// withTx кладёт tx в контекст под TxKey и под ключом экземпляра key.
+func withTx(ctx context.Context, key any, tx pgx.Tx) context.Context {
+	ctx = context.WithValue(ctx, TxKey, tx)
+	if key != nil && key != TxKey {
+		ctx = context.WithValue(context.WithValue(ctx, key, tx), txOwnerKey{}, key)
+	}
+	return ctx
+}
+
+// txFrom возвращает транзакцию экземпляра с ключом key. Транзакция, положенная в контекст
+// без владельца (MakeContextTx вне pgfx), видна всем экземплярам, как и раньше.
+func txFrom(ctx context.Context, key any) (pgx.Tx, bool) {
+	if key != nil && key != TxKey {
+		if tx, ok := ctx.Value(key).(pgx.Tx); ok {
+			return tx, true
+		}
+		if ctx.Value(txOwnerKey{}) != nil {
+			return nil, false
+		}
+	}
+	tx, ok := ctx.Value(TxKey).(pgx.Tx)
+	return tx, ok
+}
+
+// executorTxKey возвращает ключ транзакций, в которых выполняет запросы db: для TransactionalPool —
+// ключ его экземпляра Postgres, для остальных исполнителей — TxKey (самая внутренняя транзакция).
+func executorTxKey(db QueryExecutor) any {
+	if t, ok := db.(pgTransactor); ok {
+		return t.key
+	}
+	return TxKey
+}
+
+// MakeContextTx заменяет самую внутреннюю транзакцию контекста на tx (например, на savepoint),
+// сохраняя её принадлежность экземпляру Postgres.
 func MakeContextTx(ctx context.Context, tx pgx.Tx) context.Context {
-	return context.WithValue(ctx, TxKey, tx)
+	return withTx(ctx, ctx.Value(txOwnerKey{}), tx)
+}
+
+// TxFromContext возвращает самую внутреннюю транзакцию контекста (на savepoint — вложенную)
+// и false, если транзакции нет. Транзакцию конкретной базы возвращает Postgres.TxFromContext.
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(TxKey).(pgx.Tx)
+	return tx, ok
+}
+
+// InTransaction сообщает, выполняется ли код внутри транзакции, например в тестах:
+//
+//	err := tm.ReadCommitted(ctx, func(ctx context.Context) error {
+//	    require.True(t, pgfx.InTransaction(ctx))
+//	    return svc.Do(ctx)
+//	})
+func InTransaction(ctx context.Context) bool {
+	_, ok := TxFromContext(ctx)
+	return ok
+}
+
+func (m *Manager) warnSlow(name string, elapsed time.Duration, err error) {
+	if elapsed < m.slowTx {
+		return
+	}
+	log.Printf("pgfx: slow transaction %q took %s (threshold %s), err: %v", txDisplayName(name), elapsed, m.slowTx, err)
+}
+
+func txDisplayName(name string) string {
+	if name == "" {
+		return "unnamed"
+	}
+	return name
+}
+
+type nopTxManager struct{}
+
+// NopTxManager возвращает TxManager, который просто вызывает обработчик, не обращаясь к базе.
+// Подходит для unit-тестов сервисов и dry-run режимов CLI.
+func NopTxManager() TxManager {
+	return nopTxManager{}
+}
+
+func (nopTxManager) ReadCommitted(ctx context.Context, f func(ctx context.Context) error, _ ...TxOption) error {
+	return f(ctx)
+}
+
+func (nopTxManager) ReadOnly(ctx context.Context, f func(ctx context.Context) error, _ ...TxOption) error {
+	return f(ctx)
+}
+
+func (nopTxManager) RepeatableRead(ctx context.Context, f func(ctx context.Context) error, _ ...TxOption) error {
+	return f(ctx)
+}
+
+func (nopTxManager) WithTxOptions(ctx context.Context, _ pgx.TxOptions, f Handler, _ ...TxOption) error {
+	return f(ctx)
+}
+
+func (nopTxManager) Begin(ctx context.Context, _ pgx.TxOptions, _ ...TxOption) (*TxHandle, error) {
+	return &TxHandle{ctx: ctx}, nil
+}
+
+func (nopTxManager) Serializable(ctx context.Context, f func(ctx context.Context) error, _ ...TxOption) error {
+	return f(ctx)
 }
@@ -0,0 +1,64 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type stubTx struct {
+	pgx.Tx
+	name string
+}
+
+func TestTxFromInstanceKeys(t *testing.T) {
+	keyA, keyB := &instanceTxKey{}, &instanceTxKey{}
+	txA, txB, sp := &stubTx{name: "a"}, &stubTx{name: "b"}, &stubTx{name: "sp"}
+
+	ctx := withTx(context.Background(), keyA, txA)
+	if _, ok := txFrom(ctx, keyB); ok {
+		t.Fatal("instance B sees the transaction of instance A")
+	}
+
+	ctx = withTx(ctx, keyB, txB)
+	if tx, _ := txFrom(ctx, keyA); tx != txA {
+		t.Fatalf("instance A got %v, want its own transaction", tx)
+	}
+	if tx, _ := TxFromContext(ctx); tx != txB {
+		t.Fatalf("TxFromContext got %v, want the innermost transaction", tx)
+	}
+
+	// MakeContextTx заменяет транзакцию владельца самой внутренней транзакции.
+	ctx = MakeContextTx(ctx, sp)
+	if tx, _ := txFrom(ctx, keyB); tx != sp {
+		t.Fatalf("instance B got %v, want the savepoint", tx)
+	}
+	if tx, _ := txFrom(ctx, keyA); tx != txA {
+		t.Fatalf("instance A got %v, want its own transaction", tx)
+	}
+
+	// Транзакция без владельца видна всем экземплярам.
+	legacy := MakeContextTx(context.Background(), txA)
+	if tx, _ := txFrom(legacy, keyB); tx != txA {
+		t.Fatalf("instance B got %v, want the ownerless transaction", tx)
+	}
+}
+
+func TestHelpersUseExecutorTransaction(t *testing.T) {
+	keyA, keyB := &instanceTxKey{}, &instanceTxKey{}
+	txA, txB := &execRecorderTx{}, &execRecorderTx{}
+	ctx := withTx(withTx(context.Background(), keyA, txA), keyB, txB)
+
+	if err := SetLocal(ctx, pgTransactor{key: keyA}, "app.tenant_id", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(txA.sql) != 1 || len(txB.sql) != 0 {
+		t.Fatalf("SetLocal ran %d statements on A and %d on B, want 1 and 0", len(txA.sql), len(txB.sql))
+	}
+
+	if err := SetLocal(context.Background(), pgTransactor{key: keyA}, "app.tenant_id", "1"); !errors.Is(err, ErrNoTransaction) {
+		t.Fatalf("outside transaction: got %v, want ErrNoTransaction", err)
+	}
+}
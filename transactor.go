@@ -2,54 +2,260 @@ package pgfx
 
 import (
 	"context"
+	"errors"
+	"io"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrNestedTransaction возвращается BeginTx внутри активной транзакции, если вложенные транзакции запрещены.
+var ErrNestedTransaction = errors.New("nested transaction is not allowed")
+
 // pgTransactor -.
 type pgTransactor struct {
-	dbc *pgxpool.Pool
+	dbc       *pgxpool.Pool
+	maxRows   int
+	rewriters []QueryRewriter
+	// noNestedBegin запрещает BeginTx внутри активной транзакции (см. DisableNestedBegin).
+	noNestedBegin bool
+	// timeouts — таймауты по тегу запроса (см. QueryTimeouts).
+	timeouts timeoutPolicy
+	// gate — очередь получения соединений по приоритету (см. PriorityAcquire), nil — выключена.
+	gate *priorityGate
+	// retryInvalidated повторяет запросы вне транзакции, упавшие из-за устаревшего
+	// подготовленного оператора (см. RetryInvalidatedStatements).
+	retryInvalidated bool
+	// key — ключ транзакций экземпляра Postgres в контексте (см. TxKey).
+	key any
+}
+
+func (p pgTransactor) rewrite(ctx context.Context, sql string) string {
+	for _, r := range p.rewriters {
+		sql = r(ctx, sql)
+	}
+	return sql
 }
 
 func (p pgTransactor) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
-	tx, ok := ctx.Value(TxKey).(pgx.Tx)
+	sql = p.rewrite(ctx, sql)
+
+	ctx, cancel := p.timeouts.withTimeout(ctx)
+	defer cancel()
+
+	tx, ok := txFrom(ctx, p.key)
+	args, err := applyPlanCacheMode(ctx, tx, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
 	if ok {
 		return tx.Exec(ctx, sql, args...)
 	}
 
-	return p.dbc.Exec(ctx, sql, args...)
+	release, err := p.gate.enter(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer release()
+
+	tag, err := p.dbc.Exec(ctx, sql, args...)
+	if p.retryInvalidated && isStatementInvalidated(err) {
+		tag, err = p.dbc.Exec(ctx, sql, args...)
+	}
+	return tag, poolTimeout(ctx, err)
 }
 
 func (p pgTransactor) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
-	tx, ok := ctx.Value(TxKey).(pgx.Tx)
+	sql = p.rewrite(ctx, sql)
+
+	ctx, cancel := p.timeouts.withTimeout(ctx)
+
+	var (
+		rows pgx.Rows
+		err  error
+	)
+
+	tx, ok := txFrom(ctx, p.key)
+	if args, err = applyPlanCacheMode(ctx, tx, args); err != nil {
+		cancel()
+		return nil, err
+	}
 	if ok {
-		return tx.Query(ctx, sql, args...)
+		rows, err = tx.Query(ctx, sql, args...)
+	} else {
+		var release func()
+		if release, err = p.gate.enter(ctx); err != nil {
+			cancel()
+			return nil, err
+		}
+		done := cancel
+		cancel = func() { done(); release() }
+		rows, err = p.dbc.Query(ctx, sql, args...)
+		if p.retryInvalidated && isStatementInvalidated(err) {
+			rows, err = p.dbc.Query(ctx, sql, args...)
+		}
+		if p.retryInvalidated && err == nil {
+			rows = &retryRows{Rows: rows, retry: func() (pgx.Rows, error) { return p.dbc.Query(ctx, sql, args...) }}
+		}
+		err = poolTimeout(ctx, err)
+	}
+	if err != nil {
+		cancel()
+		return rows, err
+	}
+	if len(p.timeouts) > 0 || p.gate != nil {
+		rows = &cancelRows{Rows: rows, cancel: cancel}
+	}
+
+	if limit := maxRowsFromContext(ctx, p.maxRows); limit > 0 {
+		rows = &limitedRows{Rows: rows, limit: limit}
 	}
 
-	return p.dbc.Query(ctx, sql, args...)
+	return rows, nil
 }
 
 func (p pgTransactor) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
-	tx, ok := ctx.Value(TxKey).(pgx.Tx)
+	sql = p.rewrite(ctx, sql)
+
+	var row pgx.Row
+	ctx, cancel := p.timeouts.withTimeout(ctx)
+
+	tx, ok := txFrom(ctx, p.key)
+	args, err := applyPlanCacheMode(ctx, tx, args)
+	if err != nil {
+		cancel()
+		return errRow{err}
+	}
 	if ok {
-		return tx.QueryRow(ctx, sql, args...)
+		row = tx.QueryRow(ctx, sql, args...)
+	} else {
+		release, err := p.gate.enter(ctx)
+		if err != nil {
+			cancel()
+			return errRow{err}
+		}
+		done := cancel
+		cancel = func() { done(); release() }
+		row = p.dbc.QueryRow(ctx, sql, args...)
+		if p.retryInvalidated {
+			row = retryRow{row: row, retry: func() pgx.Row { return p.dbc.QueryRow(ctx, sql, args...) }}
+		}
+		row = poolTimeoutRow{Row: row, ctx: ctx}
 	}
 
-	return p.dbc.QueryRow(ctx, sql, args...)
+	if len(p.timeouts) > 0 || p.gate != nil {
+		return cancelRow{Row: row, cancel: cancel}
+	}
+	return row
 }
 
 func (p pgTransactor) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
-	tx, ok := ctx.Value(TxKey).(pgx.Tx)
+	tx, ok := txFrom(ctx, p.key)
 	if ok {
 		return tx.CopyFrom(ctx, tableName, columnNames, rowSrc)
 	}
-	return p.dbc.CopyFrom(ctx, tableName, columnNames, rowSrc)
+
+	release, err := p.gate.enter(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	n, err := p.dbc.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	return n, poolTimeout(ctx, err)
+}
+
+// rewriteBatch возвращает копию b с переписанными запросами: сам b вызывающего не меняется,
+// поэтому повторная отправка того же пакета не переписывает запросы дважды.
+func (p pgTransactor) rewriteBatch(ctx context.Context, b *pgx.Batch) *pgx.Batch {
+	if len(p.rewriters) == 0 {
+		return b
+	}
+
+	rb := &pgx.Batch{QueuedQueries: make([]*pgx.QueuedQuery, len(b.QueuedQueries))}
+	for i, q := range b.QueuedQueries {
+		c := *q
+		c.SQL = p.rewrite(ctx, q.SQL)
+		rb.QueuedQueries[i] = &c
+	}
+	return rb
+}
+
+func (p pgTransactor) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	b = p.rewriteBatch(ctx, b)
+
+	tx, ok := txFrom(ctx, p.key)
+	if ok {
+		return tx.SendBatch(ctx, b)
+	}
+
+	if p.gate != nil {
+		release, err := p.gate.enter(ctx)
+		if err != nil {
+			return errBatchResults{err}
+		}
+		return gatedBatchResults{BatchResults: p.dbc.SendBatch(ctx, b), release: release}
+	}
+	return p.dbc.SendBatch(ctx, b)
 }
 
+func (p pgTransactor) CopyTo(ctx context.Context, w io.Writer, sql string) (pgconn.CommandTag, error) {
+	ctx, cancel := p.timeouts.withTimeout(ctx)
+	defer cancel()
+
+	tx, ok := txFrom(ctx, p.key)
+	if ok {
+		return copyToTx(ctx, tx, w, sql)
+	}
+
+	release, err := p.gate.enter(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer release()
+
+	conn, err := p.dbc.Acquire(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, poolTimeout(ctx, err)
+	}
+	defer conn.Release()
+
+	return conn.Conn().PgConn().CopyTo(ctx, w, sql)
+}
+
+// BeginTx начинает транзакцию. Если в контексте уже есть транзакция, создаётся вложенная
+// транзакция на savepoint той же транзакции (txOptions при этом игнорируются), а не новая
+// транзакция на другом соединении пула, которая молча разделила бы работу на две части.
+// С опцией DisableNestedBegin вместо этого возвращается ErrNestedTransaction.
 func (p pgTransactor) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
-	return p.dbc.BeginTx(ctx, txOptions)
+	tx, ok := txFrom(ctx, p.key)
+	if ok {
+		if p.noNestedBegin {
+			return nil, ErrNestedTransaction
+		}
+		return tx.Begin(ctx)
+	}
+	ctx = context.WithValue(ctx, beginTxKey{}, true)
+
+	if p.gate != nil {
+		release, err := p.gate.enter(ctx)
+		if err != nil {
+			return nil, err
+		}
+		tx, err := p.dbc.BeginTx(ctx, txOptions)
+		if err != nil {
+			release()
+			return nil, poolTimeout(ctx, err)
+		}
+		return gatedTx{Tx: tx, release: release}, nil
+	}
+
+	tx, err := p.dbc.BeginTx(ctx, txOptions)
+	if err != nil {
+		return nil, poolTimeout(ctx, err)
+	}
+	return tx, nil
 }
 
 func (p pgTransactor) Ping(ctx context.Context) error {
@@ -59,3 +265,18 @@ func (p pgTransactor) Ping(ctx context.Context) error {
 func (p pgTransactor) Close() {
 	p.dbc.Close()
 }
+
+// txCopier реализуется обёртками транзакции (бюджет, сторож простоя, защита от одновременного
+// использования), чтобы COPY ... TO STDOUT, идущий мимо pgx.Tx напрямую по соединению,
+// подчинялся тем же ограничениям, что и обычные запросы.
+type txCopier interface {
+	copyTo(ctx context.Context, w io.Writer, sql string) (pgconn.CommandTag, error)
+}
+
+// copyToTx выполняет COPY ... TO STDOUT на соединении транзакции tx через её обёртки.
+func copyToTx(ctx context.Context, tx pgx.Tx, w io.Writer, sql string) (pgconn.CommandTag, error) {
+	if c, ok := tx.(txCopier); ok {
+		return c.copyTo(ctx, w, sql)
+	}
+	return tx.Conn().PgConn().CopyTo(ctx, w, sql)
+}
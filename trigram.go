@@ -0,0 +1,114 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TrigramSearch описывает нечёткий поиск по колонке через расширение pg_trgm.
+type TrigramSearch struct {
+	Table  string
+	Column string
+	// Threshold — минимальная похожесть от 0 до 1 (по умолчанию 0.3, как в pg_trgm).
+	Threshold float64
+	// Word включает поиск по похожести на часть строки (word_similarity, оператор <%):
+	// подходит для поиска короткого ввода в длинных названиях.
+	Word bool
+}
+
+// SimilarityResult — строка, найденная SimilarSearch, и её похожесть на ввод.
+type SimilarityResult[T any] struct {
+	Row        T
+	Similarity float64
+}
+
+// SimilarSearch возвращает не более limit строк, похожих на input, в порядке убывания похожести.
+// Колонки выбираются по полям T (см. правила тегов db у Repository).
+//
+// Порог задаётся через pg_trgm.similarity_threshold на время запроса, а фильтрация идёт
+// оператором % (<%), поэтому используется trigram-индекс колонки, если он есть
+// (см. TrigramIndexAdvice). Запрос выполняется в транзакции или на savepoint уже активной.
+func SimilarSearch[T any](ctx context.Context, db QueryExecutor, s TrigramSearch, input string, limit int) ([]SimilarityResult[T], error) {
+	meta, err := structMetaOf(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, fmt.Errorf("similar search: %w", err)
+	}
+
+	if s.Threshold <= 0 {
+		s.Threshold = 0.3
+	}
+
+	column := quoteIdent(s.Column)
+	setting := "pg_trgm.similarity_threshold"
+	similarity, cond := fmt.Sprintf("similarity(%s, $1)", column), fmt.Sprintf("%s %% $1", column)
+	if s.Word {
+		setting = "pg_trgm.word_similarity_threshold"
+		similarity, cond = fmt.Sprintf("word_similarity($1, %s)", column), fmt.Sprintf("$1 <%% %s", column)
+	}
+
+	query := fmt.Sprintf(`SELECT %s, %s AS pgfx_similarity FROM %s WHERE %s ORDER BY pgfx_similarity DESC LIMIT $2`,
+		columnList(meta.fields), similarity, quoteTable(s.Table), cond)
+
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("similar search - begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, `SELECT set_config($1, $2, true)`, setting, fmt.Sprint(s.Threshold)); err != nil {
+		return nil, fmt.Errorf("similar search - set threshold: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, query, input, limit)
+	if err != nil {
+		return nil, fmt.Errorf("similar search: %w", err)
+	}
+
+	results, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (SimilarityResult[T], error) {
+		var res SimilarityResult[T]
+		rv := reflect.ValueOf(&res.Row).Elem()
+
+		targets := make([]any, 0, len(meta.fields)+1)
+		for _, f := range meta.fields {
+			targets = append(targets, rv.FieldByIndex(f.index).Addr().Interface())
+		}
+		targets = append(targets, &res.Similarity)
+
+		return res, row.Scan(targets...)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("similar search: %w", err)
+	}
+
+	// Запрос только читает; откат возвращает прежний порог.
+	return results, nil
+}
+
+// TrigramIndexAdvice проверяет, есть ли у колонки trigram-индекс (GIN gin_trgm_ops или
+// GiST gist_trgm_ops). Если нет, возвращает false и команду создания индекса,
+// без которого SimilarSearch сканирует всю таблицу.
+func TrigramIndexAdvice(ctx context.Context, db QueryExecutor, table, column string) (bool, string, error) {
+	var ok bool
+	err := db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM pg_index i
+			CROSS JOIN LATERAL unnest(i.indkey::int2[], i.indclass::oid[]) AS k(attnum, opclass)
+			JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum
+			JOIN pg_opclass oc ON oc.oid = k.opclass
+			WHERE i.indrelid = $1::regclass
+			  AND a.attname = $2
+			  AND oc.opcname IN ('gin_trgm_ops', 'gist_trgm_ops')
+		)`, quoteTable(table), column).Scan(&ok)
+	if err != nil {
+		return false, "", fmt.Errorf("trigram index advice: %w", err)
+	}
+	if ok {
+		return true, "", nil
+	}
+
+	return false, fmt.Sprintf("CREATE INDEX CONCURRENTLY ON %s USING gin (%s gin_trgm_ops)", quoteTable(table), quoteIdent(column)), nil
+}
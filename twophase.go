@@ -0,0 +1,63 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrTwoPhaseNested возвращается TwoPhase внутри активной транзакции: подготовить
+// можно только транзакцию целиком, а не её часть.
+var ErrTwoPhaseNested = errors.New("two-phase transaction cannot join an active transaction")
+
+// TwoPhase выполняет f в новой транзакции с txOptions и вместо COMMIT завершает её
+// PREPARE TRANSACTION gid — первой фазой двухфазной фиксации, которую координирует внешний
+// менеджер распределённых транзакций. Подготовленная транзакция переживает разрыв соединения
+// и рестарт сервера и держит свои блокировки, пока координатор не вызовет CommitPrepared
+// или RollbackPrepared с тем же gid, возможно — из другого процесса.
+//
+// Сервер должен разрешать подготовленные транзакции (max_prepared_transactions > 0).
+// Обработчики OnCommit при этом не вызываются: исход транзакции решает координатор.
+// Если f или PREPARE завершились ошибкой, транзакция откатывается как обычно.
+func (m *Manager) TwoPhase(ctx context.Context, gid string, txOptions pgx.TxOptions, f Handler, opts ...TxOption) error {
+	if _, ok := txFrom(ctx, m.key); ok {
+		return ErrTwoPhaseNested
+	}
+
+	cfg := newTxConfig(opts)
+	cfg.prepareGID = gid
+	return m.transaction(ctx, txOptions, cfg, f)
+}
+
+// CommitPrepared фиксирует подготовленную транзакцию gid (COMMIT PREPARED) — вторая фаза
+// после решения координатора. Выполняется вне транзакции, на любом соединении пула.
+func (m *Manager) CommitPrepared(ctx context.Context, gid string) error {
+	if err := m.finishPrepared(ctx, "COMMIT PREPARED ", gid); err != nil {
+		return fmt.Errorf("commit prepared %q: %w", gid, err)
+	}
+	return nil
+}
+
+// RollbackPrepared откатывает подготовленную транзакцию gid (ROLLBACK PREPARED).
+func (m *Manager) RollbackPrepared(ctx context.Context, gid string) error {
+	if err := m.finishPrepared(ctx, "ROLLBACK PREPARED ", gid); err != nil {
+		return fmt.Errorf("rollback prepared %q: %w", gid, err)
+	}
+	return nil
+}
+
+func (m *Manager) finishPrepared(ctx context.Context, stmt, gid string) error {
+	// COMMIT/ROLLBACK PREPARED нельзя выполнить внутри блока транзакции.
+	if _, ok := txFrom(ctx, m.key); ok {
+		return ErrTwoPhaseNested
+	}
+	db, ok := m.db.(execer)
+	if !ok {
+		return errors.New("transaction manager cannot execute statements")
+	}
+
+	_, err := db.Exec(ctx, stmt+quoteLiteral(gid))
+	return err
+}
@@ -0,0 +1,99 @@
+package pgfx
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TxOption настраивает транзакцию, запускаемую через TxManager.
+type TxOption func(*txConfig)
+
+type txConfig struct {
+	name string
+	// init выполняется первым в новой транзакции (например, SET TRANSACTION SNAPSHOT).
+	init func(ctx context.Context, tx pgx.Tx) error
+	// budget и stmtBudget — бюджет времени транзакции и отдельного запроса (см. WithBudget).
+	budget     time.Duration
+	stmtBudget time.Duration
+	// nesting — поведение вложенного вызова, 0 — по умолчанию менеджера.
+	nesting NestingMode
+	// deadlockAttempts и onDeadlockRetry — повтор при взаимоблокировке (см. WithDeadlockRetry).
+	deadlockAttempts int
+	onDeadlockRetry  DeadlockRetryFunc
+	// prepareGID — завершить транзакцию PREPARE TRANSACTION вместо COMMIT (см. Manager.TwoPhase).
+	prepareGID string
+	// settings — параметры SET LOCAL в начале транзакции (см. WithSetLocal).
+	settings []txSetting
+}
+
+// txSetting — параметр, устанавливаемый до конца транзакции.
+type txSetting struct {
+	name, value string
+}
+
+func newTxConfig(opts []TxOption) txConfig {
+	var cfg txConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithSetLocal устанавливает параметр name в value сразу после BEGIN, до конца транзакции
+// (как SET LOCAL), например
+//
+//	tm.ReadCommitted(ctx, handler,
+//	    pgfx.WithSetLocal("role", "tenant_rw"),
+//	    pgfx.WithSetLocal("app.tenant_id", tenantID),
+//	)
+//
+// Опцию можно повторять; параметры устанавливаются одним запросом после параметров
+// менеджера (см. Manager.WithSettings), поэтому значения вызова их переопределяют.
+// Для вложенного вызова, присоединяющегося к уже активной транзакции, опция игнорируется.
+func WithSetLocal(name, value string) TxOption {
+	return func(c *txConfig) {
+		c.settings = append(c.settings, txSetting{name: name, value: value})
+	}
+}
+
+// WithTxName задаёт имя транзакции (обычно — бизнес-операции: "CreateOrder").
+// Имя попадает в span транзакции, в предупреждения о медленных транзакциях
+// (WarnSlowTransactions) и в application_name сессии на время транзакции,
+// поэтому видно в pg_stat_activity. Внутри обработчика имя доступно через TxName.
+//
+// Для вложенного вызова, присоединяющегося к уже активной транзакции, имя игнорируется.
+func WithTxName(name string) TxOption {
+	return func(c *txConfig) {
+		c.name = name
+	}
+}
+
+type txNameKey struct{}
+
+// TxName возвращает имя текущей транзакции (см. WithTxName) или пустую строку.
+func TxName(ctx context.Context) string {
+	name, _ := ctx.Value(txNameKey{}).(string)
+	return name
+}
+
+// NestingMode определяет, что делает вызов TxManager, если в контексте уже есть транзакция.
+type NestingMode int
+
+const (
+	// NestJoin — обработчик выполняется в уже активной транзакции; его ошибка откатывает
+	// всю внешнюю транзакцию (поведение по умолчанию).
+	NestJoin NestingMode = iota + 1
+	// NestSavepoint — обработчик выполняется на savepoint: при ошибке откатывается только его
+	// работа (ROLLBACK TO SAVEPOINT), и внешний обработчик может продолжить транзакцию.
+	NestSavepoint
+)
+
+// WithNesting задаёт поведение вызова, если в контексте уже есть транзакция,
+// переопределяя значение по умолчанию из опции NestedTransactions.
+func WithNesting(mode NestingMode) TxOption {
+	return func(c *txConfig) {
+		c.nesting = mode
+	}
+}
@@ -0,0 +1,135 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrConcurrentTxUse возвращается, если транзакцию используют из нескольких горутин одновременно
+// (см. GuardConcurrentTxUse): соединение pgx не потокобезопасно, и такие запросы перемешали бы
+// протокол соединения.
+var ErrConcurrentTxUse = errors.New("transaction is used concurrently from multiple goroutines")
+
+// guardedTx отклоняет запрос, пока на соединении транзакции выполняется другой: Rows и
+// BatchResults занимают соединение до закрытия. Savepoint делят флаг с транзакцией.
+type guardedTx struct {
+	pgx.Tx
+	busy *atomic.Bool
+}
+
+func newGuardedTx(tx pgx.Tx) guardedTx {
+	return guardedTx{Tx: tx, busy: &atomic.Bool{}}
+}
+
+// enter занимает соединение; release можно вызывать несколько раз.
+func (t guardedTx) enter() (release func(), err error) {
+	if !t.busy.CompareAndSwap(false, true) {
+		return nil, ErrConcurrentTxUse
+	}
+	var once sync.Once
+	return func() { once.Do(func() { t.busy.Store(false) }) }, nil
+}
+
+func (t guardedTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	release, err := t.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	sp, err := t.Tx.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return guardedTx{Tx: sp, busy: t.busy}, nil
+}
+
+func (t guardedTx) Commit(ctx context.Context) error {
+	release, err := t.enter()
+	if err != nil {
+		return err
+	}
+	defer release()
+	return t.Tx.Commit(ctx)
+}
+
+func (t guardedTx) Rollback(ctx context.Context) error {
+	release, err := t.enter()
+	if err != nil {
+		return err
+	}
+	defer release()
+	return t.Tx.Rollback(ctx)
+}
+
+func (t guardedTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	release, err := t.enter()
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer release()
+	return t.Tx.Exec(ctx, sql, args...)
+}
+
+func (t guardedTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	release, err := t.enter()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := t.Tx.Query(ctx, sql, args...)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &cancelRows{Rows: rows, cancel: release}, nil
+}
+
+func (t guardedTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	release, err := t.enter()
+	if err != nil {
+		return errRow{err}
+	}
+	return cancelRow{Row: t.Tx.QueryRow(ctx, sql, args...), cancel: release}
+}
+
+func (t guardedTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	release, err := t.enter()
+	if err != nil {
+		return errBatchResults{err}
+	}
+	return gatedBatchResults{BatchResults: t.Tx.SendBatch(ctx, b), release: release}
+}
+
+func (t guardedTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	release, err := t.enter()
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	return t.Tx.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (t guardedTx) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	release, err := t.enter()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return t.Tx.Prepare(ctx, name, sql)
+}
+
+func (t guardedTx) copyTo(ctx context.Context, w io.Writer, sql string) (pgconn.CommandTag, error) {
+	release, err := t.enter()
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer release()
+	return copyToTx(ctx, t.Tx, w, sql)
+}
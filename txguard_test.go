@@ -0,0 +1,53 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// blockingTx — транзакция, Exec которой ждёт сигнала.
+type blockingTx struct {
+	pgx.Tx
+	started, unblock chan struct{}
+}
+
+func (t blockingTx) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	t.started <- struct{}{}
+	<-t.unblock
+	return pgconn.CommandTag{}, nil
+}
+
+func TestGuardedTxRejectsConcurrentUse(t *testing.T) {
+	inner := blockingTx{started: make(chan struct{}), unblock: make(chan struct{})}
+	tx := newGuardedTx(inner)
+	ctx := context.Background()
+
+	done := make(chan error)
+	go func() {
+		_, err := tx.Exec(ctx, "SELECT 1")
+		done <- err
+	}()
+	<-inner.started
+
+	if _, err := tx.Exec(ctx, "SELECT 2"); !errors.Is(err, ErrConcurrentTxUse) {
+		t.Fatalf("concurrent Exec: got %v, want ErrConcurrentTxUse", err)
+	}
+	sp, err := tx.Begin(ctx)
+	if !errors.Is(err, ErrConcurrentTxUse) || sp != nil {
+		t.Fatalf("concurrent Begin: got %v, want ErrConcurrentTxUse", err)
+	}
+
+	close(inner.unblock)
+	if err := <-done; err != nil {
+		t.Fatalf("first Exec: %v", err)
+	}
+
+	go func() { <-inner.started }()
+	if _, err := tx.Exec(ctx, "SELECT 3"); err != nil {
+		t.Fatalf("sequential Exec: %v", err)
+	}
+}
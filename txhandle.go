@@ -0,0 +1,259 @@
+package pgfx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TxHandle — транзакция, открытая Begin и завершаемая явно через Commit или Rollback,
+// для сценариев, которые не укладываются в обработчик: например, HTTP middleware открывает
+// транзакцию в начале запроса и фиксирует её при отправке ответа.
+//
+//	h, err := tm.Begin(r.Context(), pgx.TxOptions{})
+//	if err != nil { ... }
+//	defer h.Rollback(context.Background())
+//	next.ServeHTTP(w, r.WithContext(h.Context()))
+//	if err := h.Commit(r.Context()); err != nil { ... }
+//
+// Обработчики OnCommit, OnRollback и BeforeCommit работают так же, как в ReadCommitted.
+// Методы TxHandle можно вызывать из разных горутин, но запросы через Context() — нет:
+// транзакция выполняется на одном соединении.
+type TxHandle struct {
+	ctx context.Context
+	tx  pgx.Tx
+
+	hooks *txHooks
+	// parent — обработчики внешней транзакции, если handle открыт на её savepoint.
+	parent    *txHooks
+	savepoint bool
+
+	budget bool
+	cancel context.CancelFunc
+	// replay — запросы транзакции для TxReplayError, nil — запись выключена (см. RecordTransactionReplay).
+	replay *txReplay
+	name   string
+	// finish завершает span и предупреждение о медленной транзакции,
+	// outcome сообщает TxMetrics итог транзакции (для savepoint — ничего).
+	finish  func(err error)
+	outcome func(TxOutcome)
+
+	mu   sync.Mutex
+	done bool
+}
+
+// Begin открывает транзакцию с txOptions и возвращает её handle. Контекст handle.Context()
+// уже содержит транзакцию, поэтому репозитории и вложенные вызовы менеджера выполняются в ней.
+// Если в ctx уже есть транзакция, handle открывается на её savepoint (txOptions игнорируются):
+// Commit освобождает savepoint, Rollback откатывает только его.
+//
+// Поддерживаются WithTxName, WithBudget/WithTimeout и остальные настройки первых запросов,
+// а также настройки менеджера: сторож простоя, запись запросов для TxReplayError, RequestStats
+// на время транзакции, метрики и обнаружение утечек. WithDeadlockRetry игнорируется —
+// повторить обработчик, которого нет, невозможно.
+func (m *Manager) Begin(ctx context.Context, txOptions pgx.TxOptions, opts ...TxOption) (*TxHandle, error) {
+	if tx, ok := txFrom(ctx, m.key); ok {
+		depth := txDepth(ctx) + 1
+		m.stats.recordJoin(depth)
+
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("can't create savepoint: %w", err)
+		}
+		h := &TxHandle{tx: sp, savepoint: true, finish: func(error) {}, outcome: func(TxOutcome) {}}
+		h.parent, _ = ctx.Value(txHooksKey{}).(*txHooks)
+		if h.parent != nil {
+			ctx, h.hooks = withTxHooks(ctx)
+		}
+		h.ctx = withTxDepth(withTx(ctx, m.key, sp), depth)
+		return h, nil
+	}
+
+	cfg := newTxConfig(opts)
+	h := &TxHandle{budget: cfg.budget > 0, cancel: func() {}}
+
+	if cfg.name != "" {
+		ctx = context.WithValue(ctx, txNameKey{}, cfg.name)
+	}
+	if m.replay != nil {
+		h.replay, h.name = &txReplay{withSQL: *m.replay}, cfg.name
+		ctx = context.WithValue(ctx, txReplayKey{}, h.replay)
+	}
+	if m.txStatsScope && RequestStatsFromContext(ctx) == nil {
+		ctx, _ = WithRequestStats(ctx)
+	}
+	if cfg.budget > 0 {
+		ctx, h.cancel = context.WithTimeoutCause(ctx, cfg.budget, ErrTxBudgetExceeded)
+	}
+
+	var span trace.Span
+	ctx, span = m.startSpan(ctx, cfg.name)
+	start := time.Now()
+	h.finish = func(err error) {
+		h.cancel()
+		if span != nil {
+			endSpan(span, err)
+		}
+		if m.slowTx > 0 {
+			m.warnSlow(cfg.name, time.Since(start), err)
+		}
+	}
+
+	began := time.Now()
+	tx, err := m.db.BeginTx(ctx, txOptions)
+	if err != nil {
+		err = h.replayErr(fmt.Errorf("can't begin transaction %w", err))
+		h.finish(err)
+		return nil, err
+	}
+	if m.metrics != nil {
+		m.metrics.TxStarted(cfg.name)
+	}
+	h.outcome = func(outcome TxOutcome) { m.finished(cfg.name, outcome, began) }
+	if m.leakAfter > 0 {
+		stop, finish := m.watchLeak(cfg.name), h.finish
+		h.finish = func(err error) {
+			stop()
+			finish(err)
+		}
+	}
+	if m.idleTx > 0 {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+
+		var stop func()
+		tx, stop = m.watchIdle(ctx, tx, cfg.name, cancel)
+		finish := h.finish
+		h.finish = func(err error) {
+			stop()
+			cancel(nil)
+			finish(err)
+		}
+	}
+	if cfg.stmtBudget > 0 {
+		tx = budgetTx{Tx: tx, stmt: cfg.stmtBudget}
+	}
+	if m.guardTx {
+		tx = newGuardedTx(tx)
+	}
+	h.tx = tx
+
+	ctx = withTxDepth(withTx(ctx, m.key, tx), 1)
+	ctx, h.hooks = withTxHooks(ctx)
+	h.ctx, _ = withRollbackOnly(ctx)
+	m.stats.recordStart()
+
+	if err := setupTx(h.ctx, tx, cfg, m.settings); err != nil {
+		_ = rollbackTx(h.ctx, tx)
+		err = h.replayErr(err)
+		h.outcome(TxRolledBack)
+		h.finish(err)
+		return nil, err
+	}
+	return h, nil
+}
+
+// Context возвращает контекст с транзакцией handle.
+func (h *TxHandle) Context() context.Context {
+	return h.ctx
+}
+
+// Tx возвращает саму транзакцию; для NopTxManager — nil.
+func (h *TxHandle) Tx() pgx.Tx {
+	return h.tx
+}
+
+// Commit выполняет обработчики BeforeCommit и фиксирует транзакцию (или освобождает savepoint).
+// ctx используется для COMMIT, обработчики BeforeCommit получают Context().
+// Если обработчик BeforeCommit завершился ошибкой, транзакция помечена SetRollbackOnly
+// или превысила бюджет времени, она откатывается. Повторный вызов после завершения возвращает pgx.ErrTxClosed.
+func (h *TxHandle) Commit(ctx context.Context) (err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.done {
+		return pgx.ErrTxClosed
+	}
+	h.done = true
+	if h.tx == nil {
+		return nil
+	}
+	defer func() { h.finish(err) }()
+	defer func() { err = h.replayErr(err) }()
+
+	if h.savepoint {
+		if err = h.tx.Commit(ctx); err != nil {
+			return fmt.Errorf("release savepoint failed: %w", err)
+		}
+		if h.parent != nil {
+			h.parent.merge(h.hooks)
+		}
+		return nil
+	}
+
+	if IsRollbackOnly(h.ctx) {
+		err = ErrRollbackOnly
+	} else if err = h.hooks.runBeforeCommit(h.ctx); err != nil {
+		err = fmt.Errorf("before commit hook failed: %w", err)
+	} else if IsRollbackOnly(h.ctx) {
+		err = ErrRollbackOnly
+	} else if h.budget && h.ctx.Err() != nil {
+		err = fmt.Errorf("transaction exceeded its time budget: %w", context.Cause(h.ctx))
+	}
+	if err != nil {
+		if errRollback := rollbackTx(ctx, h.tx); errRollback != nil {
+			err = errors.Join(err, fmt.Errorf("errRollback: %w", errRollback))
+		}
+		h.outcome(TxRolledBack)
+		h.hooks.runRollback()
+		return err
+	}
+
+	if err = h.tx.Commit(ctx); err != nil {
+		h.outcome(TxRolledBack)
+		h.hooks.runRollback()
+		return fmt.Errorf("tx commit failed: %w", err)
+	}
+	h.outcome(TxCommitted)
+	h.hooks.runCommit()
+	return nil
+}
+
+// Rollback откатывает транзакцию (или её savepoint). После Commit или повторного
+// Rollback ничего не делает и возвращает nil, поэтому его удобно откладывать через defer.
+func (h *TxHandle) Rollback(ctx context.Context) (err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.done {
+		return nil
+	}
+	h.done = true
+	if h.tx == nil {
+		return nil
+	}
+	defer func() { h.finish(err) }()
+	defer func() { err = h.replayErr(err) }()
+
+	if err = h.tx.Rollback(ctx); err != nil {
+		err = fmt.Errorf("errRollback: %w", err)
+	}
+	h.outcome(TxRolledBack)
+	if h.hooks != nil {
+		h.hooks.runRollback()
+	}
+	return err
+}
+
+// replayErr оборачивает err в TxReplayError с запросами транзакции, если их запись включена.
+func (h *TxHandle) replayErr(err error) error {
+	if err == nil || h.replay == nil {
+		return err
+	}
+	return &TxReplayError{TxName: h.name, Statements: h.replay.snapshot(), Err: err}
+}
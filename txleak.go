@@ -0,0 +1,21 @@
+package pgfx
+
+import (
+	"log"
+	"runtime/debug"
+	"time"
+)
+
+// watchLeak логирует транзакцию name со стеком её создания, если она не завершилась
+// за m.leakAfter (см. DetectTransactionLeaks). Возвращаемую функцию нужно вызвать
+// после COMMIT или ROLLBACK.
+func (m *Manager) watchLeak(name string) (stop func()) {
+	stack := debug.Stack()
+	start := time.Now()
+
+	t := time.AfterFunc(m.leakAfter, func() {
+		log.Printf("pgfx: transaction %q still open after %s (threshold %s), begun at:\n%s",
+			txDisplayName(name), time.Since(start).Round(time.Millisecond), m.leakAfter, stack)
+	})
+	return func() { t.Stop() }
+}
@@ -0,0 +1,40 @@
+package pgfx
+
+import "time"
+
+// TxOutcome — чем завершилась транзакция.
+type TxOutcome int
+
+const (
+	TxCommitted TxOutcome = iota
+	TxRolledBack
+	// TxPrepared — транзакция подготовлена для двухфазной фиксации (см. Manager.TwoPhase).
+	TxPrepared
+)
+
+func (o TxOutcome) String() string {
+	switch o {
+	case TxCommitted:
+		return "commit"
+	case TxRolledBack:
+		return "rollback"
+	case TxPrepared:
+		return "prepare"
+	}
+	return "unknown"
+}
+
+// TxMetrics получает события транзакций TxManager (см. WithTxMetrics) — например, чтобы
+// вести в Prometheus или OTEL счётчики begin/commit/rollback и гистограмму длительности.
+// name — имя транзакции (WithTxName), пустое для безымянных. Методы вызываются синхронно
+// в горутине транзакции и не должны блокироваться. Вложенные вызовы и savepoint не учитываются.
+type TxMetrics interface {
+	// TxStarted вызывается после успешного BEGIN.
+	TxStarted(name string)
+	// TxFinished вызывается после COMMIT, ROLLBACK или PREPARE TRANSACTION;
+	// d — время от начала BEGIN до завершения.
+	TxFinished(name string, outcome TxOutcome, d time.Duration)
+	// TxRetried вызывается перед повтором транзакции целиком;
+	// reason — "deadlock" (WithDeadlockRetry) или "connection" (ReadCommittedRetry).
+	TxRetried(name string, reason string)
+}
@@ -0,0 +1,72 @@
+package pgfx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// UUIDStrategy — способ генерации идентификаторов UUID.
+type UUIDStrategy int
+
+const (
+	// UUIDv4 — случайный UUID (gen_random_uuid() на сервере).
+	UUIDv4 UUIDStrategy = iota
+	// UUIDv7 — UUID с меткой времени в старших битах (RFC 9562). Новые значения монотонно растут,
+	// поэтому вставки не фрагментируют B-tree индекс первичного ключа, как случайные v4.
+	UUIDv7
+)
+
+// NewUUID генерирует UUID на стороне клиента. Для UUIDv7 значения, созданные одним процессом,
+// упорядочены по времени создания.
+func NewUUID(strategy UUIDStrategy) uuid.UUID {
+	if strategy == UUIDv7 {
+		return uuid.Must(uuid.NewV7())
+	}
+	return uuid.New()
+}
+
+// _uuidV7Function — серверная генерация UUIDv7 для PostgreSQL до 18 (где есть встроенная uuidv7()).
+const _uuidV7Function = `
+CREATE OR REPLACE FUNCTION pgfx_uuid_v7() RETURNS uuid
+LANGUAGE sql VOLATILE AS $$
+	SELECT encode(
+		set_bit(set_bit(
+			overlay(uuid_send(gen_random_uuid())
+				PLACING substring(int8send((extract(epoch FROM clock_timestamp()) * 1000)::bigint) FROM 3)
+				FROM 1 FOR 6),
+			52, 1), 53, 1),
+		'hex')::uuid
+$$`
+
+// UUIDDefault возвращает SQL-выражение значения по умолчанию для колонки uuid:
+// gen_random_uuid() для UUIDv4, uuidv7() на PostgreSQL 18+ и pgfx_uuid_v7() на более старых
+// версиях (функцию создаёт SetUUIDDefault).
+func UUIDDefault(strategy UUIDStrategy, serverVersion int) string {
+	switch {
+	case strategy == UUIDv4:
+		return "gen_random_uuid()"
+	case serverVersion >= 180000:
+		return "uuidv7()"
+	default:
+		return "pgfx_uuid_v7()"
+	}
+}
+
+// SetUUIDDefault задаёт колонке column таблицы table значение по умолчанию по стратегии strategy
+// (ALTER TABLE ... ALTER COLUMN ... SET DEFAULT), при необходимости создавая pgfx_uuid_v7().
+func (p *Postgres) SetUUIDDefault(ctx context.Context, table, column string, strategy UUIDStrategy) error {
+	def := UUIDDefault(strategy, p.ServerVersion())
+	if def == "pgfx_uuid_v7()" {
+		if _, err := p.TransactionalPool.Exec(ctx, _uuidV7Function); err != nil {
+			return fmt.Errorf("postgres - SetUUIDDefault - create function: %w", err)
+		}
+	}
+
+	query := fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s`, quoteTable(table), quoteIdent(column), def)
+	if _, err := p.TransactionalPool.Exec(ctx, query); err != nil {
+		return fmt.Errorf("postgres - SetUUIDDefault: %w", err)
+	}
+	return nil
+}
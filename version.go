@@ -0,0 +1,118 @@
+package pgfx
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Feature — возможность сервера PostgreSQL, доступность которой зависит от версии.
+type Feature string
+
+const (
+	// FeatureSkipLocked — FOR UPDATE SKIP LOCKED (9.5+).
+	FeatureSkipLocked Feature = "skip_locked"
+	// FeatureLogicalReplication — встроенная логическая репликация, PUBLICATION/SUBSCRIPTION (10+).
+	FeatureLogicalReplication Feature = "logical_replication"
+	// FeatureMerge — оператор MERGE (15+).
+	FeatureMerge Feature = "merge"
+	// FeatureNullsNotDistinct — UNIQUE NULLS NOT DISTINCT (15+).
+	FeatureNullsNotDistinct Feature = "nulls_not_distinct"
+	// FeatureGenericPlan — EXPLAIN (GENERIC_PLAN) (16+).
+	FeatureGenericPlan Feature = "generic_plan"
+	// FeatureMergeReturning — MERGE ... RETURNING (17+).
+	FeatureMergeReturning Feature = "merge_returning"
+)
+
+// _featureVersions — минимальная версия (в формате server_version_num) для каждой возможности.
+var _featureVersions = map[Feature]int{
+	FeatureSkipLocked:         90500,
+	FeatureLogicalReplication: 100000,
+	FeatureMerge:              150000,
+	FeatureNullsNotDistinct:   150000,
+	FeatureGenericPlan:        160000,
+	FeatureMergeReturning:     170000,
+}
+
+// SupportedBy сообщает, доступна ли возможность на сервере версии version
+// (в формате server_version_num, например 150004). Неизвестные возможности не поддерживаются.
+func (f Feature) SupportedBy(version int) bool {
+	need, ok := _featureVersions[f]
+	return ok && version >= need
+}
+
+// ServerVersion возвращает версию сервера в формате server_version_num (150004 для 15.4).
+// Версия запоминается при установке соединений пула; если соединений ещё не было,
+// метод открывает одно. Возвращает 0, если подключиться не удалось.
+func (p *Postgres) ServerVersion() int {
+	if v := p.serverVersion.Load(); v != 0 {
+		return int(v)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(p.liveConnTimeout.Load()))
+	defer cancel()
+
+	if conn, err := p.Pool.Acquire(ctx); err == nil {
+		p.recordServerVersion(conn.Conn())
+		conn.Release()
+	}
+	return int(p.serverVersion.Load())
+}
+
+// Supports сообщает, доступна ли возможность на сервере, к которому подключён пул.
+//
+//	if pg.Supports(pgfx.FeatureMerge) {
+//	    ...
+//	}
+func (p *Postgres) Supports(f Feature) bool {
+	return f.SupportedBy(p.ServerVersion())
+}
+
+func (p *Postgres) recordServerVersion(conn *pgx.Conn) int {
+	v := parseServerVersion(conn.PgConn().ParameterStatus("server_version"))
+	if v != 0 {
+		p.serverVersion.Store(int64(v))
+	}
+	return v
+}
+
+// parseServerVersion переводит server_version ("15.4", "9.6.24", "17beta1",
+// "16.2 (Debian 16.2-1)") в формат server_version_num.
+func parseServerVersion(s string) int {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), " ")
+
+	var parts []int
+	for _, part := range strings.SplitN(s, ".", 3) {
+		end := strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' })
+		if end >= 0 {
+			part = part[:end]
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+		if end >= 0 {
+			break
+		}
+	}
+
+	for len(parts) < 3 {
+		parts = append(parts, 0)
+	}
+	if parts[0] >= 10 {
+		return parts[0]*10000 + parts[1]
+	}
+	return parts[0]*10000 + parts[1]*100 + parts[2]
+}
+
+// serverVersionNum запрашивает версию сервера через db; используется хелперами,
+// которым доступен только QueryExecutor.
+func serverVersionNum(ctx context.Context, db QueryExecutor) (int, error) {
+	var v int
+	err := db.QueryRow(ctx, `SELECT current_setting('server_version_num')::int`).Scan(&v)
+	return v, err
+}
@@ -0,0 +1,24 @@
+package pgfx
+
+import "testing"
+
+func TestParseServerVersion(t *testing.T) {
+	tests := map[string]int{
+		"15.4":                 150004,
+		"16.2 (Debian 16.2-1)": 160002,
+		"17beta1":              170000,
+		"9.6.24":               90624,
+		"9.5":                  90500,
+		"":                     0,
+	}
+
+	for in, want := range tests {
+		if got := parseServerVersion(in); got != want {
+			t.Errorf("parseServerVersion(%q) = %d, want %d", in, got, want)
+		}
+	}
+
+	if !FeatureMerge.SupportedBy(150004) || FeatureMerge.SupportedBy(140010) {
+		t.Error("FeatureMerge gating is wrong")
+	}
+}